@@ -0,0 +1,225 @@
+/*
+
+archives.go
+
+List the members of zip and tar archives as virtual entries, used by the -archives cmd line option
+
+Example: fstat -archives -r backups/
+Example: fstat -archives -ext .csv -oc exports.zip
+
+Each .zip, .tar, .tar.gz and .tgz file is followed by its members, which are named archive!inner/path, with
+the size, modified time and permissions stored in the archive. The members are examined by the same filters as
+any other entry, so that -ir, -ext, -dn, -szl, -type and -where also apply to them, even when the archive
+itself is excluded. The size of a member is its uncompressed size. Archives within archives are not opened.
+
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveSeparator - separates the name of an archive from the name of one of its members
+const archiveSeparator = "!"
+
+// archiveExtensions - the file name endings of the archives which are opened, ignoring case
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// isArchive - true when fname ends with one of archiveExtensions
+func isArchive(fname string) bool {
+	lower := strings.ToLower(fname)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// memberName - the name of a member within an archive, without any leading ./ or trailing /; empty for the archive root
+func memberName(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+/*
+readZip calls add for each member of a zip archive
+
+Args:
+    fname: the archive name
+
+    device: the device of the archive, which is used for each member
+
+    add: called with the name and stat results of each member
+
+Returns:
+    an error when the archive can not be read
+*/
+func readZip(fname string, device uint64, add func(name string, rec statRecord)) error {
+	r, err := zip.OpenReader(fname)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		name := memberName(f.Name)
+		if 0 == len(name) {
+			continue
+		}
+		size := int64(f.UncompressedSize64)
+		add(name, statRecord{Size: size, ModTime: f.Modified, Mode: f.Mode(), Device: device, Access: f.Modified, Allocated: size})
+	}
+	return nil
+}
+
+/*
+readTar calls add for each member of a tar archive, which is decompressed when its name ends with .gz or .tgz
+
+Args:
+    fname: the archive name
+
+    device: the device of the archive, which is used for each member
+
+    add: called with the name and stat results of each member
+
+Returns:
+    an error when the archive can not be read
+*/
+func readTar(fname string, device uint64, add func(name string, rec statRecord)) error {
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	lower := strings.ToLower(fname)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		input = gz
+	}
+
+	tr := tar.NewReader(input)
+	for {
+		h, err := tr.Next()
+		if io.EOF == err {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := memberName(h.Name)
+		if 0 == len(name) {
+			continue
+		}
+		access := h.AccessTime
+		if access.IsZero() {
+			access = h.ModTime
+		}
+		rec := statRecord{Size: h.Size, ModTime: h.ModTime, Mode: h.FileInfo().Mode(), Device: device, Access: access, Allocated: h.Size, Owner: h.Uname, Group: h.Gname, HaveOwner: true, HaveGroup: true}
+		if tar.TypeChar == h.Typeflag || tar.TypeBlock == h.Typeflag {
+			rec.DeviceNumber = fmt.Sprintf("%d:%d", h.Devmajor, h.Devminor)
+		}
+		add(name, rec)
+	}
+}
+
+/*
+readArchive returns the members of an archive
+
+Args:
+    fname: the archive name
+
+Returns:
+    the name of each member, in archive order, as archive!inner/path;
+    the stat results of each member, where the usage of each directory is the total size of the files within it;
+    and an error when the archive can not be read, in which case the members read so far are still returned
+*/
+func readArchive(fname string) ([]string, map[string]statRecord, error) {
+	f, err := os.Stat(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !f.Mode().IsRegular() {
+		return nil, nil, nil
+	}
+	device := getDevice(fname, f)
+
+	var names []string
+	records := make(map[string]statRecord)
+	usage := make(map[string]int64)
+	add := func(name string, rec statRecord) {
+		full := fname + archiveSeparator + name
+		if _, seen := records[full]; !seen {
+			names = append(names, full)
+		}
+		records[full] = rec
+		if rec.Mode.IsRegular() {
+			for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+				usage[dir] += rec.Size
+			}
+		}
+	}
+	if strings.HasSuffix(strings.ToLower(fname), ".zip") {
+		err = readZip(fname, device, add)
+	} else {
+		err = readTar(fname, device, add)
+	}
+
+	for full, rec := range records {
+		if rec.Mode.IsDir() {
+			rec.DirUsage, rec.HaveDirUsage = usage[strings.TrimPrefix(full, fname+archiveSeparator)], true
+			records[full] = rec
+		}
+	}
+	return names, records, err
+}
+
+/*
+archiveNames returns each file name from nextName, with the members of each archive after it
+
+Args:
+    nextName: returns each file name in turn, see sliceNames() and scanNames()
+
+    members: filled in with the stat results of each member, which GetFileInfo() uses instead of os.Lstat()
+
+    quiet: when set, archives which can not be read are not reported to STDERR (cmd line option: -q)
+
+Returns:
+    a function which returns each name in turn, and false when there are no more
+*/
+func archiveNames(nextName func() (string, bool), members map[string]statRecord, quiet bool) func() (string, bool) {
+	var pending []string
+	return func() (string, bool) {
+		if len(pending) > 0 {
+			name := pending[0]
+			pending = pending[1:]
+			return name, true
+		}
+		fname, ok := nextName()
+		if !ok || !isArchive(fname) || isRemote(fname) {
+			return fname, ok
+		}
+		names, records, err := readArchive(fname)
+		if err != nil && !quiet {
+			logError("%s: %s", fname, err)
+		}
+		for name, rec := range records {
+			members[name] = rec
+		}
+		pending = names
+		return fname, ok
+	}
+}
@@ -0,0 +1,26 @@
+//go:build darwin || freebsd || netbsd
+
+/*
+
+atime_atimespec.go
+
+Last access time for systems whose stat structure uses Atimespec
+
+*/
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// getAccessTime returns the last access time of the file, or its modification time when not available
+func getAccessTime(f os.FileInfo) time.Time {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return f.ModTime()
+	}
+	return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+}
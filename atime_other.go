@@ -0,0 +1,21 @@
+//go:build !linux && !openbsd && !darwin && !freebsd && !netbsd && !windows
+
+/*
+
+atime_other.go
+
+Last access time for systems where it is not supported
+
+*/
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// getAccessTime returns the modification time, since the last access time is not available
+func getAccessTime(f os.FileInfo) time.Time {
+	return f.ModTime()
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+/*
+
+atime_windows.go
+
+Last access time for Windows
+
+*/
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// getAccessTime returns the last access time of the file, or its modification time when not available
+func getAccessTime(f os.FileInfo) time.Time {
+	data, ok := f.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return f.ModTime()
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds())
+}
@@ -0,0 +1,187 @@
+/*
+
+badnames.go
+
+Find file names which break other tools, used by the -badnames cmd line option and the badnames subcommand
+
+Example: fstat -badnames -r dirs.txt
+Example: fstat badnames -oc -r /srv/share > renames.csv
+
+The base name of each entry is checked for:
+    invalid UTF-8, which many programs and file systems can not show or store
+    control characters, such as a tab or newline, which break line based tools such as xargs
+    a trailing space or dot, which Windows removes, so that the file can not be opened from Windows
+
+Names are shown with each invalid byte and control character escaped, such as \x0a, so that they can be read
+in a terminal. A suggested name is also shown, where each of these is replaced with _ and any trailing spaces
+and dots are removed.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// badName - a file name with at least one problem
+type badName struct {
+	FullName  string   `json:"fullname"`
+	Escaped   string   `json:"escaped"`
+	Problems  []string `json:"problems"`
+	Suggested string   `json:"suggested"`
+}
+
+// escapeName - s with each invalid UTF-8 byte and control character replaced by an escape, such as \x0a
+func escapeName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case utf8.RuneError == r && 1 == size:
+			fmt.Fprintf(&b, "\\x%02x", s[i])
+		case unicode.IsControl(r):
+			if r < 0x100 {
+				fmt.Fprintf(&b, "\\x%02x", r)
+			} else {
+				fmt.Fprintf(&b, "\\u%04x", r)
+			}
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+/*
+checkName finds the problems with a single base name
+
+Args:
+    base: the base name of a file
+
+Returns:
+    a description of each problem, empty when there are none
+
+    the suggested name, which is base when there are no problems
+*/
+func checkName(base string) ([]string, string) {
+	var problems []string
+	var suggested strings.Builder
+	var invalid, control bool
+	for i := 0; i < len(base); {
+		r, size := utf8.DecodeRuneInString(base[i:])
+		switch {
+		case utf8.RuneError == r && 1 == size:
+			invalid = true
+			suggested.WriteByte('_')
+		case unicode.IsControl(r):
+			control = true
+			suggested.WriteByte('_')
+		default:
+			suggested.WriteString(base[i : i+size])
+		}
+		i += size
+	}
+	if invalid {
+		problems = append(problems, "invalid UTF-8")
+	}
+	if control {
+		problems = append(problems, "control character")
+	}
+	if strings.HasSuffix(base, " ") {
+		problems = append(problems, "trailing space")
+	}
+	if strings.HasSuffix(base, ".") && "." != base && ".." != base {
+		problems = append(problems, "trailing dot")
+	}
+
+	fixed := suggested.String()
+	if "." != base && ".." != base {
+		fixed = strings.TrimRight(fixed, " .")
+	}
+	if 0 == len(fixed) {
+		fixed = "_"
+	}
+	return problems, fixed
+}
+
+/*
+FindBadNames returns the entries whose base names have problems
+
+Args:
+    allEntries: the entries to check
+
+    onlyTypes: only check these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    each entry with at least one problem, in the same order as allEntries
+*/
+func FindBadNames(allEntries []FileStat, onlyTypes string) []badName {
+	var bad []badName
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		problems, suggested := checkName(filepath.Base(e.FullName))
+		if len(problems) > 0 {
+			bad = append(bad, badName{FullName: e.FullName, Escaped: escapeName(e.FullName), Problems: problems, Suggested: suggested})
+		}
+	}
+	return bad
+}
+
+/*
+RenderBadNames outputs each entry whose base name has problems
+
+Args:
+    bad: created by FindBadNames() or FindNonPortable()
+
+    label: describes the entries in the summary line, such as "bad names"
+
+    outputCSV, outputTSV, outputJSON: alternate output formats (-oc, -ot, -oj cmd line options)
+
+    csvDelimiter: the field delimiter used with outputCSV (-csvdelim cmd line option)
+
+Returns:
+    the number of entries output
+*/
+func RenderBadNames(bad []badName, label string, outputCSV bool, csvDelimiter rune, outputTSV bool, outputJSON bool) int {
+	if outputJSON {
+		if nil == bad {
+			bad = []badName{}
+		}
+		j, _ := json.MarshalIndent(bad, "", "    ")
+		fmt.Println(string(j))
+		return len(bad)
+	}
+
+	var allRows [][]string
+	for _, b := range bad {
+		allRows = append(allRows, []string{strings.Join(b.Problems, ", "), b.Suggested, b.Escaped})
+	}
+	header := []string{"Problems", "Suggested", "Name"}
+
+	if outputCSV {
+		renderCSV(header, allRows, csvDelimiter, nil)
+		return len(bad)
+	}
+
+	if outputTSV {
+		renderTSV(header, allRows, nil)
+		return len(bad)
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("%s: %d\n", label, len(bad))
+	return len(bad)
+}
@@ -0,0 +1,239 @@
+/*
+
+cache.go
+
+Keep stat results between runs, used by the -cache and -cachettl cmd line options
+
+Each examined file is stored under its absolute path along with the device of its directory, so that
+different file systems mounted at the same path do not share entries. While an entry is younger than
+-cachettl, the file is not examined again. This helps with repeat scans over slow network mounts, and
+with -du since the cumulative size of each directory is also kept.
+
+*/
+
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// cacheFileName - the file created within the -cache directory
+const cacheFileName = "fstat-cache.gob"
+
+// statRecord - everything GetFileInfo() needs to know about a file, without calling os.Lstat()
+type statRecord struct {
+	Size               int64
+	ModTime            time.Time
+	Mode               os.FileMode
+	Device             uint64
+	Access             time.Time
+	Allocated          int64
+	DeviceNumber       string
+	Owner              string
+	Group              string
+	HaveOwner          bool
+	HaveGroup          bool
+	DirUsage           int64
+	HaveDirUsage       bool
+	DirUsageSameDevice bool
+	Uncompressed       int64
+	HaveUncompressed   bool
+	Entropy            float64
+	HaveEntropy        bool
+	Class              string
+	Interpreter        string
+	HaveInterpreter    bool
+	Width              int
+	Height             int
+	HaveImageSize      bool
+	Duration           float64
+	HaveDuration       bool
+	Pages              int64
+	HavePages          bool
+	Cached             time.Time
+}
+
+// statCache - stat results loaded from, and saved to, the -cache directory
+// fname is empty when the results are only kept in memory, such as for -snapshot-save
+type statCache struct {
+	fname   string
+	ttl     time.Duration
+	cwd     string
+	records map[string]statRecord
+	changed bool
+	offline bool
+	// the device of each directory, which is only found once for each run
+	devices map[string]uint64
+}
+
+/*
+newStatRecord collects the results of os.Lstat()
+
+Args:
+    fname: the file name
+
+    f: returned by os.Lstat(fname)
+
+Returns:
+    the record; owner, group and cumulative directory size are added by the caller when needed
+*/
+func newStatRecord(fname string, f os.FileInfo) statRecord {
+	return statRecord{Size: f.Size(), ModTime: f.ModTime(), Mode: f.Mode(), Device: getDevice(fname, f), Access: getAccessTime(f), Allocated: getAllocated(f), DeviceNumber: getDeviceNumber(f), Cached: time.Now()}
+}
+
+/*
+LoadStatCache reads the cache file within dir, which is created when it does not exist
+
+Args:
+    dir: the cache directory (-cache cmd line option)
+
+    ttl: entries older than this are ignored (-cachettl cmd line option)
+
+Returns:
+    the cache; or an error when dir can not be created or the cache file can not be read
+*/
+func LoadStatCache(dir string, ttl time.Duration) (*statCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	c := &statCache{fname: filepath.Join(dir, cacheFileName), ttl: ttl, cwd: cwd, records: make(map[string]statRecord), devices: make(map[string]uint64)}
+
+	file, err := os.Open(c.fname)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := gob.NewDecoder(file).Decode(&c.records); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.fname, err)
+	}
+	return c, nil
+}
+
+/*
+newMemoryStatCache returns an empty cache that is never saved
+Every file is examined, and its stat results are kept for SaveSnapshot()
+*/
+func newMemoryStatCache() *statCache {
+	cwd, _ := os.Getwd()
+	return &statCache{cwd: cwd, records: make(map[string]statRecord)}
+}
+
+/*
+newSnapshotCache returns an empty cache whose entries never expire, to be filled in by LoadSnapshot()
+The file system is never examined, even for files that are not in the cache (-snapshot-load cmd line option)
+*/
+func newSnapshotCache() *statCache {
+	cwd, _ := os.Getwd()
+	return &statCache{ttl: math.MaxInt64, cwd: cwd, records: make(map[string]statRecord), offline: true}
+}
+
+// isOffline - true when the file system should not be examined; c can be nil
+func (c *statCache) isOffline() bool {
+	return c != nil && c.offline
+}
+
+/*
+key returns the name that the record of fname is stored under, computed without calling os.Getwd() for every file
+
+Args:
+    fname: the file name
+
+Returns:
+    the absolute path of fname, which is preceded by the device of its directory when the cache is saved to a file;
+    a cache which is only kept in memory, such as for a snapshot, is only used for a single scan
+*/
+func (c *statCache) key(fname string) string {
+	abs := filepath.Join(c.cwd, fname)
+	if filepath.IsAbs(fname) {
+		abs = filepath.Clean(fname)
+	}
+	if 0 == len(c.fname) {
+		return abs
+	}
+
+	dir := filepath.Dir(abs)
+	device, ok := c.devices[dir]
+	if !ok {
+		if info, err := os.Stat(dir); err == nil {
+			device = getDevice(dir, info)
+		}
+		c.devices[dir] = device
+	}
+	return strconv.FormatUint(device, 16) + ":" + abs
+}
+
+/*
+get returns the cached record for fname
+
+Args:
+    fname: the file name
+
+    needOwner, needGroup: when set, a record without the owner or group name is not used
+
+Returns:
+    the record; and false when c is nil, fname is not cached, or the record is too old or incomplete
+*/
+func (c *statCache) get(fname string, needOwner bool, needGroup bool) (statRecord, bool) {
+	if c == nil {
+		return statRecord{}, false
+	}
+	rec, ok := c.records[c.key(fname)]
+	if !ok || time.Since(rec.Cached) > c.ttl || (needOwner && !rec.HaveOwner) || (needGroup && !rec.HaveGroup) {
+		return statRecord{}, false
+	}
+	return rec, true
+}
+
+// put - store the record for fname; c can be nil
+func (c *statCache) put(fname string, rec statRecord) {
+	if c != nil {
+		c.records[c.key(fname)] = rec
+		c.changed = true
+	}
+}
+
+/*
+Save writes the cache file, leaving out entries older than the TTL
+The file is replaced by renaming, so that an interrupted run does not leave a partial cache
+
+Returns:
+    an error when the cache file can not be written
+*/
+func (c *statCache) Save() error {
+	if !c.changed || 0 == len(c.fname) {
+		return nil
+	}
+	for key, rec := range c.records {
+		if time.Since(rec.Cached) > c.ttl {
+			delete(c.records, key)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.fname), cacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(c.records); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.fname)
+}
@@ -0,0 +1,98 @@
+/*
+
+cancel.go
+
+Stop the scan early when it is interrupted, or when the -timeout cmd line option expires
+
+Example: echo /data | fstat -timeout 5m -r -t
+Example: fstat -timeout 30s -oj -r dirs.txt > partial.json
+
+Ctrl-C, SIGTERM and -timeout stop the walk, the examining of each file, hashing with -dups and -cmphash, and
+requests to remote servers, and the entries which were already examined are output as usual. With -r, each name
+is examined as soon as it is walked, so stopping during a long walk still outputs the entries found so far. The
+output is marked as partial: the table has a partial row, -oc, -ot and -oh have a partial footer, the -oj object
+and -ts have a partial field with the reason, a warning is written to STDERR, and the exit status is
+exitPartial. A second Ctrl-C stops at once, without any output. -watch is not limited by -timeout, and runs
+until interrupted.
+
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// errInterrupted - the cause of a scan which was stopped by Ctrl-C or SIGTERM
+var errInterrupted = errors.New("interrupted")
+
+/*
+newRunContext creates the context which is passed to the walk, the examining of each file, and hashing
+
+Args:
+    timeout: when not 0, the context is done after this much time (-timeout cmd line option)
+
+Returns:
+    the context, which is also done at the first Ctrl-C or SIGTERM, with a cause of errInterrupted;
+    and a function which releases the context and restores the default handling of Ctrl-C, so that it ends the
+    program at once; it is called once, when the context is no longer used
+*/
+func newRunContext(timeout time.Duration) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	release := func() { cancel(nil) }
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("-timeout of %s expired", timeout))
+		release = func() {
+			cancelTimeout()
+			cancel(nil)
+		}
+	}
+
+	// only the first signal is caught, so that a second Ctrl-C ends the program
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			cancel(errInterrupted)
+		case <-done:
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		release()
+	}
+}
+
+// partialReason - why the scan was stopped early, such as "interrupted"; empty when the context is not done
+func partialReason(ctx context.Context) string {
+	if nil == ctx.Err() {
+		return ""
+	}
+	return context.Cause(ctx).Error()
+}
+
+// contextReader - an io.Reader which fails with the error of ctx once it is done, used to stop hashing large files
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read - the same as the Read of the underlying reader, until ctx is done
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
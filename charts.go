@@ -0,0 +1,224 @@
+/*
+
+charts.go
+
+Text based charts built from the collected FileStat entries, used by the -hist and -timeline cmd line options
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jftuga/termsize"
+	"github.com/olekukonko/tablewriter"
+)
+
+// maxBarWidth - number of characters used for the longest bar in a chart
+const maxBarWidth = 40
+
+// validTimelineModes - allowed values for the -timeline cmd line option
+var validTimelineModes = []string{"day", "week"}
+
+// sparkLevels - characters used to draw the -timeline sparkline, from lowest to highest
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// sizeBucket - a range of file sizes in the -hist chart
+type sizeBucket struct {
+	label string
+	upper int64 // exclusive upper bound; 0 means no upper bound
+	count int64
+	size  int64
+}
+
+// newSizeBuckets - logarithmic size buckets from <1K to >=1G
+func newSizeBuckets() []sizeBucket {
+	return []sizeBucket{
+		{label: "< 1K", upper: 1 << 10},
+		{label: "1K - 10K", upper: 10 << 10},
+		{label: "10K - 100K", upper: 100 << 10},
+		{label: "100K - 1M", upper: 1 << 20},
+		{label: "1M - 10M", upper: 10 << 20},
+		{label: "10M - 100M", upper: 100 << 20},
+		{label: "100M - 1G", upper: 1 << 30},
+		{label: ">= 1G", upper: 0},
+	}
+}
+
+// renderBar returns a bar of '#' characters scaled so that max fills maxBarWidth
+// any non-zero value will have a bar of at least one character
+func renderBar(value int64, max int64) string {
+	if value == 0 || max == 0 {
+		return ""
+	}
+	width := int(value * maxBarWidth / max)
+	if width == 0 {
+		width = 1
+	}
+	return strings.Repeat("#", width)
+}
+
+/*
+RenderSizeHistogram outputs a text histogram of regular file sizes
+
+Args:
+    allEntries: a slice of all files; only regular files are counted
+
+    addCommas: when set, add a comma as a thousands separator (-c cmd line option)
+
+    convertToMiB: when set, output the total size of each bucket in Mebibytes (-m cmd line option)
+
+    useSI: when set, output sizes in decimal KB, MB, GB units (-dec cmd line option)
+*/
+func RenderSizeHistogram(allEntries []FileStat, addCommas bool, convertToMiB bool, useSI bool) {
+	buckets := newSizeBuckets()
+	for _, e := range allEntries {
+		if "F" != e.FileType {
+			continue
+		}
+		for i := range buckets {
+			if buckets[i].upper == 0 || e.Size < buckets[i].upper {
+				buckets[i].count++
+				buckets[i].size += e.Size
+				break
+			}
+		}
+	}
+
+	var maxCount int64
+	for _, b := range buckets {
+		if b.count > maxCount {
+			maxCount = b.count
+		}
+	}
+
+	var allRows [][]string
+	for _, b := range buckets {
+		allRows = append(allRows, []string{b.label, formatSize(b.count, addCommas, false, false), formatSize(b.size, addCommas, convertToMiB, useSI), renderBar(b.count, maxCount)})
+	}
+	header := []string{"File Size", "Files", "Total Size", "Distribution"}
+	renderTable(header, allRows, []int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+}
+
+// timelinePeriod returns the start of the day or week (starting on Monday) that t falls in, in t's time zone
+func timelinePeriod(t time.Time, period string) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if "week" == period {
+		offset := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -offset)
+	}
+	return start
+}
+
+// renderSparkline returns at most width characters for the given counts, scaled so that the largest
+// value uses the highest level; when there are more counts than width, adjacent counts are summed
+// periods without any files are rendered as a space
+func renderSparkline(counts []int64, width int) string {
+	var columns []int64
+	if len(counts) <= width {
+		columns = counts
+	} else {
+		for i := 0; i < width; i++ {
+			var sum int64
+			for _, c := range counts[i*len(counts)/width : (i+1)*len(counts)/width] {
+				sum += c
+			}
+			columns = append(columns, sum)
+		}
+	}
+
+	var max int64
+	for _, c := range columns {
+		if c > max {
+			max = c
+		}
+	}
+
+	var sb strings.Builder
+	for _, c := range columns {
+		if c == 0 {
+			sb.WriteRune(' ')
+			continue
+		}
+		level := int((c - 1) * int64(len(sparkLevels)) / max)
+		sb.WriteRune(sparkLevels[level])
+	}
+	return sb.String()
+}
+
+/*
+RenderTimeline outputs a sparkline covering the entire range of modification times,
+followed by a table of file counts for each day or week that contains modified files
+
+Args:
+    allEntries: a slice of all files; only regular files are counted
+
+    period: one of validTimelineModes (-timeline cmd line option)
+
+    addCommas: when set, add a comma as a thousands separator (-c cmd line option)
+
+    convertToMiB: when set, output the total size of each period in Mebibytes (-m cmd line option)
+
+    useSI: when set, output sizes in decimal KB, MB, GB units (-dec cmd line option)
+*/
+func RenderTimeline(allEntries []FileStat, period string, addCommas bool, convertToMiB bool, useSI bool) {
+	counts := make(map[time.Time]int64)
+	sizes := make(map[time.Time]int64)
+	var first, last time.Time
+	for _, e := range allEntries {
+		if "F" != e.FileType {
+			continue
+		}
+		p := timelinePeriod(e.ModTime, period)
+		if len(counts) == 0 || p.Before(first) {
+			first = p
+		}
+		if len(counts) == 0 || p.After(last) {
+			last = p
+		}
+		counts[p]++
+		sizes[p] += e.Size
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	days := 1
+	if "week" == period {
+		days = 7
+	}
+
+	var maxCount int64
+	var allCounts []int64
+	var allRows [][]string
+	for p := first; !p.After(last); p = timelinePeriod(p.AddDate(0, 0, days), period) {
+		c := counts[p]
+		allCounts = append(allCounts, c)
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	for p := first; !p.After(last); p = timelinePeriod(p.AddDate(0, 0, days), period) {
+		if counts[p] == 0 {
+			continue
+		}
+		allRows = append(allRows, []string{p.Format("2006-01-02"), formatSize(counts[p], addCommas, false, false), formatSize(sizes[p], addCommas, convertToMiB, useSI), renderBar(counts[p], maxCount)})
+	}
+
+	// leave room for the dates on either side of the sparkline
+	width := termsize.Width() - 24
+	if width < minTermWidth {
+		width = minTermWidth
+	}
+	fmt.Printf("%s %s %s\n\n", first.Format("2006-01-02"), renderSparkline(allCounts, width), last.Format("2006-01-02"))
+
+	firstColumn := "Day"
+	if "week" == period {
+		firstColumn = "Week"
+	}
+	header := []string{firstColumn, "Files", "Total Size", "Distribution"}
+	renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+}
@@ -0,0 +1,142 @@
+/*
+
+cmp.go
+
+Compare two directory trees, used by the -cmp and -cmphash cmd line options
+
+Example: fstat -cmp -if /backup/photos /home/user/photos
+
+Both trees are walked and examined with the same filter options, and entries are matched by their
+path relative to each directory. Only metadata is compared unless -cmphash is given, in which case
+files of the same size are also compared by their SHA-256 hash.
+
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cmpKinds - all values of FileChange.Change created by CompareDirs, in the order they are counted below the table
+var cmpKinds = []string{"left only", "right only", "resized", "redated", "content"}
+
+/*
+scanTree examines everything beneath dir
+
+Args:
+    dir: the directory to walk; it is not included
+
+    scan: examines a list of files with all of the filter options, see main()
+
+    ctx, quiet, maxDepth, ignorePatterns, sameDevice, followLinks, netfs: passed to WalkAllFilenames()
+
+Returns:
+    the entries, with each FullName relative to dir
+*/
+func scanTree(ctx context.Context, dir string, scan func(nextName func() (string, bool)) []FileStat, quiet bool, maxDepth int, ignorePatterns []string, sameDevice bool, followLinks bool, netfs *netFileSystems) []FileStat {
+	allEntries := scan(sliceNames(WalkAllFilenames(ctx, []string{dir}, quiet, 1, maxDepth, ignorePatterns, sameDevice, followLinks, netfs)))
+	for i := range allEntries {
+		if rel, err := filepath.Rel(dir, allEntries[i].FullName); err == nil {
+			allEntries[i].FullName = rel
+		}
+	}
+	return allEntries
+}
+
+// hashFile - the SHA-256 hash of a file's contents; an error once ctx is done, even while reading a large file
+func hashFile(ctx context.Context, fname string) ([]byte, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, contextReader{ctx, file}); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+/*
+CompareDirs reports the entries which are only in one directory, or which differ between the two
+
+Args:
+    ctx: once done, the walk and hashing stop, and only the entries examined so far are compared, see cancel.go
+
+    leftDir, rightDir: the directories to compare
+
+    scan: examines a list of files with all of the filter options, see main()
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    maxDepth: when not negative, do not descend below this depth (-maxdepth cmd line option)
+
+    ignorePatterns, sameDevice, followLinks, netfs: passed to WalkAllFilenames()
+
+    compareHashes: when set, also compare the contents of files that are the same size (-cmphash cmd line option)
+
+Returns:
+    the differences, sorted by relative name; Change is one of cmpKinds, or several joined by +
+*/
+func CompareDirs(ctx context.Context, leftDir string, rightDir string, scan func(nextName func() (string, bool)) []FileStat, quiet bool, maxDepth int, ignorePatterns []string, sameDevice bool, followLinks bool, netfs *netFileSystems, compareHashes bool) []FileChange {
+	leftEntries := scanTree(ctx, leftDir, scan, quiet, maxDepth, ignorePatterns, sameDevice, followLinks, netfs)
+	rightEntries := scanTree(ctx, rightDir, scan, quiet, maxDepth, ignorePatterns, sameDevice, followLinks, netfs)
+
+	changes := ComputeChanges(leftEntries, rightEntries)
+	changed := make(map[string]int, len(changes))
+	for i := range changes {
+		switch changes[i].Change {
+		case "added":
+			changes[i].Change = "right only"
+		case "removed":
+			changes[i].Change = "left only"
+		}
+		changed[changes[i].FullName] = i
+	}
+	if !compareHashes {
+		return changes
+	}
+
+	allLeft := make(map[string]*FileStat, len(leftEntries))
+	for i := range leftEntries {
+		allLeft[leftEntries[i].FullName] = &leftEntries[i]
+	}
+	for i := range rightEntries {
+		if ctx.Err() != nil {
+			break
+		}
+		r := &rightEntries[i]
+		l, ok := allLeft[r.FullName]
+		if !ok || "F" != l.FileType || "F" != r.FileType || l.Size != r.Size {
+			continue
+		}
+		leftHash, err := hashFile(ctx, filepath.Join(leftDir, l.FullName))
+		if err == nil {
+			var rightHash []byte
+			if rightHash, err = hashFile(ctx, filepath.Join(rightDir, r.FullName)); err == nil && string(leftHash) == string(rightHash) {
+				continue
+			}
+		}
+		if err != nil {
+			if !quiet && nil == ctx.Err() {
+				logError("%s", err)
+			}
+			continue
+		}
+		if n, ok := changed[r.FullName]; ok {
+			changes[n].Change += "+content"
+			continue
+		}
+		changes = append(changes, FileChange{Change: "content", FullName: r.FullName, FileType: r.FileType, OldSize: &l.Size, NewSize: &r.Size, OldModTime: &l.ModTime, NewModTime: &r.ModTime})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FullName < changes[j].FullName
+	})
+	return changes
+}
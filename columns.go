@@ -0,0 +1,186 @@
+/*
+
+columns.go
+
+Optional columns which are inserted before the Name column in the default, CSV and HTML output
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// extraColumn - an optional column; value renders the column for a single entry
+type extraColumn struct {
+	header    string
+	alignment int
+	value     func(e FileStat) string
+}
+
+// modeColumn - file type and permission bits, such as "-rw-r--r--" (-mode cmd line option)
+func modeColumn() extraColumn {
+	return extraColumn{header: "Mode", alignment: tablewriter.ALIGN_LEFT, value: func(e FileStat) string {
+		return e.Mode
+	}}
+}
+
+// deviceNumberColumn - major and minor numbers of character and block devices, such as "8:1" (-devnum cmd line option)
+func deviceNumberColumn() extraColumn {
+	return extraColumn{header: "Dev Num", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		return e.DeviceNumber
+	}}
+}
+
+// allocColumn - allocated size on disk; sparse files are marked with a trailing "S" (-alloc cmd line option)
+func allocColumn(addCommas bool, convertToMiB bool, useSI bool) extraColumn {
+	return extraColumn{header: "Allocated", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		alloc := formatSize(e.Allocated, addCommas, convertToMiB, useSI)
+		if e.Sparse {
+			return alloc + " S"
+		}
+		return alloc
+	}}
+}
+
+// gzipSizeColumn - uncompressed size of .gz files, empty for all other entries (-gzsize cmd line option)
+func gzipSizeColumn(addCommas bool, convertToMiB bool, useSI bool) extraColumn {
+	return extraColumn{header: "Uncompressed", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		if nil == e.Uncompressed {
+			return ""
+		}
+		return formatSize(*e.Uncompressed, addCommas, convertToMiB, useSI)
+	}}
+}
+
+// entropyColumn - estimated entropy of files in bits per byte, such as "7.98"; empty for all other entries (-entropy cmd line option)
+func entropyColumn() extraColumn {
+	return extraColumn{header: "Entropy", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		if nil == e.Entropy {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *e.Entropy)
+	}}
+}
+
+// classColumn - text or binary, for files; empty for all other entries (-class cmd line option)
+func classColumn() extraColumn {
+	return extraColumn{header: "Class", alignment: tablewriter.ALIGN_LEFT, value: func(e FileStat) string {
+		return e.Class
+	}}
+}
+
+// interpreterColumn - the #! line of executable scripts, such as "/usr/bin/env python3" (-shebang cmd line option)
+func interpreterColumn() extraColumn {
+	return extraColumn{header: "Interpreter", alignment: tablewriter.ALIGN_LEFT, value: func(e FileStat) string {
+		return e.Interpreter
+	}}
+}
+
+// imageSizeColumn - the width and height of images, such as "1920x1080"; empty for all other entries (-imgsize cmd line option)
+func imageSizeColumn() extraColumn {
+	return extraColumn{header: "Dimensions", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		if 0 == e.Width && 0 == e.Height {
+			return ""
+		}
+		return fmt.Sprintf("%dx%d", e.Width, e.Height)
+	}}
+}
+
+// durationColumn - the duration of audio and video files, such as "1:02:03"; empty for all other entries (-duration cmd line option)
+func durationColumn() extraColumn {
+	return extraColumn{header: "Duration", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		if 0 == e.Duration {
+			return ""
+		}
+		return formatDuration(e.Duration)
+	}}
+}
+
+// pagesColumn - the number of pages of PDF files; empty for all other entries (-pages cmd line option)
+func pagesColumn() extraColumn {
+	return extraColumn{header: "Pages", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		if 0 == e.Pages {
+			return ""
+		}
+		return strconv.FormatInt(e.Pages, 10)
+	}}
+}
+
+// gitColumns - the abbreviated hash, author and date of the last commit of entries in a git repository (-git cmd line option)
+func gitColumns(timeLayout string, relativeOnly bool) []extraColumn {
+	now := time.Now()
+	return []extraColumn{
+		{header: "Commit", alignment: tablewriter.ALIGN_LEFT, value: func(e FileStat) string {
+			return e.Commit[:min(gitShortHash, len(e.Commit))]
+		}},
+		{header: "Author", alignment: tablewriter.ALIGN_LEFT, value: func(e FileStat) string {
+			return e.Author
+		}},
+		{header: "Committed", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+			if nil == e.CommitTime {
+				return ""
+			}
+			if relativeOnly {
+				return formatAge(*e.CommitTime, now)
+			}
+			return e.CommitTime.Format(timeLayout)
+		}},
+	}
+}
+
+// ageUnits - units used by formatAge, from largest to smallest
+var ageUnits = []struct {
+	suffix string
+	length time.Duration
+}{
+	{"y", 365 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// formatAge - render the time between t and now using the two largest units, such as "3d 4h ago" or "2y ago"
+func formatAge(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	// find the largest unit, then add the next smaller unit when it is not zero
+	age := "0s"
+	for i, u := range ageUnits {
+		if d < u.length {
+			continue
+		}
+		n := d / u.length
+		age = fmt.Sprintf("%d%s", n, u.suffix)
+		if i+1 < len(ageUnits) {
+			next := ageUnits[i+1]
+			if m := (d - n*u.length) / next.length; m > 0 {
+				age += fmt.Sprintf(" %d%s", m, next.suffix)
+			}
+		}
+		break
+	}
+
+	if future {
+		return "in " + age
+	}
+	return age + " ago"
+}
+
+// ageColumn - time since the last modification, such as "3d 4h ago" (-rel cmd line option)
+func ageColumn() extraColumn {
+	now := time.Now()
+	return extraColumn{header: "Age", alignment: tablewriter.ALIGN_RIGHT, value: func(e FileStat) string {
+		return formatAge(e.ModTime, now)
+	}}
+}
@@ -0,0 +1,106 @@
+/*
+
+command.go
+
+Subcommands, which are shorter names for commonly used cmd line options
+
+Example: fstat diff yesterday.gz -r dirs.txt
+This is the same as: fstat -diff yesterday.gz -r dirs.txt
+
+A subcommand is only recognized as the first argument. Running fstat without one works as it always has;
+when a file with the same name as a subcommand exists in the current directory, it is used as the file list
+instead, so that existing scripts are not affected. Use ./list to refer to such a file explicitly.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// subcommand - a name for one of the cmd line options
+type subcommand struct {
+	name    string
+	option  string
+	arg     string
+	summary string
+}
+
+// subcommands - every subcommand, in the order they are listed by usage; an empty option is the same as no subcommand
+var subcommands = []subcommand{
+	{"list", "", "", "output the entries; the same as not giving a subcommand"},
+	{"sum", "ts", "", "output only a JSON summary of the entries, same as: -ts"},
+	{"dups", "dups", "", "output groups of files with identical contents, same as: -dups"},
+	{"top", "top", "N", "output the N largest files and their share of the total size, same as: -top N"},
+	{"oldnew", "oldnew", "N", "output the N oldest and N newest entries, same as: -oldnew N"},
+	{"samename", "samename", "", "output groups of files with the same base name in different directories, same as: -samename"},
+	{"badnames", "badnames", "", "output the names which break other tools, with a suggested name for each, same as: -badnames"},
+	{"longpaths", "maxpath=" + strconv.Itoa(windowsMaxPath), "", "output the paths that are too long for Windows, same as: -maxpath 260"},
+	{"portable", "portable", "", "output the names which can not be used on Windows, same as: -portable"},
+	{"du", "du", "", "output the cumulative size of each directory, same as: -du"},
+	{"snapshot", "snapshot-save", "FILE", "also save the entries to FILE, same as: -snapshot-save FILE"},
+	{"diff", "diff", "SNAPSHOT", "output the changes since SNAPSHOT was saved, same as: -diff SNAPSHOT"},
+	{"verify", "baseline", "SNAPSHOT", "exit with status 6 when anything changed since SNAPSHOT was saved, same as: -baseline SNAPSHOT"},
+	{"cmp", "cmp", "", "compare two directory trees given as: DIR_A DIR_B, same as: -cmp"},
+	{"watch", "watch", "", "output create, modify and delete events after the entries, same as: -watch"},
+	{"explain", "explain", "", "output every file name and the filter option that excluded it, same as: -explain"},
+	{"help", "h", "", "output this usage"},
+}
+
+/*
+expandSubcommand replaces a subcommand with its cmd line option
+
+Args:
+    args: the program name and its arguments, such as os.Args
+
+Returns:
+    args with the subcommand replaced; args is returned unchanged when it does not start with a subcommand
+
+    an error when the subcommand requires an argument that was not given
+*/
+func expandSubcommand(args []string) ([]string, error) {
+	if len(args) < 2 {
+		return args, nil
+	}
+	for _, cmd := range subcommands {
+		if cmd.name != args[1] {
+			continue
+		}
+		if _, err := os.Stat(cmd.name); err == nil {
+			// an existing file list
+			return args, nil
+		}
+
+		expanded := []string{args[0]}
+		rest := args[2:]
+		switch {
+		case 0 == len(cmd.option):
+		case 0 == len(cmd.arg):
+			expanded = append(expanded, "-"+cmd.option)
+		case 0 == len(rest) || 0 == len(rest[0]) || '-' == rest[0][0]:
+			return nil, fmt.Errorf("%s requires: %s", cmd.name, cmd.arg)
+		default:
+			expanded = append(expanded, "-"+cmd.option+"="+rest[0])
+			rest = rest[1:]
+		}
+		return append(expanded, rest...), nil
+	}
+	return args, nil
+}
+
+// printSubcommands - output each subcommand and its summary, for usage
+func printSubcommands(w io.Writer) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range subcommands {
+		name := cmd.name
+		if len(cmd.arg) > 0 {
+			name += " " + cmd.arg
+		}
+		fmt.Fprintf(w, "  %-18s %s\n", name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\n")
+}
@@ -0,0 +1,326 @@
+/*
+
+config.go
+
+Default cmd line options loaded from TOML configuration files, used unless the -noconfig cmd line option is given,
+and from environment variables, see env.go
+
+Options are read from fstat/config.toml in the user's configuration directory, such as ~/.config/fstat/config.toml,
+and then from .fstat.toml in the current directory, which takes precedence. Each key is the name of a cmd line
+option without the leading dash, and each value is a string, number or boolean. For example:
+
+    # sort by size, descending, and show sizes in mebibytes with commas
+    sS = true
+    m = true
+    c = true
+    er = "\\.git|node_modules"
+    tz = "America/New_York"
+
+A profile is a named set of options in the profiles table, selected with -profile. Each profile is either a string
+of cmd line options, or a table of options as above:
+
+    [profiles]
+    cleanup = "-sS -t -dn 20240101"
+
+    [profiles.audit]
+    oc = true
+    user = "root"
+
+As the current directory may be a checkout from anyone, .fstat.toml can only set the options in configLocal,
+which choose what is shown and how; any other option, such as -notify or -osqlite, is ignored with a warning, so
+that running fstat within a directory never sends the file list elsewhere, writes files or runs commands.
+
+Options set by environment variables take precedence over configuration files, the options of the selected profile
+take precedence over both, and options given on the cmd line take precedence over everything else. This includes
+options that can not be used together: when any sort, output format or size unit option is given, the options of
+that kind from a lower precedence are not used.
+
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configFileName - the name of the configuration file in the current directory
+const configFileName = ".fstat.toml"
+
+// configProfiles - the table of named profiles in a configuration file, selected with the -profile cmd line option
+const configProfiles = "profiles"
+
+// configExclusive - cmd line options that replace each other; an option is not used when any other option of its kind was given with a higher precedence
+var configExclusive = [][]string{
+	{"ss", "sS", "sd", "sD", "sn", "sN", "si", "sI"},
+	{"oc", "ot", "oh", "oj", "ojl", "ocbor", "opb", "oh-treemap", "oncdu", "osqlite", "opq", "names", "format", "printf", "print0", "xargs", "ts", "group", "hist", "timeline"},
+	{"m", "dec"},
+	{"q", "verbosity"},
+	{"utc", "tz"},
+}
+
+// configLocal - the only options which can be set by .fstat.toml in the current directory: the sort, display,
+// filter and output format options, which do not write files, send the entries elsewhere, or run commands
+var configLocal = map[string]bool{
+	"ss": true, "sS": true, "sd": true, "sD": true, "sn": true, "sN": true, "si": true, "sI": true,
+	"q": true, "verbosity": true, "log": true, "loglevel": true, "progress": true, "stats": true, "errors": true,
+	"c": true, "m": true, "dec": true, "M": true, "ns": true, "datefmt": true, "rfc3339": true, "utc": true, "tz": true,
+	"rel": true, "relonly": true, "highlight": true, "t": true, "tm": true, "tx": true, "ts": true,
+	"mode": true, "devnum": true, "alloc": true, "gzsize": true, "entropy": true, "class": true, "shebang": true,
+	"imgsize": true, "duration": true, "pages": true, "long": true, "longwidth": true, "truncate": true,
+	"shorten": true, "minwidth": true, "abs": true, "relto": true, "unc": true, "splitname": true, "du": true,
+	"oc": true, "ot": true, "oh": true, "links": true, "linkbase": true, "title": true, "theme": true, "oj": true,
+	"ojl": true, "ocbor": true, "opb": true, "oh-treemap": true, "oncdu": true, "names": true, "format": true,
+	"printf": true, "print0": true, "xargs": true, "xargsmax": true, "stream": true, "sortchunk": true,
+	"group": true, "groupdepth": true, "hist": true, "timeline": true,
+	"explain": true, "dups": true, "top": true, "oldnew": true, "samename": true, "badnames": true,
+	"maxpath": true, "portable": true,
+	"type": true, "if": true, "id": true, "il": true, "ip": true, "is": true, "ed": true, "er": true, "ir": true,
+	"fuzzy": true, "noignore": true, "ext": true, "dn": true, "do": true, "an": true, "ao": true, "user": true,
+	"grp": true, "perm": true, "where": true, "text": true, "binary": true, "sparse": true,
+	"r": true, "mindepth": true, "maxdepth": true, "L": true, "xdev": true, "archives": true, "clean": true,
+	"cleanabs": true, "nettimeout": true, "timeout": true,
+}
+
+// configFiles - the configuration files in the order they are read
+func configFiles() []string {
+	var allFiles []string
+	var user string
+	if dir, err := os.UserConfigDir(); err == nil {
+		user = filepath.Join(dir, "fstat", "config.toml")
+		allFiles = append(allFiles, user)
+	}
+	if local, err := filepath.Abs(configFileName); err == nil && local != user {
+		allFiles = append(allFiles, local)
+	}
+	return allFiles
+}
+
+/*
+configOptions converts the options of a configuration file, or of one of its profiles, to strings
+
+Args:
+    source: where the options are from, used in error messages
+
+    raw: the decoded TOML keys and values
+
+Returns:
+    the value of each option, converted to a string as it would be given on the cmd line
+
+    an error when an option is unknown or its value is not a string, number or boolean
+*/
+func configOptions(source string, raw map[string]interface{}) (map[string]string, error) {
+	options := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if nil == flag.Lookup(name) {
+			return nil, fmt.Errorf("%s: unknown option: %s", source, name)
+		}
+		switch v := value.(type) {
+		case string:
+			options[name] = v
+		case bool:
+			options[name] = strconv.FormatBool(v)
+		case int64:
+			options[name] = strconv.FormatInt(v, 10)
+		case float64:
+			options[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("%s: %s must be a string, number or boolean", source, name)
+		}
+	}
+	return options, nil
+}
+
+/*
+readConfigFile returns all options and profiles listed in fname
+
+Args:
+    fname: the configuration file; a missing file is not an error
+
+Returns:
+    the value of each option, converted to a string as it would be given on the cmd line
+
+    the options of each profile in the profiles table
+
+    an error when the file can not be read or is not valid
+*/
+func readConfigFile(fname string) (map[string]string, map[string]map[string]string, error) {
+	var raw map[string]interface{}
+	if _, err := toml.DecodeFile(fname, &raw); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	profiles := make(map[string]map[string]string)
+	if rawProfiles, ok := raw[configProfiles]; ok {
+		delete(raw, configProfiles)
+		table, ok := rawProfiles.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: %s must be a table", fname, configProfiles)
+		}
+		for name, value := range table {
+			source := fmt.Sprintf("%s: profile %s", fname, name)
+			var err error
+			switch v := value.(type) {
+			case string:
+				profiles[name], err = parseOptions(source, v)
+			case map[string]interface{}:
+				profiles[name], err = configOptions(source, v)
+			default:
+				err = fmt.Errorf("%s must be a string of cmd line options or a table", source)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := profiles[name]["profile"]; ok {
+				return nil, nil, fmt.Errorf("%s can not include another profile", source)
+			}
+		}
+	}
+
+	options, err := configOptions(fname, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if configFileName == filepath.Base(fname) {
+		restrictLocal(fname, options)
+		for name := range profiles {
+			restrictLocal(fmt.Sprintf("%s: profile %s", fname, name), profiles[name])
+		}
+	}
+	return options, profiles, nil
+}
+
+// restrictLocal - remove each option of .fstat.toml in the current directory which is not in configLocal
+func restrictLocal(source string, options map[string]string) {
+	for name := range options {
+		if !configLocal[name] {
+			logWarn("%s: -%s is ignored; it can only be set in the cmd line, an environment variable, or the configuration directory", source, name)
+			delete(options, name)
+		}
+	}
+}
+
+/*
+overridden returns the options that are replaced by the given options
+
+Args:
+    given: the names of options set with a higher precedence
+
+Returns:
+    the names in given, along with every option of the same kind, see configExclusive
+*/
+func overridden(given map[string]bool) map[string]bool {
+	replaced := make(map[string]bool, len(given))
+	for name := range given {
+		replaced[name] = true
+	}
+	for _, kind := range configExclusive {
+		for _, name := range kind {
+			if given[name] {
+				for _, other := range kind {
+					replaced[other] = true
+				}
+				break
+			}
+		}
+	}
+	return replaced
+}
+
+/*
+mergeOptions adds options to defaults, removing the defaults that they replace
+
+Args:
+    defaults: options with a lower precedence, which is updated
+
+    options: options with a higher precedence
+*/
+func mergeOptions(defaults map[string]string, options map[string]string) {
+	given := make(map[string]bool, len(options))
+	for name := range options {
+		given[name] = true
+	}
+	for name := range overridden(given) {
+		delete(defaults, name)
+	}
+	for name, value := range options {
+		defaults[name] = value
+	}
+}
+
+/*
+ApplyConfig sets each option from the configuration files and environment variables that was not given on the cmd line
+This is called after flag.Parse(), so that the cmd line options are known
+
+Args:
+    readFiles: when not set, only environment variables are used (-noconfig cmd line option)
+
+    profile: use the options of this profile (-profile cmd line option); when empty, a profile option set by a
+             configuration file or environment variable is used
+
+Returns:
+    an error when a configuration file or environment variable is not valid, or sets an option to an invalid value
+*/
+func ApplyConfig(readFiles bool, profile string) error {
+	options := make(map[string]string)
+	profiles := make(map[string]map[string]string)
+	if readFiles {
+		for _, fname := range configFiles() {
+			fileOptions, fileProfiles, err := readConfigFile(fname)
+			if err != nil {
+				return err
+			}
+			mergeOptions(options, fileOptions)
+			for name, profile := range fileProfiles {
+				profiles[name] = profile
+			}
+		}
+	}
+	envOptions, err := readEnvOptions()
+	if err != nil {
+		return err
+	}
+	mergeOptions(options, envOptions)
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	if 0 == len(profile) {
+		profile = options["profile"]
+	}
+	if len(profile) > 0 {
+		profileOptions, ok := profiles[profile]
+		if !ok {
+			if !readFiles {
+				return fmt.Errorf("-profile can not be used with: -noconfig")
+			}
+			return fmt.Errorf("unknown profile: %s; add it to the %s table of a configuration file", profile, configProfiles)
+		}
+		mergeOptions(options, profileOptions)
+	}
+	if 0 == len(options) {
+		return nil
+	}
+
+	given = overridden(given)
+
+	for name, value := range options {
+		if given[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid default value for %s: %s", name, err)
+		}
+	}
+	return nil
+}
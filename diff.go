@@ -0,0 +1,164 @@
+/*
+
+diff.go
+
+Compare a saved snapshot to the current entries, used by the -diff cmd line option
+
+The current entries are either from a live scan or from -snapshot-load, so two snapshots can also be compared.
+Both sides are examined with the same filter options, so a filter such as -ext does not cause every other file
+to be reported as removed.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// FileChange - a single difference between two scans
+type FileChange struct {
+	Change     string     `json:"change"`
+	FullName   string     `json:"name"`
+	FileType   string     `json:"type"`
+	OldSize    *int64     `json:"oldsize,omitempty"`
+	NewSize    *int64     `json:"newsize,omitempty"`
+	OldModTime *time.Time `json:"oldmodtime,omitempty"`
+	NewModTime *time.Time `json:"newmodtime,omitempty"`
+}
+
+// changeKinds - all values of FileChange.Change, in the order they are counted below the table
+var changeKinds = []string{"added", "removed", "resized", "redated"}
+
+/*
+ComputeChanges compares two scans by file name
+
+Args:
+    oldEntries: the entries of the earlier scan, such as from a snapshot
+
+    newEntries: the entries of the current scan
+
+Returns:
+    the added, removed, resized and redated entries, sorted by name
+    when both the size and modified time changed, Change is: resized+redated
+*/
+func ComputeChanges(oldEntries []FileStat, newEntries []FileStat) []FileChange {
+	allOld := make(map[string]*FileStat, len(oldEntries))
+	for i := range oldEntries {
+		allOld[oldEntries[i].FullName] = &oldEntries[i]
+	}
+
+	changes := []FileChange{}
+	for i := range newEntries {
+		n := &newEntries[i]
+		o, ok := allOld[n.FullName]
+		if !ok {
+			changes = append(changes, FileChange{Change: "added", FullName: n.FullName, FileType: n.FileType, NewSize: &n.Size, NewModTime: &n.ModTime})
+			continue
+		}
+		delete(allOld, n.FullName)
+
+		var kinds []string
+		if o.Size != n.Size {
+			kinds = append(kinds, "resized")
+		}
+		if !o.ModTime.Equal(n.ModTime) {
+			kinds = append(kinds, "redated")
+		}
+		if len(kinds) > 0 {
+			changes = append(changes, FileChange{Change: strings.Join(kinds, "+"), FullName: n.FullName, FileType: n.FileType, OldSize: &o.Size, NewSize: &n.Size, OldModTime: &o.ModTime, NewModTime: &n.ModTime})
+		}
+	}
+	for _, o := range allOld {
+		changes = append(changes, FileChange{Change: "removed", FullName: o.FullName, FileType: o.FileType, OldSize: &o.Size, OldModTime: &o.ModTime})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FullName < changes[j].FullName
+	})
+	return changes
+}
+
+// shownChanges - the changes not removed by the -type cmd line option
+func shownChanges(changes []FileChange, onlyTypes string) []FileChange {
+	shown := []FileChange{}
+	for _, c := range changes {
+		if !typeIncluded(onlyTypes, c.FileType) {
+			continue
+		}
+		shown = append(shown, c)
+	}
+	return shown
+}
+
+/*
+RenderChanges outputs the differences found by ComputeChanges
+
+Args:
+    changes: created by ComputeChanges or CompareDirs
+
+    kinds: all possible changes, which are counted below the table, such as changeKinds
+
+    oldLabel, newLabel: the column name prefixes for each side, such as: Old, New
+
+    options: how the changes are output (-c, -m, -dec, -type, -oc, -ot, -oj and -csvdelim cmd line options)
+
+Returns:
+    the number of changes that were output
+*/
+func RenderChanges(changes []FileChange, kinds []string, oldLabel string, newLabel string, options renderOptions) int {
+	shown := shownChanges(changes, options.onlyTypes)
+	counts := make(map[string]int)
+	for _, c := range shown {
+		for _, kind := range strings.Split(c.Change, "+") {
+			counts[kind]++
+		}
+	}
+
+	if options.outputJSON {
+		j, _ := json.MarshalIndent(shown, "", "    ")
+		fmt.Println(string(j))
+		return len(shown)
+	}
+
+	var allRows [][]string
+	for _, c := range shown {
+		row := []string{c.Change, "", "", "", "", c.FullName}
+		if c.OldSize != nil {
+			row[1] = formatSize(*c.OldSize, options.addCommas, options.convertToMiB, options.useSI)
+			row[3] = c.OldModTime.Format(options.timeLayout)
+		}
+		if c.NewSize != nil {
+			row[2] = formatSize(*c.NewSize, options.addCommas, options.convertToMiB, options.useSI)
+			row[4] = c.NewModTime.Format(options.timeLayout)
+		}
+		allRows = append(allRows, row)
+	}
+	header := []string{"Change", oldLabel + " Size", newLabel + " Size", oldLabel + " Mod Time", newLabel + " Mod Time", "Name"}
+
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, nil)
+		return len(shown)
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, nil)
+		return len(shown)
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	var totals []string
+	for _, kind := range kinds {
+		totals = append(totals, fmt.Sprintf("%s: %d", kind, counts[kind]))
+	}
+	fmt.Println(strings.Join(totals, "  "))
+	return len(shown)
+}
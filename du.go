@@ -0,0 +1,66 @@
+/*
+
+du.go
+
+Cumulative directory sizes, used by the -du cmd line option
+
+*/
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+/*
+diskUsage walks a directory tree and sums the sizes of all regular files found within it
+Symbolic links are not followed
+
+Args:
+    dir: the directory to walk
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    sameDevice: when set, do not descend into directories on other file systems (cmd line option: -xdev)
+
+Returns:
+    the cumulative size of all regular files in dir and its subdirectories
+*/
+func diskUsage(dir string, quiet bool, sameDevice bool) int64 {
+	var total int64
+	var device uint64
+	if sameDevice {
+		if info, err := os.Lstat(dir); err == nil {
+			device = getDevice(dir, info)
+		}
+	}
+
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if !quiet {
+				logError("%s", err)
+			}
+			return nil
+		}
+		if sameDevice && d.IsDir() && p != dir {
+			if info, err := d.Info(); err == nil && getDevice(p, info) != device {
+				return filepath.SkipDir
+			}
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if !quiet {
+				logError("%s", err)
+			}
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	return total
+}
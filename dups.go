@@ -0,0 +1,164 @@
+/*
+
+dups.go
+
+Find files with identical contents, used by the -dups cmd line option
+
+Example: fstat -dups -r dirs.txt
+
+Only files of the same size are read, and their contents are compared by SHA-256 hash.
+Empty files are not reported, since they are all the same. The groups are output largest first.
+Hard links to the same file are listed in its group, but are read and counted once, since removing one of them
+does not free any space; a group of only hard links is not reported.
+
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// dupGroup - files with identical contents
+type dupGroup struct {
+	Hash string `json:"sha256"`
+	Size int64  `json:"size"`
+	// the number of distinct files, where all hard links to a file count as one
+	Copies int        `json:"copies"`
+	Files  []FileStat `json:"files"`
+}
+
+/*
+FindDuplicates returns the groups of files with identical contents
+
+Args:
+    ctx: once done, no more files are hashed, and only the groups found so far are returned, see cancel.go
+
+    allEntries: the entries to compare; only files are compared
+
+    quiet: when set, files that can not be read are not reported to STDERR (cmd line option: -q)
+
+Returns:
+    each group of two or more distinct files with the same contents, sorted by size descending, and then by hash
+*/
+func FindDuplicates(ctx context.Context, allEntries []FileStat, quiet bool) []dupGroup {
+	bySize := make(map[int64][]FileStat)
+	for _, e := range allEntries {
+		if "F" == e.FileType && e.Size > 0 {
+			bySize[e.Size] = append(bySize[e.Size], e)
+		}
+	}
+
+	var groups []dupGroup
+	for size, entries := range bySize {
+		if ctx.Err() != nil {
+			break
+		}
+		if len(entries) < 2 {
+			continue
+		}
+		byHash := make(map[string][]FileStat)
+		copies := make(map[string]int)
+		// the hash of each file by its device and inode, so that hard links to it are only read once
+		linked := make(map[fileID]string)
+		for _, e := range entries {
+			var id fileID
+			var haveID bool
+			if info, err := os.Lstat(e.statName); err == nil {
+				id, haveID = getFileID(e.statName, info)
+			}
+			if key, ok := linked[id]; haveID && ok {
+				byHash[key] = append(byHash[key], e)
+				continue
+			}
+			hash, err := hashFile(ctx, e.statName)
+			if err != nil {
+				if !quiet && nil == ctx.Err() {
+					logError("%s", err)
+				}
+				continue
+			}
+			key := hex.EncodeToString(hash)
+			if haveID {
+				linked[id] = key
+			}
+			byHash[key] = append(byHash[key], e)
+			copies[key]++
+		}
+		for hash, files := range byHash {
+			if copies[hash] > 1 {
+				sort.Slice(files, func(i, j int) bool { return files[i].FullName < files[j].FullName })
+				groups = append(groups, dupGroup{Hash: hash, Size: size, Copies: copies[hash], Files: files})
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+	return groups
+}
+
+/*
+RenderDuplicates outputs each group of files with identical contents
+
+Args:
+    groups: created by FindDuplicates()
+
+    options: how the groups are output (-c, -m, -dec, -oc, -ot, -oj and -csvdelim cmd line options)
+
+Returns:
+    the number of duplicate files, not counting the first file of each group, or more than one hard link to a file
+*/
+func RenderDuplicates(groups []dupGroup, options renderOptions) int {
+	var duplicates int
+	var reclaimable int64
+	for _, g := range groups {
+		duplicates += g.Copies - 1
+		reclaimable += int64(g.Copies-1) * g.Size
+	}
+
+	if options.outputJSON {
+		if nil == groups {
+			groups = []dupGroup{}
+		}
+		j, _ := json.MarshalIndent(groups, "", "    ")
+		fmt.Println(string(j))
+		return duplicates
+	}
+
+	var allRows [][]string
+	for i, g := range groups {
+		for _, e := range g.Files {
+			allRows = append(allRows, []string{strconv.Itoa(i + 1), formatSize(g.Size, options.addCommas, options.convertToMiB, options.useSI), e.ModTime.Format(options.timeLayout), e.FullName})
+		}
+	}
+	header := []string{"Group", "Size", "Mod Time", "Name"}
+
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, nil)
+		return duplicates
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, nil)
+		return duplicates
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("groups: %d  duplicates: %d  reclaimable: %s\n", len(groups), duplicates, formatSize(reclaimable, options.addCommas, options.convertToMiB, options.useSI))
+	return duplicates
+}
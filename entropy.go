@@ -0,0 +1,98 @@
+/*
+
+entropy.go
+
+Estimate how well the contents of files can be compressed, used by the -entropy cmd line option
+
+Example: fstat -entropy -type f -r backups/
+Example: fstat -entropy -oj -r /srv/share | jq '.[] | select(.entropy > 7.5) | .fullname'
+
+The Shannon entropy of the bytes in each file is shown in bits per byte, from 0 for a file which repeats a
+single byte, to 8 for random data. Text is usually between 4 and 5.5, while data which has already been
+compressed or encrypted, such as .zip, .jpg or .gpg files, is close to 8 and will not be made smaller by
+compression or deduplication. Up to three samples of 64 KiB are read from the start, middle and end of each
+file, so that large files are not read in full.
+
+*/
+
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+)
+
+// entropySampleSize - the number of bytes read from each part of a file
+const entropySampleSize = 64 * 1024
+
+// entropySamples - the number of parts of a file which are read, spread evenly from its start to its end
+const entropySamples = 3
+
+// byteEntropy - the Shannon entropy in bits per byte of total bytes, counted by value
+func byteEntropy(counts *[256]int64, total int64) float64 {
+	var entropy float64
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+/*
+fileEntropy estimates the entropy of the contents of a file
+
+Args:
+    fname: the file name
+
+    size: the size of the file; the whole file is read when it is no larger than all of the samples
+
+Returns:
+    the entropy in bits per byte, from 0 to 8; or an error when the file can not be read
+*/
+func fileEntropy(fname string, size int64) (float64, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var counts [256]int64
+	var total int64
+	buf := make([]byte, entropySampleSize)
+	count := func(data []byte) {
+		for _, b := range data {
+			counts[b]++
+		}
+		total += int64(len(data))
+	}
+
+	if size <= entropySampleSize*entropySamples {
+		for {
+			n, err := file.Read(buf)
+			count(buf[:n])
+			if io.EOF == err {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		step := (size - entropySampleSize) / (entropySamples - 1)
+		for i := int64(0); i < entropySamples; i++ {
+			n, err := file.ReadAt(buf, i*step)
+			count(buf[:n])
+			if err != nil && io.EOF != err {
+				return 0, err
+			}
+		}
+	}
+
+	if 0 == total {
+		return 0, nil
+	}
+	return byteEntropy(&counts, total), nil
+}
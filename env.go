@@ -0,0 +1,157 @@
+/*
+
+env.go
+
+Default cmd line options set by environment variables, so that containers and CI jobs do not need to change the invoking script
+
+FSTAT_OPTS contains cmd line options, quoted as they would be for a shell, such as:
+    FSTAT_OPTS="-c -m -er '\.git|node_modules'"
+
+FSTAT_OUTPUT and FSTAT_SORT select an output format or sort order by the name of its cmd line option, such as:
+    FSTAT_OUTPUT=oc FSTAT_SORT=sS
+
+Any other option can be set with FSTAT_ followed by its name in upper case, with - replaced by _, such as:
+    FSTAT_TZ=America/New_York FSTAT_OH_TREEMAP=true
+Options whose upper case names are the same, such as -ss and -sS, can only be set with FSTAT_OPTS or FSTAT_SORT.
+
+FSTAT_PROFILE selects a profile, the same as -profile. Options in FSTAT_OPTS take precedence over the other variables. See config.go for how these are combined with
+configuration files and the cmd line.
+
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// envPrefix - the start of the name of each environment variable
+const envPrefix = "FSTAT_"
+
+// envSelectors - environment variables that select one option of a kind by name, see configExclusive
+var envSelectors = map[string][]string{
+	"OUTPUT": configExclusive[1],
+	"SORT":   configExclusive[0],
+}
+
+// optionRecorder - a flag.Value that records the value of an option instead of setting it
+type optionRecorder struct {
+	name    string
+	isBool  bool
+	options map[string]string
+}
+
+// String - the value is never shown, since the options are not used to output usage
+func (r *optionRecorder) String() string {
+	return ""
+}
+
+// Set - record the value of the option
+func (r *optionRecorder) Set(value string) error {
+	r.options[r.name] = value
+	return nil
+}
+
+// IsBoolFlag - true when the option does not take a value, which is needed to parse the options that follow it
+func (r *optionRecorder) IsBoolFlag() bool {
+	return r.isBool
+}
+
+// isBoolOption - true when the cmd line option does not take a value, such as: -oc
+func isBoolOption(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+/*
+parseOptions returns the options given in a string, such as the FSTAT_OPTS environment variable or a profile
+
+Args:
+    source: where opts is from, used in error messages
+
+    opts: cmd line options, quoted as they would be for a shell
+
+Returns:
+    the value of each option, as it would be given on the cmd line
+
+    an error when opts can not be split into options, or contains an unknown option or a file name
+*/
+func parseOptions(source string, opts string) (map[string]string, error) {
+	args, err := shellquote.Split(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", source, err)
+	}
+
+	options := make(map[string]string)
+	fs := flag.NewFlagSet(source, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flag.VisitAll(func(f *flag.Flag) {
+		fs.Var(&optionRecorder{name: f.Name, isBool: isBoolOption(f), options: options}, f.Name, f.Usage)
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%s: %s", source, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("%s can only contain options, not: %s", source, fs.Arg(0))
+	}
+	return options, nil
+}
+
+/*
+readEnvOptions returns the options set by environment variables
+
+Returns:
+    the value of each option, as it would be given on the cmd line
+
+    an error when a variable is not valid
+*/
+func readEnvOptions() (map[string]string, error) {
+	options := make(map[string]string)
+
+	upperNames := make(map[string][]*flag.Flag)
+	flag.VisitAll(func(f *flag.Flag) {
+		upper := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		upperNames[upper] = append(upperNames[upper], f)
+	})
+	for upper, flags := range upperNames {
+		if _, ok := envSelectors[upper]; ok || "OPTS" == upper || len(flags) > 1 {
+			continue
+		}
+		if value, ok := os.LookupEnv(envPrefix + upper); ok {
+			options[flags[0].Name] = value
+		}
+	}
+
+	for selector, kind := range envSelectors {
+		name, ok := os.LookupEnv(envPrefix + selector)
+		if !ok || 0 == len(name) {
+			continue
+		}
+		name = strings.TrimPrefix(name, "-")
+		var found bool
+		for _, k := range kind {
+			if k == name && isBoolOption(flag.Lookup(k)) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid %s%s: %s; use the name of an option such as: %s", envPrefix, selector, name, kind[0])
+		}
+		mergeOptions(options, map[string]string{name: "true"})
+	}
+
+	if opts := os.Getenv(envPrefix + "OPTS"); len(strings.TrimSpace(opts)) > 0 {
+		optsOptions, err := parseOptions(envPrefix+"OPTS", opts)
+		if err != nil {
+			return nil, err
+		}
+		mergeOptions(options, optsOptions)
+	}
+	return options, nil
+}
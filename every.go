@@ -0,0 +1,150 @@
+/*
+
+every.go
+
+Run the same scan again on a schedule, used by the -every and -deltas cmd line options
+
+Example: fstat -every 5m -deltas -r dirs.txt
+
+Each cycle runs fstat again with all of the other cmd line options, so every output option works as it does
+for a single run. With -deltas, each cycle saves a snapshot to a temporary directory and only the changes
+since the previous cycle are output, using -diff. This runs in the foreground until interrupted, so that it
+can be managed by systemd or by a Windows service wrapper.
+
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+/*
+cycleArgs returns the cmd line options given to each cycle
+
+Args:
+    extra: added before the file list, such as: -diff old.gz
+
+Returns:
+    every cmd line option that was set except -every and -deltas, followed by extra and the file list
+*/
+func cycleArgs(extra ...string) []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if "every" != f.Name && "deltas" != f.Name {
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+		}
+	})
+	args = append(args, extra...)
+	return append(append(args, "--"), flag.Args()...)
+}
+
+/*
+runCycle runs fstat once, with its output going to this program's STDOUT and STDERR
+
+Args:
+    args: the cmd line options
+
+Returns:
+    the exit status, which is 0 on success
+*/
+func runCycle(args []string) int {
+	self, err := os.Executable()
+	if err != nil {
+		logError("%s", err)
+		os.Exit(1)
+	}
+	cmd := exec.Command(self, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		logError("%s", err)
+		return 1
+	}
+	return 0
+}
+
+/*
+RunEvery runs a scan, and then again after each interval until interrupted
+
+Args:
+    interval: the time between the start of each cycle (-every cmd line option)
+
+    deltas: when set, the first cycle outputs all entries and each later cycle only outputs the changes (-deltas cmd line option)
+
+    quiet: when set, failed cycles are not reported to STDERR (cmd line option: -q)
+
+When the first cycle fails, such as from an invalid option, the program exits with its status.
+Later failures are reported and the next cycle still runs.
+*/
+func RunEvery(interval time.Duration, deltas bool, quiet bool) {
+	var snapshotDir, previous, current string
+	if deltas {
+		var err error
+		snapshotDir, err = os.MkdirTemp("", "fstat-every-*")
+		if err != nil {
+			logError("%s", err)
+			os.Exit(1)
+		}
+		previous = filepath.Join(snapshotDir, "previous.gz")
+		current = filepath.Join(snapshotDir, "current.gz")
+	}
+	cleanup := func() {
+		if len(snapshotDir) > 0 {
+			os.RemoveAll(snapshotDir)
+		}
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for cycle := 1; ; cycle++ {
+		var status int
+		switch {
+		case !deltas:
+			status = runCycle(cycleArgs())
+		case 1 == cycle:
+			status = runCycle(cycleArgs("-snapshot-save=" + previous))
+		default:
+			status = runCycle(cycleArgs("-snapshot-save="+current, "-diff="+previous))
+			if 0 == status {
+				if err := os.Rename(current, previous); err != nil {
+					logError("%s", err)
+					cleanup()
+					os.Exit(1)
+				}
+			}
+		}
+		if status != 0 {
+			if 1 == cycle {
+				cleanup()
+				os.Exit(status)
+			}
+			if !quiet {
+				logError("cycle %d exited with status %d", cycle, status)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-interrupted:
+			cleanup()
+			os.Exit(0)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+
+explain.go
+
+Report why each file name was included or excluded, used by the -explain cmd line option
+
+Example: fstat -explain -ext log -dn 20240101 list.txt
+
+Every listed name is output once, in the order it was examined, along with the first filter option that excluded it.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// explanation - the result of the filter options for a single file name
+type explanation struct {
+	FullName string `json:"name"`
+	Included bool   `json:"included"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+/*
+excludeByType applies the -type cmd line option, which are only checked when entries are output
+
+Args:
+    explanations: created while calling GetFileInfo()
+
+    allEntries: returned by GetFileInfo()
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+*/
+func excludeByType(explanations []explanation, allEntries []FileStat, onlyTypes string) {
+	if 0 == len(onlyTypes) {
+		return
+	}
+	fileTypes := make(map[string]string, len(allEntries))
+	for _, e := range allEntries {
+		fileTypes[e.FullName] = e.FileType
+	}
+	for i, x := range explanations {
+		if !x.Included {
+			continue
+		}
+		if !typeIncluded(onlyTypes, fileTypes[x.FullName]) {
+			explanations[i].Included, explanations[i].Reason = false, "-type: type is "+fileTypes[x.FullName]
+		}
+	}
+}
+
+/*
+RenderExplanations outputs every file name and why it was included or excluded
+
+Args:
+    explanations: created while calling GetFileInfo(), and updated by excludeByType()
+
+    outputCSV, outputTSV, outputJSON: alternate output formats (-oc, -ot, -oj cmd line options)
+
+    csvDelimiter: the field delimiter used with outputCSV (-csvdelim cmd line option)
+*/
+func RenderExplanations(explanations []explanation, outputCSV bool, csvDelimiter rune, outputTSV bool, outputJSON bool) {
+	if outputJSON {
+		j, _ := json.MarshalIndent(explanations, "", "    ")
+		fmt.Println(string(j))
+		return
+	}
+
+	var allRows [][]string
+	var included int
+	for _, x := range explanations {
+		result := "excluded"
+		if x.Included {
+			result = "included"
+			included++
+		}
+		allRows = append(allRows, []string{result, x.Reason, x.FullName})
+	}
+	header := []string{"Result", "Reason", "Name"}
+
+	if outputCSV {
+		renderCSV(header, allRows, csvDelimiter, nil)
+		return
+	}
+
+	if outputTSV {
+		renderTSV(header, allRows, nil)
+		return
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("included: %d  excluded: %d\n", included, len(explanations)-included)
+}
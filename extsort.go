@@ -0,0 +1,162 @@
+/*
+
+extsort.go
+
+Sort more entries than fit in memory, used by the -stream cmd line option along with a sort option
+
+Entries are collected into chunks of -sortchunk entries. Each full chunk is sorted and
+written to a temporary file. The temporary files are then merged in sorted order.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"os"
+)
+
+// externalSorter - sorts entries by spilling sorted chunks to temporary files
+type externalSorter struct {
+	less      func(a *FileStat, b *FileStat) bool
+	chunkSize int
+	chunk     []FileStat
+	spills    []*os.File
+}
+
+// mergeSource - the next entry of one temporary file
+type mergeSource struct {
+	entry FileStat
+	dec   *gob.Decoder
+}
+
+// mergeHeap - the next entry of each temporary file, ordered by less
+type mergeHeap struct {
+	sources []*mergeSource
+	less    func(a *FileStat, b *FileStat) bool
+}
+
+func (h mergeHeap) Len() int            { return len(h.sources) }
+func (h mergeHeap) Less(i, j int) bool  { return h.less(&h.sources[i].entry, &h.sources[j].entry) }
+func (h mergeHeap) Swap(i, j int)       { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *mergeHeap) Push(x interface{}) { h.sources = append(h.sources, x.(*mergeSource)) }
+func (h *mergeHeap) Pop() interface{} {
+	last := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return last
+}
+
+/*
+newExternalSorter returns an empty sorter
+
+Args:
+    chunkSize: the number of entries sorted in memory at a time (-sortchunk cmd line option)
+
+    less: the comparison, see entryLess()
+
+Returns:
+    the sorter; call add() for each entry and then merge()
+*/
+func newExternalSorter(chunkSize int, less func(a *FileStat, b *FileStat) bool) *externalSorter {
+	return &externalSorter{less: less, chunkSize: chunkSize}
+}
+
+// sortFailed - report an error with a temporary file and exit; any temporary files are removed first
+func (s *externalSorter) sortFailed(err error) {
+	s.cleanup()
+	logError("unable to sort: %s", err)
+	os.Exit(1)
+}
+
+// cleanup - close and remove all temporary files
+func (s *externalSorter) cleanup() {
+	for _, f := range s.spills {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.spills = nil
+}
+
+// add - collect an entry, writing a sorted chunk to a temporary file once chunkSize entries are collected
+func (s *externalSorter) add(e FileStat) {
+	s.chunk = append(s.chunk, e)
+	if len(s.chunk) >= s.chunkSize {
+		s.spill()
+	}
+}
+
+// spill - sort the current chunk and write it to a new temporary file
+func (s *externalSorter) spill() {
+	sortEntries(s.chunk, s.less)
+	f, err := os.CreateTemp("", "fstat-sort-*")
+	if err != nil {
+		s.sortFailed(err)
+	}
+	s.spills = append(s.spills, f)
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for i := range s.chunk {
+		if err := enc.Encode(&s.chunk[i]); err != nil {
+			s.sortFailed(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		s.sortFailed(err)
+	}
+	s.chunk = s.chunk[:0]
+}
+
+/*
+merge passes every entry to output in sorted order, and then removes all temporary files
+
+Args:
+    output: called for each entry, such as the function returned by newBatchWriter()
+*/
+func (s *externalSorter) merge(output func(e FileStat)) {
+	defer s.cleanup()
+
+	// everything fit into a single chunk, so no temporary files are needed
+	if 0 == len(s.spills) {
+		sortEntries(s.chunk, s.less)
+		for _, e := range s.chunk {
+			output(e)
+		}
+		return
+	}
+	if len(s.chunk) > 0 {
+		s.spill()
+	}
+	s.chunk = nil
+
+	h := &mergeHeap{less: s.less}
+	for _, f := range s.spills {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			s.sortFailed(err)
+		}
+		src := &mergeSource{dec: gob.NewDecoder(bufio.NewReader(f))}
+		if err := src.dec.Decode(&src.entry); err != nil {
+			s.sortFailed(err)
+		}
+		h.sources = append(h.sources, src)
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		src := h.sources[0]
+		output(src.entry)
+		src.entry = FileStat{}
+		err := src.dec.Decode(&src.entry)
+		switch {
+		case io.EOF == err:
+			heap.Pop(h)
+		case err != nil:
+			s.sortFailed(err)
+		default:
+			heap.Fix(h, 0)
+		}
+	}
+}
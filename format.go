@@ -0,0 +1,84 @@
+/*
+
+format.go
+
+Custom per-entry output using a Go template, used by the -format cmd line option
+
+Example: fstat -format '{{.Size}}\t{{.FullName}}' list.txt
+
+All FileStat fields are available, such as: .FullName .Size .ModTime .FileType .Mode .Owner .Group .Access
+These functions are also available:
+    base, dir, ext    parts of a file name, such as: {{base .FullName}}
+    human             a size in KiB, MiB, GiB units, such as: {{human .Size}}
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// formatEscapes - backslash escapes allowed in -format and -printf, since shells do not expand them within single quotes
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r", `\0`, "\x00")
+
+// formatFuncs - functions available to -format templates
+var formatFuncs = template.FuncMap{
+	"base":  filepath.Base,
+	"dir":   filepath.Dir,
+	"ext":   filepath.Ext,
+	"human": humanSize,
+}
+
+// humanSize - render a size in binary (powers of 1024) units, such as "1.5 MiB"
+func humanSize(size int64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	if size < 1024 && size > -1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / 1024
+	unit := 0
+	for (value >= 1023.95 || value <= -1023.95) && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+/*
+RenderTemplate outputs each entry using a Go text/template, followed by a newline
+
+Args:
+    allEntries: a slice of all files, already sorted
+
+    format: the template, backslash escapes such as \t and \n are expanded (-format cmd line option)
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+*/
+func RenderTemplate(allEntries []FileStat, format string, onlyTypes string) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(formatEscapes.Replace(format))
+	if err != nil {
+		logError("invalid 'format' template: %s", err)
+		os.Exit(2)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		if err := tmpl.Execute(w, e); err != nil {
+			w.Flush()
+			logError("%s", err)
+			os.Exit(2)
+		}
+		w.WriteByte('\n')
+	}
+}
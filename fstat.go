@@ -7,7 +7,7 @@ Mar 2019
 Get info for a list of files across multiple directories
 
 To compile:
-go build -ldflags="-s -w" fstat.go render_number.go
+go build -ldflags="-s -w" .
 
 MIT License; Copyright (c) 2021 John Taylor
 Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
@@ -20,19 +20,22 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
-	"path"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/jftuga/ellipsis"
 	"github.com/jftuga/termsize"
 	"github.com/olekukonko/tablewriter"
 )
@@ -46,67 +49,155 @@ const (
 	wantNewer
 )
 const dateFormat = "20060102"
+const dateTimeFormat = "20060102150405"
 
 // FileStat - metadata for each entry
 type FileStat struct {
-	FullName string    `json:"fullname"`
-	Size     int64     `json:"size"`
-	ModTime  time.Time `json:"modtime"`
-	FileType string    `json:"filetype"`
+	FullName  string    `json:"fullname"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modtime"`
+	FileType  string    `json:"filetype"`
+	Owner     string    `json:"owner,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Mode      string    `json:"mode"`
+	Perm      uint32    `json:"-"`
+	Allocated int64     `json:"allocated"`
+	Sparse    bool      `json:"sparse"`
+	Device    uint64    `json:"device"`
+	Access    time.Time `json:"accesstime"`
+	// only set for character and block devices, such as "8:1"
+	DeviceNumber string `json:"devicenumber,omitempty"`
+	// only set with -gzsize, for .gz files
+	Uncompressed *int64 `json:"uncompressed,omitempty"`
+	// only set with -entropy, for files, in bits per byte
+	Entropy *float64 `json:"entropy,omitempty"`
+	// only set with -class, -text, -binary, or a -where expression using class: text or binary, for files
+	Class string `json:"class,omitempty"`
+	// only set with -shebang, or a -where expression using interpreter, for executable scripts
+	Interpreter string `json:"interpreter,omitempty"`
+	// only set with -imgsize, or a -where expression using width or height, for images
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// only set with -duration, or a -where expression using duration, for audio and video files, in seconds
+	Duration float64 `json:"duration,omitempty"`
+	// only set with -pages, or a -where expression using pages, for PDF files
+	Pages int64 `json:"pages,omitempty"`
+	// only set with -git, or a -where expression using commit, author or committed, for entries in a git repository
+	Commit     string     `json:"commit,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	CommitTime *time.Time `json:"committime,omitempty"`
+	// only set with -splitname
+	Directory string `json:"directory,omitempty"`
+	Basename  string `json:"basename,omitempty"`
+	// the name used to examine the file, before it is changed by -abs, -relto or -unc; see SaveSnapshot()
+	statName string
 }
 
-// shortenFileName - shorten file names in the last column
-// this is done by inserting "..." in the middle of a long file path
-func shortenFileName(allRows [][]string, maxWidth int) [][]string {
-	newRows := make([][]string, len(allRows))
+// sparseMinHole - a file is only considered sparse when at least this many bytes are not allocated
+const sparseMinHole = 4096
 
-	for i := 0; i < len(allRows); i++ {
-		row := allRows[i]
-		row[3] = ellipsis.Shorten(row[3], maxWidth)
-		//fmt.Println(row)
-		newRows = append(newRows, row)
-	}
-	return newRows
+// isSparse - true when the allocated size is less than half of the apparent size
+func isSparse(size int64, allocated int64) bool {
+	return allocated < size/2 && size-allocated >= sparseMinHole
 }
 
 /*
-sortSize sorts the FileStat slice by file sizes
-If ascending is true, the list is sorted from smallest to largest
+sizeLess returns a comparison for sorting by file size
+If ascending is true, from smallest to largest
 Otherwise, largest to smallest
-cmd line options: -ss and -sS
 */
-func sortSize(entry []FileStat, ascending bool) {
-	sort.Slice(entry, func(i, j int) bool {
-		if entry[i].Size > entry[j].Size {
+func sizeLess(ascending bool) func(a *FileStat, b *FileStat) bool {
+	return func(a *FileStat, b *FileStat) bool {
+		if a.Size > b.Size {
 			return !ascending
 		}
-		if entry[i].Size < entry[j].Size {
+		if a.Size < b.Size {
 			return ascending
 		}
 		// when multiple files have the same Size, then alphabetize by file name
-		return entry[i].FullName < entry[j].FullName
-	})
+		return a.FullName < b.FullName
+	}
 }
 
 /*
-sortModTime sorts the FileStat slice by file modification time
-If ascending is true, the list is sorted from oldest to newest
+modTimeLess returns a comparison for sorting by file modification time
+If ascending is true, from oldest to newest
 Otherwise, newest to oldest
-cmd line options: -sd and -sD
 */
-func sortModTime(entry []FileStat, ascending bool) {
-	sort.Slice(entry, func(i, j int) bool {
-		if entry[i].ModTime.After(entry[j].ModTime) {
+func modTimeLess(ascending bool) func(a *FileStat, b *FileStat) bool {
+	return func(a *FileStat, b *FileStat) bool {
+		if a.ModTime.After(b.ModTime) {
 			return !ascending
 		}
-		if entry[i].ModTime.Before(entry[j].ModTime) {
+		if a.ModTime.Before(b.ModTime) {
 			return ascending
 		}
 		// when multiple files have the same Mod Time, then alphabetize by file name
-		return entry[i].FullName < entry[j].FullName
+		return a.FullName < b.FullName
+	}
+}
+
+/*
+nameLess returns a comparison for sorting by file name
+If ascending is true, in alphabetical order
+Otherwise, reverse alphabetical order
+*/
+func nameLess(ascending bool) func(a *FileStat, b *FileStat) bool {
+	return func(a *FileStat, b *FileStat) bool {
+		if ascending {
+			return a.FullName < b.FullName
+		}
+		return a.FullName > b.FullName
+	}
+}
+
+/*
+nameCaseInsensitiveLess returns a comparison for sorting by file name, ignoring case
+This is done by making all names lower case before comparing names
+If ascending is true, in alphabetical order
+Otherwise, reverse alphabetical order
+*/
+func nameCaseInsensitiveLess(ascending bool) func(a *FileStat, b *FileStat) bool {
+	return func(a *FileStat, b *FileStat) bool {
+		lowerA, lowerB := strings.ToLower(a.FullName), strings.ToLower(b.FullName)
+		if lowerA == lowerB {
+			// when names only differ by case, then use the same order every time
+			return a.FullName < b.FullName
+		}
+		if ascending {
+			return lowerA < lowerB
+		}
+		return lowerA > lowerB
+	}
+}
+
+// sortEntries - sort the FileStat slice with one of the comparisons above
+func sortEntries(entry []FileStat, less func(a *FileStat, b *FileStat) bool) {
+	sort.Slice(entry, func(i, j int) bool {
+		return less(&entry[i], &entry[j])
 	})
 }
 
+/*
+sortSize sorts the FileStat slice by file sizes
+If ascending is true, the list is sorted from smallest to largest
+Otherwise, largest to smallest
+cmd line options: -ss and -sS
+*/
+func sortSize(entry []FileStat, ascending bool) {
+	sortEntries(entry, sizeLess(ascending))
+}
+
+/*
+sortModTime sorts the FileStat slice by file modification time
+If ascending is true, the list is sorted from oldest to newest
+Otherwise, newest to oldest
+cmd line options: -sd and -sD
+*/
+func sortModTime(entry []FileStat, ascending bool) {
+	sortEntries(entry, modTimeLess(ascending))
+}
+
 /*
 sortName sorts the FileStat slice by file name
 If ascending is true, the list is sorted in alphabetical order
@@ -114,28 +205,17 @@ Otherwise, reverse alphabetical order
 cmd line options: -sn and -sN
 */
 func sortName(entry []FileStat, ascending bool) {
-	sort.Slice(entry, func(i, j int) bool {
-		if ascending {
-			return entry[i].FullName < entry[j].FullName
-		}
-		return entry[i].FullName > entry[j].FullName
-	})
+	sortEntries(entry, nameLess(ascending))
 }
 
 /*
 sortNameCaseInsensitive sorts the FileStat slice by file name, ignoring case
-This is done by making all names lower case before comparing names
 If ascending is true, the list is sorted in alphabetical order
 Otherwise, reverse alphabetical order
 cmd line options: -si and -sI
 */
 func sortNameCaseInsensitive(entry []FileStat, ascending bool) {
-	sort.Slice(entry, func(i, j int) bool {
-		if ascending {
-			return strings.ToLower(entry[i].FullName) < strings.ToLower(entry[j].FullName)
-		}
-		return strings.ToLower(entry[i].FullName) > strings.ToLower(entry[j].FullName)
-	})
+	sortEntries(entry, nameCaseInsensitiveLess(ascending))
 }
 
 /*
@@ -157,7 +237,7 @@ func GetFileList(input *bufio.Scanner) []string {
 }
 
 /*
-roundToLocalTime converts from UTC to Local time and rounds the day
+roundToLocalTime parses a date in the Local time zone and rounds the day
 to either the start of the day or end of the day depending on the value of olderOrNewer
 
 Args:
@@ -176,450 +256,1466 @@ Returns:
     Example: given modTime of 20190325; then "2019-03-24 23:59:59.999999999 -0400 EDT" is returned
     (when Local time zone is: Eastern Daylight Savings)
 */
-//goland:noinspection GoUnhandledErrorResult
 func roundToLocalTime(olderOrNewer int, modTime string) time.Time {
 	// set up time.Time variables for dateOlder and dateNewer; -do and -dn
-	// roundedModTime will be rounded down
-	roundedModTime, err := time.Parse(dateFormat, modTime)
+	// the date is parsed as midnight in the Local time zone
+	roundedModTime, err := time.ParseInLocation(dateFormat, modTime, time.Local)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error when parsing date:", modTime)
-		fmt.Fprintln(os.Stderr, "Date format should be  : YYYYMMDD")
+		logError("unable to parse date: %s; the format should be: YYYYMMDD", modTime)
 		os.Exit(5)
 	}
 
-	//fmt.Println("[start.1] roundedModTime: ", roundedModTime)
-	roundedModTime = roundedModTime.In(time.Local)
-	//fmt.Println("[start.2] roundedModTime: ", roundedModTime)
-	roundedModTime = time.Date(roundedModTime.Year(), roundedModTime.Month(), roundedModTime.Day(), 23, 59, 59, 999999999, roundedModTime.Location())
-	//fmt.Println("[start.3] roundedModTime: ", roundedModTime)
+	// roundedModTime will be rounded down to 1 nanosecond before the day starts
+	roundedModTime = roundedModTime.Add(-time.Nanosecond)
 
 	if olderOrNewer == wantOlder {
 		// add 1 day so that a file with a timestamp of 23:59:59 (of the same day) will be included
-		roundedModTime = roundedModTime.Add(time.Hour * 24)
-		//fmt.Println("[older.4] roundedModTime: ", roundedModTime)
+		roundedModTime = roundedModTime.AddDate(0, 0, 1)
 	}
 	return roundedModTime
 }
 
+/*
+parseDateFilter converts the value given to -dn or -do into a time
+
+Args:
+    olderOrNewer: should be either wantOlder or wantNewer, depending on which files you want
+
+    value: either YYYYMMDD, YYYYMMDDHHMMSS (in the Local time zone), or an RFC 3339 time stamp
+
+Returns:
+    a time that can be compared to a file's modification time
+    YYYYMMDD values are rounded to a whole day by roundToLocalTime(); other values are used as is
+*/
+func parseDateFilter(olderOrNewer int, value string) (time.Time, error) {
+	switch len(value) {
+	case len(dateFormat):
+		if _, err := time.Parse(dateFormat, value); err != nil {
+			return time.Time{}, err
+		}
+		return roundToLocalTime(olderOrNewer, value), nil
+	case len(dateTimeFormat):
+		return time.ParseInLocation(dateTimeFormat, value, time.Local)
+	default:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+}
+
+// matchesAccount - true when actual is the wanted user or group name
+// for Windows style DOMAIN\name accounts, wanted may omit the domain and is compared case-insensitively
+func matchesAccount(actual string, wanted string) bool {
+	if actual == wanted {
+		return true
+	}
+	if i := strings.LastIndex(actual, `\`); i >= 0 && !strings.Contains(wanted, `\`) {
+		return strings.EqualFold(actual[i+1:], wanted)
+	}
+	return strings.EqualFold(actual, wanted) && strings.Contains(actual, `\`)
+}
+
+// hasExtension - true when fname ends with one of the lower-cased extensions, such as ".log" or ".tar.gz"
+func hasExtension(fname string, allExtensions []string) bool {
+	base := strings.ToLower(filepath.Base(fname))
+	for _, ext := range allExtensions {
+		if strings.HasSuffix(base, ext) && len(base) > len(ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanOptions - the filter options of GetFileInfo(), and what else to find for each file, from the cmd line
+type scanOptions struct {
+	// errors are not reported to STDERR (cmd line option: -q)
+	quiet bool
+	// exclude dot files (cmd line option: -ed)
+	excludeDot bool
+	// when set, exclude based on this regular expression (-er cmd line option)
+	excludeRE string
+	// when set, only include based on this regular expression (-ir cmd line option)
+	includeRE string
+	// when set, only include if the file name matches this fzf style query, see fuzzy.go (-fuzzy cmd line option)
+	fuzzy string
+	// exclude files matching any of these patterns, see ignore.go
+	ignorePatterns []string
+	// when set, only include files ending with one of these comma separated extensions, ignoring case (-ext cmd
+	// line option)
+	extensions string
+	// when set, only include if date is equal or newer, or equal or older, than the given date, see parseDateFilter()
+	// (-dn and -do cmd line options)
+	dateNewer string
+	dateOlder string
+	// the same as dateNewer and dateOlder, for the last access time (-an and -ao cmd line options)
+	accessNewer string
+	accessOlder string
+	// when not 0, only include if file size is equal or smaller, or equal or larger, than this number of bytes (-szs
+	// and -szl cmd line options)
+	sizeSmaller int64
+	sizeLarger  int64
+	// look up the name of the user and group that own each file
+	lookupOwner bool
+	lookupGroup bool
+	// when set, only include files owned by this user or group name, or uid or gid (-user and -grp cmd line options)
+	userFilter  string
+	groupFilter string
+	// when set, only include files whose permission bits match, see perm.go (-perm cmd line option)
+	permission string
+	// when set, only include files matching this filter expression, see where.go (-where cmd line option)
+	where string
+	// the size of a directory is the cumulative size of all files within it (-du cmd line option)
+	dirUsage bool
+	// find the uncompressed size of each .gz file, see gzsize.go (-gzsize cmd line option)
+	gzSize bool
+	// estimate the entropy of the contents of each file, see entropy.go (-entropy cmd line option)
+	findEntropy bool
+	// classify the contents of each file as text or binary, see textbin.go (-class cmd line option)
+	classify bool
+	// when set to classText or classBinary, only include files of this class (-text and -binary cmd line options)
+	onlyClass string
+	// find the interpreter named by the #! line of each executable file, see shebang.go (-shebang cmd line option)
+	findShebang bool
+	// find the width and height of each image, see imgsize.go (-imgsize cmd line option)
+	findImageSize bool
+	// find the duration of each audio and video file, see media.go (-duration cmd line option)
+	findDuration bool
+	// find the number of pages of each PDF file, see pdf.go (-pages cmd line option)
+	findPages bool
+	// find the last commit of each entry in a git repository, see gitlog.go (-git cmd line option)
+	gitLog bool
+	// only include sparse files (-sparse cmd line option)
+	onlySparse bool
+	// only include entries on the same file system as the first entry, and do not cross file systems when walking
+	// directories (-xdev cmd line option)
+	sameDevice bool
+	// each zip and tar archive is followed by its members, see archives.go (-archives cmd line option)
+	archives bool
+	// when not nil, called for every file name with the reason it was excluded, or an empty reason when included
+	// (-explain cmd line option)
+	explain func(fname string, reason string)
+	// when not nil, changes each file name before it is matched and output, see pathRenamer() (-abs, -relto and
+	// -unc cmd line options)
+	rename func(fname string) string
+	// also set the Directory and Basename of each entry (-splitname cmd line option)
+	splitName bool
+}
+
 /*
 GetFileInfo will read a list of file names, get the file's timestamp and size,
 and create the allEntries slice
 
 Args:
-    input: a slice of file names
+    ctx: once done, no more file names are examined and the entries found so far are returned, see cancel.go
+         (-timeout cmd line option)
 
-    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+    nextName: returns each file name in turn, and false when there are no more, see sliceNames() and scanNames()
 
-    excludeDot: when set, exclude dot files (cmd line option: -ed)
+    options: the filter options, and what else to find for each file
 
-    excludeRE: when set, exclude based on this regular expression
+    remote: the stat results of each remote name, from ExpandRemoteFilenames(), which are used instead of os.Lstat()
 
-    includeRE: when set, only include based on this regular expression
+    netfs: when not nil, limit the time of each os.Lstat() on network file systems, see netfs.go (-nettimeout cmd line option)
 
-    dateNewer: when set, only include if date is equal or newer that the given YYYYMMDD formatted date
+    cache: when not nil, use and update stat results from previous runs (-cache cmd line option)
 
-    dateOlder: when set, only include if date is equal or older that the given YYYYMMDD formatted date
+    counters: when not nil, count each file name, error and included byte (-progress and -stats cmd line options)
 
-    sizeSmaller: when set, only include if file size is equal or smaller that given value (in bytes)
-
-    sizeLarger: when set, only include if file size is equal or larger that given value (in bytes)
+    stream: when not nil, each entry is passed to this function instead of being collected (-ojl and -ocbor cmd line options)
 
 Returns:
     a slice of type FileStat containing all files that were successfully examined
 */
-func GetFileInfo(allFilenames []string, quiet bool, excludeDot bool, excludeRE string, includeRE string, dateNewer string, dateOlder string, sizeSmaller int64, sizeLarger int64) []FileStat {
+func GetFileInfo(ctx context.Context, nextName func() (string, bool), options scanOptions, remote map[string]statRecord, netfs *netFileSystems, cache *statCache, counters *scanCounters, stream func(e FileStat)) []FileStat {
 	var allEntries []FileStat
+	if nil == options.explain {
+		options.explain = func(string, string) {}
+	}
 	shouldExcludeRE := false
 	shouldIncludeRE := false
 	var excludeMatched *regexp.Regexp
 	var includeMatched *regexp.Regexp
 	var err error
 
-	if len(excludeRE) > 0 {
-		excludeMatched, err = regexp.Compile(excludeRE)
+	if len(options.excludeRE) > 0 {
+		excludeMatched, err = regexp.Compile(options.excludeRE)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Invalid 'exclude' regular expression: %s\n", excludeRE)
+			logError("invalid 'exclude' regular expression: %s", options.excludeRE)
 			os.Exit(3)
 		}
 		shouldExcludeRE = true
 	}
 
-	if len(includeRE) > 0 {
-		includeMatched, err = regexp.Compile(includeRE)
+	if len(options.includeRE) > 0 {
+		includeMatched, err = regexp.Compile(options.includeRE)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Invalid 'include' regular expression: %s\n", includeRE)
+			logError("invalid 'include' regular expression: %s", options.includeRE)
 			os.Exit(4)
 		}
 		shouldIncludeRE = true
 	}
+	fuzzyMatched := newFuzzyQuery(options.fuzzy)
+
+	// a leading dot is optional, so that both "log" and ".log" are accepted; -ext
+	var allExtensions []string
+	for _, ext := range strings.Split(options.extensions, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if len(ext) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		allExtensions = append(allExtensions, ext)
+	}
 
 	// set up time.Time variables for dateOlder and dateNewer; -do and -dn
 	var olderModTime, newerModTime time.Time
 	useOlder := false
 	useNewer := false
-	if len(dateNewer) > 0 {
+	if len(options.dateNewer) > 0 {
 		useNewer = true
-		newerModTime = roundToLocalTime(wantNewer, dateNewer)
+		newerModTime, err = parseDateFilter(wantNewer, options.dateNewer)
+		if err != nil {
+			logError("unable to parse date: %s; the format should be: YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339", options.dateNewer)
+			os.Exit(5)
+		}
 	}
-	if len(dateOlder) > 0 {
+	if len(options.dateOlder) > 0 {
 		useOlder = true
-		olderModTime = roundToLocalTime(wantOlder, dateOlder)
+		olderModTime, err = parseDateFilter(wantOlder, options.dateOlder)
+		if err != nil {
+			logError("unable to parse date: %s; the format should be: YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339", options.dateOlder)
+			os.Exit(5)
+		}
+	}
+
+	// set up time.Time variables for accessOlder and accessNewer; -ao and -an
+	var olderAccessTime, newerAccessTime time.Time
+	useAccessOlder := false
+	useAccessNewer := false
+	if len(options.accessNewer) > 0 {
+		useAccessNewer = true
+		newerAccessTime, err = parseDateFilter(wantNewer, options.accessNewer)
+		if err != nil {
+			logError("unable to parse date: %s; the format should be: YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339", options.accessNewer)
+			os.Exit(5)
+		}
+	}
+	if len(options.accessOlder) > 0 {
+		useAccessOlder = true
+		olderAccessTime, err = parseDateFilter(wantOlder, options.accessOlder)
+		if err != nil {
+			logError("unable to parse date: %s; the format should be: YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339", options.accessOlder)
+			os.Exit(5)
+		}
+	}
+
+	// numeric ids are converted to names; -user and -grp
+	if len(options.userFilter) > 0 {
+		options.lookupOwner = true
+		options.userFilter = resolveUser(options.userFilter)
+	}
+	if len(options.groupFilter) > 0 {
+		options.groupFilter = resolveGroup(options.groupFilter)
+	}
+
+	// permission bits; -perm
+	var perm permFilter
+	usePerm := false
+	if len(options.permission) > 0 {
+		usePerm = true
+		perm, err = parsePermFilter(options.permission)
+		if err != nil {
+			logError("invalid 'perm' mode: %s: %s", options.permission, err)
+			os.Exit(2)
+		}
+	}
+
+	// filter expression; -where
+	var whereExpr whereNode
+	var whereUses map[string]bool
+	if len(options.where) > 0 {
+		whereExpr, whereUses, err = parseWhere(options.where)
+		if err != nil {
+			logError("invalid 'where' expression: %s", err)
+			os.Exit(2)
+		}
+		options.lookupOwner = options.lookupOwner || whereUses["owner"]
+		options.lookupGroup = options.lookupGroup || whereUses["group"]
+		options.classify = options.classify || whereUses["class"]
+		options.findShebang = options.findShebang || whereUses["interpreter"]
+		options.findImageSize = options.findImageSize || whereUses["width"] || whereUses["height"]
+		options.findDuration = options.findDuration || whereUses["duration"]
+		options.findPages = options.findPages || whereUses["pages"]
+		options.gitLog = options.gitLog || whereUses["commit"] || whereUses["author"] || whereUses["committed"]
+	}
+	now := time.Now()
+
+	// the device of the first entry; -xdev
+	var firstDevice uint64
+	haveFirstDevice := false
+
+	// the stat results of the members of each archive, which are removed once used; -archives
+	var members map[string]statRecord
+	if options.archives {
+		members = make(map[string]statRecord)
+		nextName = archiveNames(nextName, members, options.quiet)
+	}
+
+	// the last commit of every path in each repository; -git
+	var history *gitHistory
+	if options.gitLog {
+		history = newGitHistory()
+	}
+
+	// stop before reading the next file name once ctx is done, such as from STDIN with -stream
+	allNames := nextName
+	nextName = func() (string, bool) {
+		if ctx.Err() != nil {
+			return "", false
+		}
+		return allNames()
 	}
 
 	// iterate through each file and get its os.Lstat()
-	pathSepDot := fmt.Sprintf("%c.", os.PathSeparator)
-	for _, fname := range allFilenames {
+	for statName, ok := nextName(); ok; statName, ok = nextName() {
+		counters.addExamined()
+		// the name which is matched and output, while statName is used to examine the file
+		fname := statName
+		if options.rename != nil && !isRemote(statName) {
+			fname = options.rename(statName)
+		}
+
+		// the members of an archive, and remote entries, already have their stat results; -archives and remote.go
+		member, isMember := members[statName]
+		delete(members, statName)
+		if !isMember {
+			member, isMember = remote[statName]
+		}
+
 		// check excludeDot; -ed
-		if excludeDot && ("." == path.Base(fname)[:1] || strings.Contains(fname, pathSepDot)) {
+		if options.excludeDot && isDotPath(statName) {
+			options.explain(fname, "-ed: dot file or directory")
 			continue
 		}
 
 		// check excludeRE and includeRE; -er and -ir
 		if shouldExcludeRE && excludeMatched.Match([]byte(fname)) {
+			options.explain(fname, "-er: matches "+options.excludeRE)
 			continue
 		}
 		if shouldIncludeRE && !includeMatched.Match([]byte(fname)) {
+			options.explain(fname, "-ir: does not match "+options.includeRE)
 			continue
 		}
 
-		f, err := os.Lstat(fname)
-		if err != nil {
-			if !quiet {
-				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-			}
+		// check the fuzzy query; -fuzzy
+		if _, ok := fuzzyMatched.score(fname); !ok {
+			options.explain(fname, "-fuzzy: does not match "+options.fuzzy)
 			continue
 		}
 
-		// check dateOlder and dateNewer; -do and -dn
-		if useOlder && f.ModTime().After(olderModTime) {
+		// check the patterns from .fstatignore
+		if len(options.ignorePatterns) > 0 && isIgnored(fname, options.ignorePatterns) {
+			options.explain(fname, ignoreFileName+": matches a pattern")
 			continue
 		}
-		if useNewer && f.ModTime().Before(newerModTime) {
+
+		// check the file extension; -ext
+		if len(allExtensions) > 0 && !hasExtension(fname, allExtensions) {
+			options.explain(fname, "-ext: extension not listed")
 			continue
 		}
 
-		var ftype = "?"
-		if f.Mode().IsRegular() {
-			ftype = "F"
-		} else if f.IsDir() {
-			ftype = "D"
-		} else if f.Mode()&os.ModeSymlink == os.ModeSymlink {
-			ftype = "L"
+		// use the stat results from a previous run when possible; -cache
+		needGroup := options.lookupGroup || len(options.groupFilter) > 0
+		rec, cached := cache.get(statName, options.lookupOwner, needGroup)
+		if isMember {
+			rec, cached = member, true
 		}
-
-		// check file sizes; -szs and -szl
-		if sizeSmaller > 0 && f.Size() > sizeSmaller && "F" == ftype {
+		if !cached && cache.isOffline() {
+			counters.addFailed(fname, errNotInSnapshot)
+			if !options.quiet {
+				logError("%s: %s", fname, errNotInSnapshot)
+			}
+			options.explain(fname, errNotInSnapshot.Error())
 			continue
 		}
-		// check file sizes; -szs and -szl
-		if sizeLarger > 0 && f.Size() < sizeLarger && "F" == ftype {
-			continue
+		if !cached {
+			var statStart time.Time
+			if counters.timed() {
+				statStart = time.Now()
+			}
+			f, err := netfs.lstat(statName)
+			if counters.timed() {
+				counters.addStatTime(fname, time.Since(statStart))
+			}
+			if err != nil {
+				counters.addFailed(fname, err)
+				if !options.quiet {
+					logError("%s", err)
+				}
+				options.explain(fname, err.Error())
+				continue
+			}
+			rec = newStatRecord(statName, f)
+			if options.lookupOwner {
+				rec.Owner, rec.HaveOwner = getOwner(statName, f), true
+			}
+			if needGroup {
+				rec.Group, rec.HaveGroup = getGroup(statName, f), true
+			}
+			cache.put(statName, rec)
 		}
 
-		entry := FileStat{FullName: fname, Size: f.Size(), ModTime: f.ModTime(), FileType: ftype}
-		allEntries = append(allEntries, entry)
-	}
-	return allEntries
-}
-
-/*
-RenderAllEntries creates a table of all given files which are sorted from the given sort options
-
-Args:
-    allEntries: a slice of all files, modification times, sizes, and if the entry is a file, directory, or symbolic link
-
-    addCommas: when set, add a comma as a thousands separator (-c cmd line option)
-
-    convertToMiB: when set, output file size in Mebibytes (-m cmd line option)
-
-    addMilliseconds: when set, output modification times to include thousands of a second (-M cmd line option)
-
-    includeTotals: when set, append a line include summed file sizes and number of files (-t cmd line option)
+		// check that all entries are on the same file system; -xdev
+		device := rec.Device
+		if options.sameDevice {
+			if !haveFirstDevice {
+				firstDevice = device
+				haveFirstDevice = true
+			} else if device != firstDevice {
+				options.explain(fname, "-xdev: on another file system")
+				continue
+			}
+		}
 
-    onlyFiles: when set, only output files and exclude directories, symbolic links (-of cmd line option)
+		// check the permission bits; -perm
+		if usePerm && !perm.matches(rec.Mode) {
+			options.explain(fname, "-perm: mode is "+rec.Mode.String())
+			continue
+		}
 
-    onlyDirs: when set, only output directories and exclude files, symbolic links (-od cmd line option)
+		// check the owner and group; -user and -grp
+		var owner, group string
+		if options.lookupOwner {
+			owner = rec.Owner
+			if len(options.userFilter) > 0 && !matchesAccount(owner, options.userFilter) {
+				options.explain(fname, "-user: owned by "+owner)
+				continue
+			}
+		}
+		if needGroup {
+			group = rec.Group
+			if len(options.groupFilter) > 0 && !matchesAccount(group, options.groupFilter) {
+				options.explain(fname, "-grp: group is "+group)
+				continue
+			}
+		}
 
-    onlyLinks: when set, only output symbolic links and exclude files, directories (-ol cmd line option)
+		// check dateOlder and dateNewer; -do and -dn
+		if useOlder && rec.ModTime.After(olderModTime) {
+			options.explain(fname, "-do: modified "+rec.ModTime.Format(time.RFC3339))
+			continue
+		}
+		if useNewer && rec.ModTime.Before(newerModTime) {
+			options.explain(fname, "-dn: modified "+rec.ModTime.Format(time.RFC3339))
+			continue
+		}
 
-	longFileNames: when set, do not use ellipses to shorten file names (-long cmd line option)
+		// check accessOlder and accessNewer; -ao and -an
+		accessTime := rec.Access
+		if useAccessOlder && accessTime.After(olderAccessTime) {
+			options.explain(fname, "-ao: accessed "+accessTime.Format(time.RFC3339))
+			continue
+		}
+		if useAccessNewer && accessTime.Before(newerAccessTime) {
+			options.explain(fname, "-an: accessed "+accessTime.Format(time.RFC3339))
+			continue
+		}
 
-	longWidth: when set, use this at the max line width (-longwidth cmd line option)
+		ftype := fileType(rec.Mode)
 
-*/
-func RenderAllEntries(allEntries []FileStat, addCommas bool, convertToMiB bool, addMilliseconds bool, includeTotals bool, onlyFiles bool, onlyDirs bool, onlyLinks bool, outputCSV bool, outputHTML bool, outputJSON bool, longFileNames bool, longWidth int) {
-	var allRows [][]string
-	var e FileStat
-	var fsize string
-	var modtime string
-	var totalFileSize int64
-	var totalFileCount int64
-	var totalDirCount int64
-	var totalSymLinkCount int64
+		// cumulative directory size; -du
+		size := rec.Size
+		checkSize := "F" == ftype
+		if options.dirUsage && "D" == ftype {
+			if (!rec.HaveDirUsage || rec.DirUsageSameDevice != options.sameDevice) && !cache.isOffline() && !isMember {
+				rec.DirUsage, rec.HaveDirUsage, rec.DirUsageSameDevice = diskUsage(statName, options.quiet, options.sameDevice), true, options.sameDevice
+				cache.put(statName, rec)
+			}
+			if rec.HaveDirUsage {
+				size = rec.DirUsage
+			}
+			checkSize = true
+		}
 
-	for _, e = range allEntries {
-		if onlyFiles && "F" != e.FileType {
+		// check file sizes; -szs and -szl
+		if options.sizeSmaller > 0 && size > options.sizeSmaller && checkSize {
+			options.explain(fname, "-szs: size is "+strconv.FormatInt(size, 10))
 			continue
 		}
-		if onlyDirs && "D" != e.FileType {
+		// check file sizes; -szs and -szl
+		if options.sizeLarger > 0 && size < options.sizeLarger && checkSize {
+			options.explain(fname, "-szl: size is "+strconv.FormatInt(size, 10))
 			continue
 		}
-		if onlyLinks && "L" != e.FileType {
+
+		// check for sparse files; -sparse
+		allocated := rec.Allocated
+		sparse := "F" == ftype && isSparse(size, allocated)
+		if options.onlySparse && !sparse {
+			options.explain(fname, "-sparse: not sparse")
 			continue
 		}
-		if includeTotals {
-			if "F" == e.FileType {
-				totalFileSize += e.Size
-				totalFileCount++
+
+		// uncompressed size of gzip files; -gzsize
+		var uncompressed *int64
+		if options.gzSize && "F" == ftype && isGzip(statName) && !isMember {
+			if !rec.HaveUncompressed && !cache.isOffline() {
+				n, err := gzipSize(statName, rec.Size)
+				if err != nil {
+					if !options.quiet {
+						logError("%s: %s", fname, err)
+					}
+				} else {
+					rec.Uncompressed, rec.HaveUncompressed = n, true
+					cache.put(statName, rec)
+				}
 			}
-			if "D" == e.FileType {
-				totalDirCount++
+			if rec.HaveUncompressed {
+				uncompressed = &rec.Uncompressed
 			}
-			if "L" == e.FileType {
-				totalSymLinkCount++
+		}
+
+		// how well the contents can be compressed; -entropy
+		var entropy *float64
+		if options.findEntropy && "F" == ftype && !isMember {
+			if !rec.HaveEntropy && !cache.isOffline() {
+				bits, err := fileEntropy(statName, rec.Size)
+				if err != nil {
+					if !options.quiet {
+						logError("%s", err)
+					}
+				} else {
+					rec.Entropy, rec.HaveEntropy = bits, true
+					cache.put(statName, rec)
+				}
+			}
+			if rec.HaveEntropy {
+				entropy = &rec.Entropy
 			}
 		}
-		if convertToMiB {
-			e.Size /= 1048576
+
+		// text or binary contents; -class, -text and -binary
+		var class string
+		if (options.classify || len(options.onlyClass) > 0) && "F" == ftype && !isMember {
+			if 0 == len(rec.Class) && !cache.isOffline() {
+				rec.Class, err = classifyContents(statName)
+				if err != nil {
+					if !options.quiet {
+						logError("%s", err)
+					}
+				} else {
+					cache.put(statName, rec)
+				}
+			}
+			class = rec.Class
 		}
-		if addCommas {
-			fsize = RenderInteger("#,###.", e.Size)
-		} else {
-			fsize = fmt.Sprintf("%d", e.Size)
+		if len(options.onlyClass) > 0 && class != options.onlyClass {
+			options.explain(fname, "-"+options.onlyClass+": contents are not "+options.onlyClass)
+			continue
 		}
-		if addMilliseconds {
-			modtime = fmt.Sprintf("%s", e.ModTime)[:23]
-			if ' ' == modtime[19] {
-				modtime = fmt.Sprintf("%s.000", modtime[:19])
+
+		// the interpreter of scripts; -shebang
+		var interpreter string
+		if options.findShebang && "F" == ftype && isExecutable(rec.Mode) && !isMember {
+			if !rec.HaveInterpreter && !cache.isOffline() {
+				rec.Interpreter, err = readShebang(statName)
+				if err != nil {
+					if !options.quiet {
+						logError("%s", err)
+					}
+				} else {
+					rec.HaveInterpreter = true
+					cache.put(statName, rec)
+				}
 			}
-		} else {
-			modtime = fmt.Sprintf("%s", e.ModTime)[:19]
+			interpreter = rec.Interpreter
 		}
 
-		allRows = append(allRows, []string{modtime, fsize, fmt.Sprintf("%s", e.FileType), e.FullName})
-	}
-
-	if includeTotals {
-		tsize := fmt.Sprintf("%d", totalFileSize)
-		if convertToMiB {
-			totalFileSize /= 1048576
-			tsize = fmt.Sprintf("%d", totalFileSize)
+		// the dimensions of images; -imgsize
+		var width, height int
+		if options.findImageSize && "F" == ftype && isImage(statName) && !isMember {
+			if !rec.HaveImageSize && !cache.isOffline() {
+				rec.Width, rec.Height, err = imageSize(statName)
+				if err != nil {
+					if !options.quiet {
+						logError("%s: %s", fname, err)
+					}
+				} else {
+					rec.HaveImageSize = true
+					cache.put(statName, rec)
+				}
+			}
+			width, height = rec.Width, rec.Height
 		}
-		if addCommas {
-			tsize = RenderInteger("#,###.", totalFileSize)
+
+		// the duration of audio and video; -duration
+		var duration float64
+		if options.findDuration && "F" == ftype && isMedia(statName) && !isMember {
+			if !rec.HaveDuration && !cache.isOffline() {
+				rec.Duration, err = mediaDuration(statName)
+				if err != nil {
+					if !options.quiet {
+						logError("%s: %s", fname, err)
+					}
+				} else {
+					rec.HaveDuration = true
+					cache.put(statName, rec)
+				}
+			}
+			duration = rec.Duration
 		}
-		allRows = append(allRows, []string{"", tsize, " ", fmt.Sprintf("  (total size for %d files)", totalFileCount)})
 
-		var averageFileSize float64
-		if totalFileCount > 0 {
-			averageFileSize = float64(totalFileSize / totalFileCount)
+		// the number of pages of documents; -pages
+		var pages int64
+		if options.findPages && "F" == ftype && isPDF(statName) && !isMember {
+			if !rec.HavePages && !cache.isOffline() {
+				rec.Pages, err = pdfPages(statName)
+				if err != nil {
+					if !options.quiet {
+						logError("%s: %s", fname, err)
+					}
+				} else {
+					rec.HavePages = true
+					cache.put(statName, rec)
+				}
+			}
+			pages = rec.Pages
 		}
 
-		var averageFilesPerDir float64
-		if totalFileCount > 0 && totalDirCount > 0 {
-			averageFilesPerDir = float64(totalFileCount / totalDirCount)
+		// the last commit; -git
+		var commit *gitCommit
+		if options.gitLog && !isMember {
+			commit, err = history.lastCommit(ctx, statName, "D" == ftype)
+			if err != nil && !options.quiet && nil == ctx.Err() {
+				logError("%s: %s", fname, err)
+			}
 		}
 
-		asize := fmt.Sprintf("%.0f", averageFileSize)
-		dsize := fmt.Sprintf("%.0f", averageFilesPerDir)
-		if addCommas {
-			asize = RenderFloat("#,###.", averageFileSize)
-			dsize = RenderFloat("#,###.", averageFilesPerDir)
+		entry := FileStat{FullName: fname, Size: size, ModTime: rec.ModTime, FileType: ftype, Allocated: allocated, Sparse: sparse, Device: device, Access: accessTime, Owner: owner, Group: group, Mode: rec.Mode.String(), Perm: unixMode(rec.Mode), DeviceNumber: rec.DeviceNumber, Uncompressed: uncompressed, Entropy: entropy, Class: class, Interpreter: interpreter, Width: width, Height: height, Duration: duration, Pages: pages, statName: statName}
+		if commit != nil {
+			entry.Commit, entry.Author, entry.CommitTime = commit.hash, commit.author, &commit.date
 		}
-		if len(allRows) > 0 {
-			allRows = append(allRows, []string{"", asize, " ", fmt.Sprintf("(average size for %d files)", totalFileCount)})
+		if options.splitName {
+			entry.Directory, entry.Basename = filepath.Dir(fname), filepath.Base(fname)
 		}
-		if totalDirCount > 0 {
-			allRows = append(allRows, []string{"", fmt.Sprintf("%d", totalDirCount), " ", "(num of directories)"})
+
+		// check the filter expression; -where
+		if whereExpr != nil && !whereExpr.eval(entry, now) {
+			options.explain(fname, "-where: expression is false")
+			continue
 		}
-		if averageFilesPerDir > 0 {
-			allRows = append(allRows, []string{"", dsize, " ", "(average num of files per directory)"})
+		options.explain(fname, "")
+		if "F" == entry.FileType {
+			counters.addBytes(entry.Size)
 		}
-		if totalSymLinkCount > 0 {
-			allRows = append(allRows, []string{"", fmt.Sprintf("%d", totalSymLinkCount), " ", "(num of sym links)"})
+		if stream != nil {
+			stream(entry)
+			continue
 		}
+		allEntries = append(allEntries, entry)
 	}
+	return allEntries
+}
 
-	header := []string{"Mod Time", "Size", "Type", "Name"}
+// siUnits - decimal size units used by the -dec cmd line option
+var siUnits = []string{"KB", "MB", "GB", "TB", "PB", "EB"}
 
-	if outputCSV {
-		fmt.Printf("\"%s\"\n", strings.Join(header[:], "\",\""))
-		for i := 0; i < len(allRows); i++ {
-			fmt.Printf("\"%s\"\n", strings.Join(allRows[i][:], "\",\""))
-		}
-		return
+// formatSI - render a size in decimal (powers of 1000) units, such as "1.5 MB"
+func formatSI(size int64) string {
+	if size < 1000 && size > -1000 {
+		return fmt.Sprintf("%d B", size)
 	}
-
-	if outputHTML {
-		fmt.Println("<!DOCTYPE html>")
-		fmt.Println("<html>")
-		fmt.Println("<body>")
-		fmt.Println("<table border='1' cellpadding='3' cellspacing='3'>")
-		fmt.Printf("<th>%s</th>\n", strings.Join(header[:], "</th><th>"))
-		for i := 0; i < len(allRows); i++ {
-			fmt.Println("<tr>")
-			fmt.Printf("\t<td>%s</td>\n", strings.Join(allRows[i][:], "</td><td>"))
-			fmt.Println("</tr>")
-		}
-		fmt.Println("</table>")
-		fmt.Println("</body>")
-		fmt.Println("</html>")
-		return
+	value := float64(size) / 1000
+	unit := 0
+	for (value >= 999.95 || value <= -999.95) && unit < len(siUnits)-1 {
+		value /= 1000
+		unit++
 	}
+	return fmt.Sprintf("%.1f %s", value, siUnits[unit])
+}
 
-	if outputJSON {
-		var row FileStat
-		var jsonRows []FileStat
-		var err error
-		layout := "2006-01-02 15:04:05"
-		for i := 0; i < len(allRows); i++ {
-			row.ModTime, err = time.Parse(layout, allRows[i][0])
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-			row.Size, _ = strconv.ParseInt(strings.Replace(allRows[i][1], ",", "", -1), 10, 64)
-			row.FileType = allRows[i][2]
-			row.FullName = allRows[i][3]
-			jsonRows = append(jsonRows, row)
-		}
-		j, _ := json.MarshalIndent(allRows, "", "    ")
-		fmt.Println(string(j))
+// formatSize - render a file size, optionally in mebibytes, SI units, and/or with a thousands separator
+func formatSize(size int64, addCommas bool, convertToMiB bool, useSI bool) string {
+	if useSI {
+		return formatSI(size)
+	}
+	if convertToMiB {
+		size /= 1048576
+	}
+	if addCommas {
+		return RenderInteger("#,###.", size)
+	}
+	return fmt.Sprintf("%d", size)
+}
 
-		return
+// renderCSV - output header and allRows as CSV, fields are only quoted when needed; header is skipped when nil
+func renderCSV(header []string, allRows [][]string, delimiter rune, summary []summaryField) {
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = delimiter
+	if header != nil {
+		w.Write(header)
 	}
+	for _, f := range summary {
+		allRows = append(allRows, []string{"# " + f.Name, f.Value})
+	}
+	w.WriteAll(allRows)
+}
 
-	// by default, output to STDOUT
-	if len(allRows) > 0 {
-		maxWidth := 3000
-		if longFileNames == false {
-			maxWidth = termsize.Width() - minTermWidth
-			if longWidth > 0 {
-				maxWidth = longWidth - minTermWidth + 2
+/*
+parseCSVDelimiter converts the value of the -csvdelim cmd line option into a field delimiter
+
+Args:
+    value: a single character such as ; or |, or the word: tab
+
+Returns:
+    the delimiter, or an error when value can not be used as a CSV delimiter
+*/
+func parseCSVDelimiter(value string) (rune, error) {
+	if "tab" == strings.ToLower(value) || `\t` == value {
+		return '\t', nil
+	}
+	r := []rune(value)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' || r[0] == utf8.RuneError {
+		return 0, fmt.Errorf("must be a single character other than a double quote, or the word: tab")
+	}
+	return r[0], nil
+}
+
+// tsvEscapes - TSV fields can not contain tabs or newlines, so these are escaped instead of quoted
+var tsvEscapes = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// renderTSV - output header and allRows as tab separated values without any quoting; header is skipped when nil
+func renderTSV(header []string, allRows [][]string, summary []summaryField) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for _, f := range summary {
+		allRows = append(allRows, []string{"# " + f.Name, f.Value})
+	}
+	if header != nil {
+		allRows = append([][]string{header}, allRows...)
+	}
+	for _, row := range allRows {
+		for i, field := range row {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			w.WriteString(tsvEscapes.Replace(field))
+		}
+		w.WriteByte('\n')
+	}
+}
+
+// renderTable - output header and allRows as a text table to STDOUT using the given column alignments
+func renderTable(header []string, allRows [][]string, columnAlignment []int) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader(header)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetColumnAlignment(columnAlignment)
+	table.AppendBulk(allRows)
+	table.Render()
+}
+
+/*
+renderEntryTable outputs the entries as a text table to STDOUT, the same as renderTable(), except that
+columns can have a minimum width and rows can be highlighted
+
+Args:
+    header, allRows, columnAlignment: see renderTable()
+
+    minimum: the minimum width of each column, see width.go; nil for none
+
+    highlighted: true for each row of allRows to show in color, see highlight.go; rows past its end are not highlighted
+*/
+func renderEntryTable(header []string, allRows [][]string, columnAlignment []int, minimum []int, highlighted []bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetHeader(header)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetColumnAlignment(columnAlignment)
+	for i, width := range minimum {
+		if width > 0 {
+			table.SetColMinWidth(i, width)
+		}
+	}
+	colors := make([]tablewriter.Colors, len(columnAlignment))
+	for i := range colors {
+		colors[i] = highlightColors
+	}
+	for i, row := range allRows {
+		if i < len(highlighted) && highlighted[i] {
+			table.Rich(row, colors)
+		} else {
+			table.Append(row)
+		}
+	}
+	table.Render()
+}
+
+// tableHeader - the columns of the default table and their alignments, with extraColumns before the Name column,
+// which is replaced by Directory and Basename columns with splitName
+func tableHeader(relativeOnly bool, extraColumns []extraColumn, splitName bool) ([]string, []int) {
+	header := []string{"Mod Time", "Size", "Type"}
+	if relativeOnly {
+		header[0] = "Age"
+	}
+	columnAlignment := []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT}
+	for _, c := range extraColumns {
+		header = append(header, c.header)
+		columnAlignment = append(columnAlignment, c.alignment)
+	}
+	if splitName {
+		return append(header, "Directory", "Basename"), append(columnAlignment, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT)
+	}
+	return append(header, "Name"), append(columnAlignment, tablewriter.ALIGN_LEFT)
+}
+
+// renderOptions - how entries are output, from the cmd line; used by RenderAllEntries() and the other Render functions
+// which output a table, -oc, -ot or -oj
+type renderOptions struct {
+	// add a comma as a thousands separator (-c cmd line option)
+	addCommas bool
+	// output file size in Mebibytes (-m cmd line option)
+	convertToMiB bool
+	// output file size in decimal KB, MB, GB units (-dec cmd line option)
+	useSI bool
+	// the Go time layout used for modification times, from getTimeLayout() (-M and -datefmt cmd line options)
+	timeLayout string
+	// output modification times as the time since modification, such as "3d 4h ago" (-relonly cmd line option)
+	relativeOnly bool
+	// append a line include summed file sizes and number of files (-t cmd line option); with outputJSON, an object
+	// with the entries and a summary is output instead of an array
+	includeTotals bool
+	// also include median, percentiles, std deviation, smallest and largest files (-tx cmd line option)
+	extendedTotals bool
+	// only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+	onlyTypes string
+	// alternate output formats (-oc, -ot, -oh, -oj cmd line options)
+	outputCSV  bool
+	outputTSV  bool
+	outputHTML bool
+	outputJSON bool
+	// the field delimiter used with outputCSV (-csvdelim cmd line option)
+	csvDelimiter rune
+	// with outputCSV or outputTSV, do not output the header row; used by -stream for all but the first batch
+	omitHeader bool
+	// when set with outputHTML, each file name is a hyperlink, see fileLink() (-links and -linkbase cmd line options)
+	linkBase string
+	// the heading and color theme of the outputHTML page (-title and -theme cmd line options)
+	htmlTitle string
+	htmlTheme string
+	// do not use ellipses to shorten file names (-long cmd line option)
+	longFileNames bool
+	// when set, use this at the max line width (-longwidth cmd line option)
+	longWidth int
+	// the column shortened to fit the line width, and the minimum width of each column, see width.go (-truncate and
+	// -minwidth cmd line options)
+	widths columnWidths
+	// optional columns which are inserted before the Name column
+	extraColumns []extraColumn
+	// when set, mark the entries modified within this time before now, see highlight.go (-highlight cmd line option)
+	highlight time.Duration
+	// the Name column is replaced by Directory and Basename columns (-splitname cmd line option)
+	splitName bool
+}
+
+/*
+RenderAllEntries creates a table of all given files which are sorted from the given sort options
+
+Args:
+    allEntries: a slice of all files, modification times, sizes, and if the entry is a file, directory, or symbolic link
+
+    options: how the entries are output
+
+    allErrors: the files that could not be examined, which are included in the output; nil unless -errors is used
+
+    failed: the number of files that could not be examined, which is included in the totals
+
+    partial: why the scan was stopped early, which is included in the output; with outputJSON, an object with the
+             entries and this reason is output instead of an array; empty when every file was examined, see cancel.go
+
+*/
+func RenderAllEntries(allEntries []FileStat, options renderOptions, allErrors []scanError, failed int64, partial string) {
+	var allRows [][]string
+	var allLinks []string
+	var e FileStat
+	var fsize string
+	var modtime string
+	var totalFileSize int64
+	var totalFileCount int64
+	var totalDirCount int64
+	var totalSymLinkCount int64
+	typeCounts := make(map[string]int64)
+	var totalFiles []FileStat
+	var jsonEntries = []FileStat{}
+	var shownEntries []FileStat
+	totalsFooter := options.includeTotals && (options.outputCSV || options.outputTSV || options.outputHTML)
+	now := time.Now()
+	var highlighted []bool
+	color := options.highlight > 0 && useColor()
+
+	for _, e = range allEntries {
+		if !typeIncluded(options.onlyTypes, e.FileType) {
+			continue
+		}
+		if options.includeTotals {
+			if "F" == e.FileType {
+				totalFileSize += e.Size
+				totalFileCount++
+				totalFiles = append(totalFiles, e)
+			}
+			if "D" == e.FileType {
+				totalDirCount++
+			}
+			if "L" == e.FileType {
+				totalSymLinkCount++
+			}
+			typeCounts[e.FileType]++
+		}
+		if options.outputJSON {
+			jsonEntries = append(jsonEntries, e)
+			continue
+		}
+		fsize = formatSize(e.Size, options.addCommas, options.convertToMiB, options.useSI)
+		modtime = e.ModTime.Format(options.timeLayout)
+		if options.relativeOnly {
+			modtime = formatAge(e.ModTime, now)
+		}
+
+		recent := isRecent(e, options.highlight, now)
+		if recent && !color {
+			modtime += highlightMarker
+		}
+		highlighted = append(highlighted, recent)
+
+		row := []string{modtime, fsize, fmt.Sprintf("%s", e.FileType)}
+		for _, c := range options.extraColumns {
+			row = append(row, c.value(e))
+		}
+		if options.splitName {
+			allRows = append(allRows, append(row, e.Directory, e.Basename))
+		} else {
+			allRows = append(allRows, append(row, e.FullName))
+		}
+		if options.outputHTML || totalsFooter {
+			shownEntries = append(shownEntries, e)
+		}
+		if options.outputHTML {
+			if len(options.linkBase) > 0 {
+				allLinks = append(allLinks, fileLink(e.FullName, options.linkBase))
+			}
+		}
+	}
+
+	// totalsRow - a row with a value in the Size column and a label in the Name column
+	totalsRow := func(value string, label string) []string {
+		row := []string{"", value, " "}
+		for range options.extraColumns {
+			row = append(row, "")
+		}
+		if options.splitName {
+			row = append(row, "")
+		}
+		return append(row, label)
+	}
+
+	if options.includeTotals && !totalsFooter && !options.outputJSON {
+		tsize := formatSize(totalFileSize, options.addCommas, options.convertToMiB, options.useSI)
+		if options.convertToMiB {
+			totalFileSize /= 1048576
+		}
+		allRows = append(allRows, totalsRow(tsize, fmt.Sprintf("  (total size for %d files)", totalFileCount)))
+
+		var averageFileSize float64
+		if totalFileCount > 0 {
+			averageFileSize = float64(totalFileSize) / float64(totalFileCount)
+		}
+
+		var averageFilesPerDir float64
+		if totalFileCount > 0 && totalDirCount > 0 {
+			averageFilesPerDir = float64(totalFileCount) / float64(totalDirCount)
+		}
+
+		asize := fmt.Sprintf("%.0f", averageFileSize)
+		dsize := fmt.Sprintf("%.0f", averageFilesPerDir)
+		if options.addCommas {
+			asize = RenderFloat("#,###.", averageFileSize)
+			dsize = RenderFloat("#,###.", averageFilesPerDir)
+		}
+		if options.useSI {
+			asize = formatSI(int64(averageFileSize))
+		}
+		if len(allRows) > 0 {
+			allRows = append(allRows, totalsRow(asize, fmt.Sprintf("(average size for %d files)", totalFileCount)))
+		}
+		if totalDirCount > 0 {
+			allRows = append(allRows, totalsRow(fmt.Sprintf("%d", totalDirCount), "(num of directories)"))
+		}
+		if averageFilesPerDir > 0 {
+			allRows = append(allRows, totalsRow(dsize, "(average num of files per directory)"))
+		}
+		if totalSymLinkCount > 0 {
+			allRows = append(allRows, totalsRow(fmt.Sprintf("%d", totalSymLinkCount), "(num of sym links)"))
+		}
+		for _, code := range []string{"P", "S", "C", "B", "?"} {
+			if typeCounts[code] > 0 {
+				allRows = append(allRows, totalsRow(fmt.Sprintf("%d", typeCounts[code]), "(num of "+typePlurals[code]+")"))
 			}
-			if maxWidth < minTermWidth {
-				maxWidth = minTermWidth
+		}
+		// only set with -duration and -pages
+		contents := ComputeSummary(totalFiles, false, 0)
+		if contents.MediaCount > 0 {
+			allRows = append(allRows, totalsRow(formatDuration(contents.TotalDuration), fmt.Sprintf("(total duration for %d media files)", contents.MediaCount)))
+			allRows = append(allRows, totalsRow(formatDuration(contents.AverageDuration), fmt.Sprintf("(average duration for %d media files)", contents.MediaCount)))
+		}
+		if contents.DocumentCount > 0 {
+			allRows = append(allRows, totalsRow(fmt.Sprintf("%d", contents.TotalPages), fmt.Sprintf("(total pages for %d documents)", contents.DocumentCount)))
+		}
+		if failed > 0 && nil == allErrors {
+			allRows = append(allRows, totalsRow(fmt.Sprintf("%d", failed), "(num of files that could not be examined)"))
+		}
+		if options.extendedTotals && totalFileCount > 0 {
+			stats := ComputeSizeStats(totalFiles)
+			allRows = append(allRows, totalsRow(formatSize(stats.Median, options.addCommas, options.convertToMiB, options.useSI), "(median file size)"))
+			allRows = append(allRows, totalsRow(formatSize(stats.P90, options.addCommas, options.convertToMiB, options.useSI), "(90th percentile file size)"))
+			allRows = append(allRows, totalsRow(formatSize(stats.P99, options.addCommas, options.convertToMiB, options.useSI), "(99th percentile file size)"))
+			allRows = append(allRows, totalsRow(formatSize(int64(stats.StdDev), options.addCommas, options.convertToMiB, options.useSI), "(standard deviation of file size)"))
+			allRows = append(allRows, totalsRow(formatSize(stats.Smallest.Size, options.addCommas, options.convertToMiB, options.useSI), fmt.Sprintf("(smallest file: %s)", stats.Smallest.FullName)))
+			allRows = append(allRows, totalsRow(formatSize(stats.Largest.Size, options.addCommas, options.convertToMiB, options.useSI), fmt.Sprintf("(largest file: %s)", stats.Largest.FullName)))
+		}
+	}
+
+	header, columnAlignment := tableHeader(options.relativeOnly, options.extraColumns, options.splitName)
+	columns := header
+
+	// the totals are a separate section after the entries, so that they can not be mistaken for a file
+	var summary []summaryField
+	if totalsFooter {
+		formatBytes := func(n int64) string { return strconv.FormatInt(n, 10) }
+		formatCount := formatBytes
+		if options.outputHTML {
+			formatBytes = func(n int64) string { return formatSize(n, options.addCommas, options.convertToMiB, options.useSI) }
+			if options.addCommas {
+				formatCount = func(n int64) string { return RenderInteger("#,###.", n) }
 			}
 		}
+		summary = summaryFields(ComputeSummary(shownEntries, options.extendedTotals, failed), formatBytes, formatCount)
+	}
+	if allErrors != nil {
+		summary = append(summary, summaryField{Name: "errors", Value: strconv.Itoa(len(allErrors))})
+		if !options.outputCSV && !options.outputTSV && !options.outputHTML && !options.outputJSON {
+			allRows = append(allRows, totalsRow(strconv.Itoa(len(allErrors)), "(files that could not be examined)"))
+		}
+	}
+	if len(partial) > 0 {
+		summary = append(summary, summaryField{Name: "partial", Value: partial})
+		if !options.outputCSV && !options.outputTSV && !options.outputHTML && !options.outputJSON {
+			allRows = append(allRows, totalsRow("partial", "("+partial+")"))
+		}
+	}
+
+	if options.omitHeader {
+		header = nil
+	}
 
-		allRows = shortenFileName(allRows, maxWidth)
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetAutoWrapText(false)
-		table.SetHeader(header)
-		table.SetAlignment(tablewriter.ALIGN_LEFT)
-		table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
-		table.AppendBulk(allRows)
-		table.Render()
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, summary)
+		return
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, summary)
+		return
+	}
+
+	if options.outputHTML {
+		renderHTML(header, allRows, allLinks, entryCards(shownEntries, options.timeLayout, options.addCommas, options.convertToMiB, options.useSI), summary, options.htmlTitle, options.htmlTheme)
+		return
+	}
+
+	if options.outputJSON {
+		var j []byte
+		if options.includeTotals || allErrors != nil || len(partial) > 0 {
+			var totals *Summary
+			if options.includeTotals {
+				s := ComputeSummary(jsonEntries, options.extendedTotals, failed)
+				totals = &s
+			}
+			j, _ = json.MarshalIndent(struct {
+				Entries []FileStat  `json:"entries"`
+				Summary *Summary    `json:"summary,omitempty"`
+				Errors  []scanError `json:"errors,omitempty"`
+				Partial string      `json:"partial,omitempty"`
+			}{jsonEntries, totals, allErrors, partial}, "", "    ")
+		} else {
+			j, _ = json.MarshalIndent(jsonEntries, "", "    ")
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	// by default, output to STDOUT
+	if len(allRows) > 0 {
+		if options.longFileNames == false {
+			lineWidth := termsize.Width()
+			if options.longWidth > 0 {
+				lineWidth = options.longWidth
+			}
+			allRows = fitColumns(columns, allRows, lineWidth, options.widths)
+		}
+		if !color {
+			highlighted = nil
+		}
+		renderEntryTable(header, allRows, columnAlignment, options.widths.minimumWidths(columns), highlighted)
 	}
 }
 
+// validateDateRange - make sure that a pair of date filters can be parsed and that dateNewer is not newer than dateOlder
+func validateDateRange(newerOption string, olderOption string, dateNewer string, dateOlder string) {
+	var older, newer time.Time
+	var err error
+	if len(dateOlder) > 0 && len(dateNewer) > 0 {
+		older, err = parseDateFilter(wantOlder, dateOlder)
+		if err != nil {
+			logError("unable to parse date for '%s': %s", olderOption, dateOlder)
+			os.Exit(2)
+		}
+		newer, err = parseDateFilter(wantNewer, dateNewer)
+		if err != nil {
+			logError("unable to parse date for '%s': %s", newerOption, dateNewer)
+			os.Exit(2)
+		}
+		if newer.After(older) {
+			logError("'%s' date is newer than '%s'", newerOption, olderOption)
+			os.Exit(2)
+		}
+	}
+}
+
+// validationArgs - the cmd line options which are checked by ValidateArgs()
+type validationArgs struct {
+	// the sort options: -ss, -sS, -sd, -sD, -sn, -sN, -si, -sI
+	sortSize              bool
+	sortSizeDesc          bool
+	sortModTime           bool
+	sortModTimeDesc       bool
+	sortName              bool
+	sortNameDesc          bool
+	sortNameCaseInsen     bool
+	sortNameCaseInsenDesc bool
+	// -t, -tx and -tm
+	totals         bool
+	extendedTotals bool
+	mountTotals    bool
+	// the output formats, and their options
+	outputCSV      bool
+	outputTSV      bool
+	outputHTML     bool
+	outputJSON     bool
+	outputJSONL    bool
+	outputCBOR     bool
+	outputProtobuf bool
+	outputTreemap  bool
+	outputNcdu     bool
+	outputNames    bool
+	outputPrint0   bool
+	outputXargs    bool
+	xargsMax       int
+	outputFormat   string
+	outputPrintf   string
+	outputSQLite   string
+	outputParquet  string
+	summaryOnly    bool
+	summaryFile    string
+	// -stream and -sortchunk
+	streaming bool
+	sortChunk int
+	// -r, -mindepth or -maxdepth; and -clean or -cleanabs
+	recursive  bool
+	cleanNames bool
+	// the date and size filters: -dn, -do, -an, -ao, -szs, -szl
+	dateNewer   string
+	dateOlder   string
+	accessNewer string
+	accessOlder string
+	sizeSmaller int64
+	sizeLarger  int64
+	// -long and -longwidth
+	longFileNames bool
+	longWidth     int
+	// -group, -groupdepth, -hist and -timeline
+	groupBy    string
+	groupDepth int
+	histogram  bool
+	timeline   string
+	// how sizes and times are shown
+	convertToMiB     bool
+	useSI            bool
+	relative         bool
+	relativeOnly     bool
+	addMilliseconds  bool
+	customDateFormat string
+	useUTC           bool
+	timeZone         string
+	useRFC3339       bool
+	addNanoseconds   bool
+	// -mindepth and -maxdepth
+	minDepth int
+	maxDepth int
+}
+
 /*
 ValidateArgs verify all command line arguments.
 It will not allow multiple sort options (such as -ss and -sd)
 It will now allow multiple 'only' options (such as -of and -od)
 */
-func ValidateArgs(argsSortSize bool, argsSortSizeDesc bool, argsSortModTime bool, argsSortModTimeDesc bool, argsSortName bool, argsSortNameDesc bool, argsSortNameCaseInsen bool, argsSortNameCaseInsenDesc bool, argsOnlyFiles bool, argsOnlyDirs bool, argsOnlyLinks bool, argsTotals bool, argsOutputCSV bool, argsOutputHTML bool, argsOutputJSON bool, dateOlder string, dateNewer string, sizeSmaller int64, sizeLarger int64, longFileNames bool, longWidth int) {
+func ValidateArgs(args validationArgs) {
 	count := 0
-	if argsSortSize {
+	if args.sortSize {
 		count++
 	}
-	if argsSortSizeDesc {
+	if args.sortSizeDesc {
 		count++
 	}
-	if argsSortModTime {
+	if args.sortModTime {
 		count++
 	}
-	if argsSortModTimeDesc {
+	if args.sortModTimeDesc {
 		count++
 	}
-	if argsSortName {
+	if args.sortName {
 		count++
 	}
-	if argsSortNameDesc {
+	if args.sortNameDesc {
 		count++
 	}
-	if argsSortNameCaseInsen {
+	if args.sortNameCaseInsen {
 		count++
 	}
-	if argsSortNameCaseInsenDesc {
+	if args.sortNameCaseInsenDesc {
 		count++
 	}
 
 	if count > 1 {
-		fmt.Fprintf(os.Stderr, "Error: only one '-s' sort argument can be given.\n\n")
+		usageError("only one '-s' sort argument can be given.")
+		os.Exit(2)
+	}
+
+	if (args.outputJSONL || args.outputCBOR) && (count > 0 || args.totals || len(args.groupBy) > 0 || args.histogram || len(args.timeline) > 0) {
+		usageError("-ojl and -ocbor stream each entry as it is examined, so they can not be used with: -s, -t, -group, -hist, or -timeline")
+		os.Exit(2)
+	}
+
+	if args.sortChunk < 1 {
+		usageError("-sortchunk must be at least 1")
 		os.Exit(2)
 	}
 
 	count = 0
-	if argsOnlyFiles {
+	if args.outputCSV {
 		count++
 	}
-	if argsOnlyDirs {
+	if args.outputTSV {
 		count++
 	}
-	if argsOnlyLinks {
+	if args.outputHTML {
 		count++
 	}
-
-	if count > 1 {
-		fmt.Fprintf(os.Stderr, "Error: only one '-i' include argument can be given.\n\n")
-		os.Exit(2)
+	if args.outputJSON {
+		count++
 	}
-
-	count = 0
-	if argsOutputCSV {
+	if args.outputJSONL {
+		count++
+	}
+	if args.outputCBOR {
+		count++
+	}
+	if args.outputProtobuf {
+		count++
+	}
+	if args.outputTreemap {
+		count++
+	}
+	if args.outputNcdu {
+		count++
+	}
+	if args.outputNames {
+		count++
+	}
+	if args.outputPrint0 {
+		count++
+	}
+	if args.outputXargs {
+		count++
+	}
+	if len(args.outputFormat) > 0 {
 		count++
 	}
-	if argsOutputHTML {
+	if len(args.outputPrintf) > 0 {
 		count++
 	}
-	if argsOutputJSON {
+	if len(args.outputSQLite) > 0 {
+		count++
+	}
+	if len(args.outputParquet) > 0 {
+		count++
+	}
+	if args.summaryOnly {
 		count++
 	}
 
 	if count > 1 {
-		fmt.Fprintf(os.Stderr, "Error: only one '-o' output argument can be given.\n\n")
+		usageError("only one '-o', '-names', '-print0', '-xargs', '-format', '-printf', or '-ts' output argument can be given.")
 		os.Exit(2)
 	}
 
-	if argsTotals && (argsOutputCSV || argsOutputHTML || argsOutputJSON) {
-		fmt.Fprintf(os.Stderr, "Error: -t can not be used with: -oc, -oh, or -oj\n\n")
+	if args.summaryOnly && (args.totals || len(args.groupBy) > 0 || args.histogram || len(args.timeline) > 0) {
+		usageError("-ts can not be used with: -t, -group, -hist, or -timeline")
+		os.Exit(2)
+	}
+
+	if len(args.summaryFile) > 0 && (args.summaryOnly || args.outputJSONL || args.outputCBOR) {
+		usageError("-tsfile can not be used with: -ts, -ojl, or -ocbor")
+		os.Exit(2)
+	}
+
+	// the other output formats need all entries at once, such as to size the columns of the table
+	if args.streaming && !(args.outputCSV || args.outputTSV || args.outputNames || args.outputPrint0 || args.outputXargs || len(args.outputFormat) > 0 || len(args.outputPrintf) > 0) {
+		usageError("-stream can only be used with: -oc, -ot, -names, -print0, -xargs, -format, or -printf")
+		os.Exit(2)
+	}
+
+	if args.streaming && (args.totals || len(args.summaryFile) > 0 || len(args.groupBy) > 0 || args.histogram || len(args.timeline) > 0 || args.recursive || args.cleanNames) {
+		usageError("-stream can not be used with: -t, -tsfile, -group, -hist, -timeline, -r, -mindepth, -maxdepth, -clean, or -cleanabs")
+		os.Exit(2)
+	}
+
+	if args.totals && (args.outputTreemap || args.outputNcdu || args.outputNames || args.outputPrint0 || args.outputXargs || len(args.outputFormat) > 0 || len(args.outputPrintf) > 0 || len(args.outputSQLite) > 0 || len(args.outputParquet) > 0) {
+		usageError("-t can not be used with: -oh-treemap, -oncdu, -osqlite, -opq, -names, -print0, -xargs, -format, or -printf")
+		os.Exit(2)
+	}
+
+	if args.xargsMax < 1 {
+		usageError("-xargsmax must be at least 1")
+		os.Exit(2)
+	}
+
+	if args.extendedTotals && !args.totals {
+		usageError("-tx can only be used with: -t")
+		os.Exit(2)
+	}
+
+	if args.mountTotals && !args.totals {
+		usageError("-tm can only be used with: -t")
+		os.Exit(2)
+	}
+
+	if args.mountTotals && (args.outputCSV || args.outputTSV || args.outputHTML || args.outputJSON || args.outputProtobuf) {
+		usageError("-tm can not be used with: -oc, -ot, -oh, -oj or -opb")
 		os.Exit(2)
 	}
 
 	// make sure dateNewer is not newer than dateOlder
-	var older, newer time.Time
-	var err error
-	if len(dateOlder) > 0 && len(dateNewer) > 0 {
-		older, err = time.Parse(dateFormat, dateOlder)
-		if err != nil {
-			//goland:noinspection GoUnhandledErrorResult
-			fmt.Fprintln(os.Stderr, "Error when parsing date for '-do':", dateOlder)
+	validateDateRange("-dn", "-do", args.dateNewer, args.dateOlder)
+	validateDateRange("-an", "-ao", args.accessNewer, args.accessOlder)
+
+	// make sure sizeSmaller is not smaller than sizeLarger
+	if args.sizeSmaller > 0 && args.sizeSmaller < args.sizeLarger {
+		logError("'-szs' file size is smaller than '-szl'")
+		os.Exit(2)
+	}
+
+	if args.convertToMiB && args.useSI {
+		logError("'-m' and '-dec' are mutually exclusive")
+		os.Exit(2)
+	}
+
+	if args.relative && args.relativeOnly {
+		logError("'-rel' and '-relonly' are mutually exclusive")
+		os.Exit(2)
+	}
+	if args.addMilliseconds && args.addNanoseconds {
+		logError("'-M' and '-ns' are mutually exclusive")
+		os.Exit(2)
+	}
+	if len(args.customDateFormat) > 0 && (args.addMilliseconds || args.addNanoseconds || args.relativeOnly) {
+		logError("'-datefmt' can not be used with: -M, -ns, or -relonly")
+		os.Exit(2)
+	}
+	if args.useRFC3339 {
+		if !(args.outputCSV || args.outputTSV || args.outputHTML || args.outputJSON) {
+			logError("'-rfc3339' can only be used with: -oc, -ot, -oh, or -oj")
 			os.Exit(2)
 		}
-		newer, err = time.Parse(dateFormat, dateNewer)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Error when parsing date for '-dn':", dateNewer)
+		if len(args.customDateFormat) > 0 || args.relativeOnly {
+			logError("'-rfc3339' can not be used with: -datefmt or -relonly")
 			os.Exit(2)
 		}
-		if older.After(newer) {
-			//goland:noinspection GoUnhandledErrorResult
-			fmt.Fprintln(os.Stderr, "Error: '-dn' date is newer than '-do'")
+	}
+	if args.useUTC && len(args.timeZone) > 0 {
+		logError("'-utc' and '-tz' are mutually exclusive")
+		os.Exit(2)
+	}
+	if args.relativeOnly && args.outputJSON {
+		logError("'-relonly' can not be used with: -oj")
+		os.Exit(2)
+	}
+
+	// these are mutually exclusive
+	if args.longFileNames == true && args.longWidth > 0 {
+		logError("'-long' and '-longwidth' are mutually exclusive")
+		os.Exit(2)
+	}
+
+	if len(args.groupBy) > 0 {
+		valid := false
+		for _, mode := range validGroupModes {
+			if args.groupBy == mode {
+				valid = true
+			}
+		}
+		if !valid {
+			logError("'-group' must be one of: %s", strings.Join(validGroupModes, ", "))
 			os.Exit(2)
 		}
+		if args.totals || args.outputTreemap || args.outputNcdu {
+			logError("'-group' can not be used with: -t, -oh-treemap, or -oncdu")
+			os.Exit(2)
+		}
+	}
+	if args.groupDepth < 0 || (args.groupDepth > 0 && args.groupBy != "dir") {
+		logError("'-groupdepth' must be a positive number and can only be used with: -group dir")
+		os.Exit(2)
 	}
 
-	// make sure sizeSmaller is not smaller than sizeLarger
-	if sizeSmaller > 0 && sizeSmaller < sizeLarger {
-		fmt.Fprintln(os.Stderr, "Error: '-szs' file size is smaller than '-szl'")
+	if args.minDepth < 0 || args.maxDepth < -1 || (args.maxDepth >= 0 && args.minDepth > args.maxDepth) {
+		logError("'-mindepth' and '-maxdepth' can not be negative, and '-mindepth' can not be larger than '-maxdepth'")
 		os.Exit(2)
 	}
 
-	// these are mutually exclusive
-	if longFileNames == true && longWidth > 0 {
-		fmt.Fprintln(os.Stderr, "Error: '-long' and '-longwidth' are mutually exclusive")
+	if args.histogram && (len(args.groupBy) > 0 || args.totals || args.outputCSV || args.outputTSV || args.outputHTML || args.outputJSON || args.outputTreemap || args.outputNcdu) {
+		logError("'-hist' can not be used with: -group, -t, -oc, -ot, -oh, -oj, -oh-treemap, or -oncdu")
 		os.Exit(2)
 	}
+
+	if len(args.timeline) > 0 {
+		valid := false
+		for _, mode := range validTimelineModes {
+			if args.timeline == mode {
+				valid = true
+			}
+		}
+		if !valid {
+			logError("'-timeline' must be one of: %s", strings.Join(validTimelineModes, ", "))
+			os.Exit(2)
+		}
+		if args.histogram || len(args.groupBy) > 0 || args.totals || args.outputCSV || args.outputTSV || args.outputHTML || args.outputJSON || args.outputTreemap || args.outputNcdu {
+			logError("'-timeline' can not be used with: -hist, -group, -t, -oc, -ot, -oh, -oj, -oh-treemap, or -oncdu")
+			os.Exit(2)
+		}
+	}
 }
 
 /*
@@ -651,91 +1747,572 @@ func SortAllEntries(allEntries []FileStat, argsSortSize bool, argsSortSizeDesc b
 		sortName(allEntries, false)
 		return
 	}
-	if argsSortNameCaseInsen {
-		sortNameCaseInsensitive(allEntries, true)
-		return
+	if argsSortNameCaseInsen {
+		sortNameCaseInsensitive(allEntries, true)
+		return
+	}
+	if argsSortNameCaseInsenDesc {
+		sortNameCaseInsensitive(allEntries, false)
+		return
+	}
+}
+
+/*
+entryLess returns the comparison used by SortAllEntries, for sorting outside of memory with -stream
+At this point, (at most) only one of the *argsSortXXX variables will be true
+
+Returns:
+    the comparison, or nil when no sort option was given
+*/
+func entryLess(argsSortSize bool, argsSortSizeDesc bool, argsSortModTime bool, argsSortModTimeDesc bool, argsSortName bool, argsSortNameDesc bool, argsSortNameCaseInsen bool, argsSortNameCaseInsenDesc bool) func(a *FileStat, b *FileStat) bool {
+	switch {
+	case argsSortSize || argsSortSizeDesc:
+		return sizeLess(argsSortSize)
+	case argsSortModTime || argsSortModTimeDesc:
+		return modTimeLess(argsSortModTime)
+	case argsSortName || argsSortNameDesc:
+		return nameLess(argsSortName)
+	case argsSortNameCaseInsen || argsSortNameCaseInsenDesc:
+		return nameCaseInsensitiveLess(argsSortNameCaseInsen)
+	}
+	return nil
+}
+
+// exitConditionMet - the exit status when -baseline finds a change, -fail-if-none finds no entries, or -fail-if-any finds
+// any entries; it differs from the exit status of every error
+const exitConditionMet = 6
+
+// exitPartial - the exit status when the scan is stopped early by Ctrl-C or -timeout, and only partial results are output
+const exitPartial = 7
+
+/*
+main processes & validates cmd line arguments, reads in file names thus creating allEntries
+Next, it sorts the entries and finally renders the results to STDOUT
+*/
+func main() {
+	argsSortSize := flag.Bool("ss", false, "sort by file size")
+	argsSortSizeDesc := flag.Bool("sS", false, "sort by file size, descending")
+
+	argsSortModTime := flag.Bool("sd", false, "sort by file modified date")
+	argsSortModTimeDesc := flag.Bool("sD", false, "sort by file modified date, newest first")
+
+	argsSortName := flag.Bool("sn", false, "sort by file name")
+	argsSortNameDesc := flag.Bool("sN", false, "sort by file name, reverse alphabetical order")
+
+	argsSortNameCaseInsen := flag.Bool("si", false, "sort by file name, ignore case")
+	argsSortNameCaseInsenDesc := flag.Bool("sI", false, "sort by file name, ignore case, reverse alphabetical order")
+
+	argsVersion := flag.Bool("v", false, "show program version and then exit")
+	argsQuiet := flag.Bool("q", false, "do not display file errors; same as: -verbosity quiet")
+	argsVerbosity := flag.String("verbosity", "normal", "how much is reported to STDERR: quiet, normal, verbose (skipped files and timing), or debug (every filter decision)")
+	argsCommas := flag.Bool("c", false, "add comma thousands separator to file sizes")
+	argsMebibytes := flag.Bool("m", false, "convert file sizes to mebibytes")
+	argsSI := flag.Bool("dec", false, "show file sizes in decimal units: KB, MB, GB (powers of 1000)")
+	argsMilliseconds := flag.Bool("M", false, "add milliseconds to file time stamps")
+	argsNanoseconds := flag.Bool("ns", false, "add nanoseconds to file time stamps")
+	argsDateFormat := flag.String("datefmt", "", "format time stamps with a Go time layout such as '2006-01-02 15:04', or a strftime format such as '%Y-%m-%d %H:%M'")
+	argsRFC3339 := flag.Bool("rfc3339", false, "with -oc, -ot, -oh, or -oj, use RFC 3339 time stamps which include the time zone offset")
+	argsUTC := flag.Bool("utc", false, "show time stamps in UTC")
+	argsTimeZone := flag.String("tz", "", "show time stamps in this IANA time zone, such as: America/New_York")
+	argsRelative := flag.Bool("rel", false, "add a column with the time since modification, such as: 3d 4h ago")
+	argsRelativeOnly := flag.Bool("relonly", false, "show the time since modification instead of the modification time")
+	argsTotals := flag.Bool("t", false, "append total file size and file count")
+	argsSummaryOnly := flag.Bool("ts", false, "output only a JSON summary: counts by type, total, average and median file size, and modified date range")
+	argsSummaryFile := flag.String("tsfile", "", "also write the JSON summary of -ts to this file")
+	argsMountTotals := flag.Bool("tm", false, "with -t, also append total, used and free space of each file system")
+	argsExtendedTotals := flag.Bool("tx", false, "with -t, also append median, 90th/99th percentile, std deviation, smallest and largest file sizes")
+
+	argsType := flag.String("type", "", "include only these comma separated `types`: f (file), d (directory), l (symbolic link), p (named pipe), s (socket), c (character device), b (block device); such as: f,l")
+	argsOnlyFiles := flag.Bool("if", false, "include only files; same as: -type f")
+	argsOnlyDirs := flag.Bool("id", false, "include only directories; same as: -type d")
+	argsOnlyLinks := flag.Bool("il", false, "include only symbolic links; same as: -type l")
+	argsOnlyPipes := flag.Bool("ip", false, "include only named pipes (FIFOs); same as: -type p")
+	argsOnlySockets := flag.Bool("is", false, "include only sockets; same as: -type s")
+
+	argsOutputCSV := flag.Bool("oc", false, "output to CSV format")
+	argsCSVDelimiter := flag.String("csvdelim", ",", "with -oc, use this field delimiter, such as: ; or | or tab")
+	argsOutputTSV := flag.Bool("ot", false, "output to tab separated values format")
+	argsOutputHTML := flag.Bool("oh", false, "output to HTML format")
+	argsLinks := flag.Bool("links", false, "with -oh, make each file name a file:// hyperlink")
+	argsLinkBase := flag.String("linkbase", "", "with -oh, make each file name a hyperlink by appending it to this URL, such as: https://intranet/share")
+	argsTitle := flag.String("title", "", "with -oh, the page title and heading")
+	argsTheme := flag.String("theme", "light", "with -oh, the color theme: "+strings.Join(htmlThemes, ", "))
+	argsOutputJSON := flag.Bool("oj", false, "output to JSON format")
+	argsOutputJSONL := flag.Bool("ojl", false, "output to JSON Lines format, writing one object per line as each entry is examined")
+	argsOutputCBOR := flag.Bool("ocbor", false, "output to CBOR binary format, writing one map per entry as each entry is examined")
+	argsOutputProtobuf := flag.Bool("opb", false, "output to Protocol Buffers format, as length delimited messages described by proto/fstat.proto")
+	argsOutputTreemap := flag.Bool("oh-treemap", false, "output to a self-contained HTML treemap of file sizes by directory")
+	argsOutputNcdu := flag.Bool("oncdu", false, "output to ncdu JSON export format, view with: ncdu -f file")
+	argsOutputSQLite := flag.String("osqlite", "", "append all entries to the files table of this SQLite database, along with a new scan_id")
+	argsOutputParquet := flag.String("opq", "", "write all entries to this Parquet file")
+	argsOutputNames := flag.Bool("names", false, "output only the file names, one per line")
+	argsOutputFormat := flag.String("format", "", "output each entry with a Go template, such as: '{{.Size}}\\t{{.FullName}}'; see format.go")
+	argsOutputPrintf := flag.String("printf", "", "output each entry with a find style format, such as: '%s %TY-%Tm-%Td %p\\n'; see printf.go")
+	argsOutputPrint0 := flag.Bool("print0", false, "output only the file names, each followed by a NUL character; use with: xargs -0")
+	argsOutputXargs := flag.Bool("xargs", false, "output the file names shell quoted, in batches of one line each no longer than -xargsmax; use with: xargs -L1")
+	argsXargsMax := flag.Int("xargsmax", xargsMaxDefault, "with -xargs, the most bytes in each batch")
+
+	argsFilenames := flag.String("f", "", "use these files instead of from a file or STDIN, can include wildcards")
+	argsExcludeDot := flag.Bool("ed", false, "exclude-dot, exclude all dot files and directories")
+	argsExcludeRE := flag.String("er", "", "exclude-regexp, exclude based on given regular expression; use .* instead of just *")
+	argsIncludeRE := flag.String("ir", "", "include-regexp, only include based on given regular expression; use .* instead of just *")
+	argsProfile := flag.String("profile", "", "use the options of this profile from the profiles table of a configuration file; see config.go")
+	argsNoConfig := flag.Bool("noconfig", false, "do not read default options from fstat/config.toml in the user's configuration directory or .fstat.toml in the current directory; see config.go")
+	argsHighlight := flag.String("highlight", "", "with the default table, mark the entries modified within this time, such as: 15m, 2h, 1d; shown in color on a terminal, otherwise with a * after the modified time")
+	argsFuzzy := flag.String("fuzzy", "", "only include file names that fuzzy match this query, as with fzf; without a sort option, the best matches are output first; see fuzzy.go")
+	argsNoIgnore := flag.Bool("noignore", false, "do not read exclusion patterns from .fstatignore in the current or home directory")
+	argsExtensions := flag.String("ext", "", "only include files with one of these comma separated extensions, ignoring case, such as: .log,.tmp,.bak")
+
+	argsDateNewer := flag.String("dn", "", "only include if date is equal or newer than given YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339 date")
+	argsDateOlder := flag.String("do", "", "only include if date is equal or older than given YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339 date")
+
+	argsAccessNewer := flag.String("an", "", "only include if last access time is equal or newer than given YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339 date")
+	argsAccessOlder := flag.String("ao", "", "only include if last access time is equal or older than given YYYYMMDD, YYYYMMDDHHMMSS, or RFC 3339 date")
+
+	argsUser := flag.String("user", "", "only include if owned by this user name or uid")
+	argsGroupFilter := flag.String("grp", "", "only include if owned by this group name or gid")
+
+	argsPerm := flag.String("perm", "", "only include if permission bits match, as with find: 644 (exactly), -220 (all of), /022 (any of), !/111 (none of); symbolic modes such as u+x,o+w are allowed")
+	argsWhere := flag.String("where", "", "only include if this expression is true, such as: 'size > 10MB && ext == \".log\" && age > 30d'; see where.go")
+	argsMode := flag.Bool("mode", false, "add a column with the file mode, such as: -rw-r--r--")
+	argsDeviceNumber := flag.Bool("devnum", false, "add a column with the major and minor numbers of character (type C) and block (type B) devices, such as: 8:1")
+
+	argsSizeSmaller := new(int64)
+	flag.Var((*sizeValue)(argsSizeSmaller), "szs", "only include if file size is equal or smaller than the given `size`, such as: 500, 500K, 10MB, 2GiB (K, M, G are binary; KB, MB, GB are decimal)")
+	argsSizeLarger := new(int64)
+	flag.Var((*sizeValue)(argsSizeLarger), "szl", "only include if file size is equal or larger than the given `size`, such as: 500, 500K, 10MB, 2GiB (K, M, G are binary; KB, MB, GB are decimal)")
+
+	argsLongFileNames := flag.Bool("long", false, "Don't use ellipses for long file names; useful when piping or using redirection")
+	argsLongWidth := flag.Int("longwidth", 0, "Set max width; Useful when piping or using redirection")
+	argsTruncate := flag.String("truncate", "", "with the default table, the `column` shortened to fit the width, instead of: name; add :left to keep the end of each value, such as: name:left")
+	argsShorten := flag.String("shorten", "", "with the default table, how the shortened column is shortened: dirs (such as /home/.../project/file.txt), middle, left (default \"middle\")")
+	argsMinWidth := flag.String("minwidth", "", "with the default table, comma separated minimum column widths, such as: name:30,mode:12")
+
+	argsAllocated := flag.Bool("alloc", false, "add a column with the allocated size on disk; sparse files are marked with: S")
+	argsGzipSize := flag.Bool("gzsize", false, "add a column with the uncompressed size of each .gz file, read from its gzip trailer; with -oj and -ojl, also an uncompressed field")
+	argsEntropy := flag.Bool("entropy", false, "add a column with the entropy of each file in bits per byte, estimated from up to 3 samples of 64 KiB; near 8 means already compressed or encrypted; with -oj and -ojl, also an entropy field")
+	argsClass := flag.Bool("class", false, "add a column which classifies each file as text or binary, by reading its first 8000 bytes for NUL bytes and invalid UTF-8; with -oj and -ojl, also a class field")
+	argsText := flag.Bool("text", false, "include only files whose contents are text, see -class")
+	argsBinary := flag.Bool("binary", false, "include only files whose contents are binary, see -class")
+	argsShebang := flag.Bool("shebang", false, "add a column with the interpreter named by the #! line of each executable file, such as: /usr/bin/env python3; with -oj and -ojl, also an interpreter field")
+	argsImageSize := flag.Bool("imgsize", false, "add a column with the width and height of each .png, .jpg, .jpeg and .gif file, such as: 1920x1080; with -oj and -ojl, also width and height fields")
+	argsDuration := flag.Bool("duration", false, "add a column with the duration of each audio and video file, read from its container headers, see media.go; with -t, also the total and average duration; with -oj and -ojl, also a duration field in seconds")
+	argsPages := flag.Bool("pages", false, "add a column with the number of pages of each PDF file, read from its page tree, see pdf.go; with -t, also the total number of pages; with -oj and -ojl, also a pages field")
+	argsGit := flag.Bool("git", false, "add columns with the abbreviated hash, author and date of the last commit of each entry in a git repository, see gitlog.go; with -oj and -ojl, also commit, author and committime fields")
+	argsOnlySparse := flag.Bool("sparse", false, "include only sparse files, whose allocated size is less than half of their size")
+
+	argsStream := flag.Bool("stream", false, "read the file list one name at a time and output entries in batches, so memory use stays low; use with -oc, -ot, -names, -print0, -xargs, -format, or -printf; a sort option uses temporary files, see -sortchunk")
+	argsSortChunk := flag.Int("sortchunk", 1000000, "with -stream and a sort option, sort this many entries in memory at a time, using temporary files for the rest")
+	argsProgress := flag.Bool("progress", false, "periodically output the number of files examined, errors and rate to STDERR")
+	argsStats := flag.Bool("stats", false, "at the end, output the wall time, files per second, bytes summed, errors and slowest stat calls to STDERR")
+	argsCache := flag.String("cache", "", "keep stat results in this directory, so that repeat runs do not examine files again until -cachettl has passed")
+	argsCacheTTL := flag.String("cachettl", "1h", "with -cache, how long stat results are used, such as: 30m, 12h, 7d")
+	argsSnapshotSave := flag.String("snapshot-save", "", "also save all examined entries to this snapshot file, which can be used with -snapshot-load")
+	argsSnapshotLoad := flag.String("snapshot-load", "", "use the entries saved by -snapshot-save instead of examining files; all filter, sort and output options can be used")
+	argsDiff := flag.String("diff", "", "compare the entries saved by -snapshot-save to the current entries, and output the added, removed, resized and redated files")
+	argsBaseline := flag.String("baseline", "", "like -diff, but output the changes as JSON unless -oc or -ot is used, and exit with status 6 when anything changed")
+	argsCmp := flag.Bool("cmp", false, "compare two directory trees given as: dirA dirB, and output the files only in one of them or with a different size or modified time")
+	argsCmpHash := flag.Bool("cmphash", false, "with -cmp, also compare the contents of files that are the same size by their SHA-256 hash")
+	argsWatch := flag.Bool("watch", false, "after the listing, output create, modify and delete events for the listed entries until interrupted; with -r, also for new entries; use -ojl for JSON")
+	argsEvery := flag.String("every", "", "run the scan again after this much time until interrupted, such as: 30s, 5m, 1h; requires a file list or -f")
+	argsDeltas := flag.Bool("deltas", false, "with -every, output all entries once and then only the changes found by each later scan, like -diff")
+	argsNotify := flag.String("notify", "", "POST the entries and their summary as JSON to this webhook URL when any are found; also used for changes and -watch events")
+	argsFailIfNone := flag.Bool("fail-if-none", false, "exit with status 6 when no entries are output")
+	argsFailIfAny := flag.Bool("fail-if-any", false, "exit with status 6 when any entries are output")
+	argsExplain := flag.Bool("explain", false, "instead of the entries, output every file name and the filter option that excluded it; use with the default table, -oc, -ot, or -oj")
+	argsDups := flag.Bool("dups", false, "instead of the entries, output groups of files with identical contents, compared by SHA-256 hash; use with the default table, -oc, -ot, or -oj")
+	argsTop := flag.Int("top", 0, "instead of the entries, output this `number` of the largest files, with their percentage and cumulative percentage of the total size; use with the default table, -oc, -ot, or -oj")
+	argsOldNew := flag.Int("oldnew", 0, "instead of the entries, output this `number` of the oldest entries followed by the same number of the newest, by modified time; use with the default table, -oc, -ot, or -oj")
+	argsSameName := flag.Bool("samename", false, "instead of the entries, output groups of files with the same base name in different directories, with their number of copies, size range and newest copy; use with the default table, -oc, -ot, or -oj")
+	argsBadNames := flag.Bool("badnames", false, "instead of the entries, output the names with invalid UTF-8, control characters, or a trailing space or dot, and a suggested name for each; use with the default table, -oc, -ot, or -oj")
+	argsMaxPath := flag.Int("maxpath", 0, "instead of the entries, output those whose absolute path is longer than this `length`, such as 260 for Windows, counted in UTF-16 code units; use with the default table, -oc, -ot, or -oj")
+	argsPortable := flag.Bool("portable", false, "instead of the entries, output the names which can not be used on Windows: characters such as <>:\"|?*, reserved device names such as CON or NUL, or a trailing space or dot; use with the default table, -oc, -ot, or -oj")
+	argsTUI := flag.Bool("tui", false, "browse the entries in an interactive terminal UI, which can sort, filter, open directories and mark entries; the marked names are output when quitting; see tui.go")
+	argsErrors := flag.Bool("errors", false, "include the files that could not be examined in the output: an error count below the table or in the -oc, -ot, -oh footer, and an errors array with -oj")
+	argsLog := flag.String("log", "plain", "how errors and other diagnostics are written to STDERR: plain, text, or json; text and json add a time stamp and level to each")
+	argsLogLevel := flag.String("loglevel", "info", "only write diagnostics of at least this level: debug, info, warn, or error")
+	argsRecursive := flag.Bool("r", false, "recursively include everything beneath each listed directory")
+	argsMinDepth := flag.Int("mindepth", 0, "with -r, only include entries at least this many levels below each listed name; implies -r")
+	argsMaxDepth := flag.Int("maxdepth", -1, "with -r, do not descend more than this many levels below each listed name; implies -r")
+	argsFollowLinks := flag.Bool("L", false, "with -r, also descend into symbolic links to directories; links which loop back to a directory being walked are reported and not followed")
+	argsArchives := flag.Bool("archives", false, "also list the members of each .zip, .tar, .tar.gz and .tgz file as entries named archive!inner/path, with their uncompressed sizes and modified times; all filters apply to them")
+
+	argsClean := flag.Bool("clean", false, "normalize listed file names, resolving . and .. and removing duplicates")
+	argsCleanAbs := flag.Bool("cleanabs", false, "same as -clean, but also convert listed file names to absolute paths")
+	argsAbsolute := flag.Bool("abs", false, "output absolute paths; filters and sort options use them too")
+	argsSplitName := flag.Bool("splitname", false, "replace the Name column with Directory and Basename columns; with -oj, -ojl, -ocbor, -opb, -opq and -osqlite, also add directory and basename fields")
+	argsUNC := flag.Bool("unc", false, "on Windows, output the UNC path of the share instead of a mapped network drive letter, such as: \\\\server\\share instead of Z:; use with -abs for relative names")
+	argsRelativeTo := flag.String("relto", "", "output paths relative to this `directory`; filters and sort options use them too")
+
+	argsDirUsage := flag.Bool("du", false, "report the cumulative size of all files within each directory, instead of the directory's own size")
+
+	argsSameDevice := flag.Bool("xdev", false, "only include entries on the same file system as the first entry; with -du, do not cross file systems")
+	argsNetTimeout := flag.String("nettimeout", "10s", "on NFS, SMB and other network file systems, the longest time to wait for each file, such as: 3s; a file system which does not respond in time is skipped for the rest of the run; 0s waits forever; see netfs.go")
+	argsTimeout := flag.String("timeout", "", "stop the scan after this much time, such as: 30s, 5m, 1h, and output the entries examined so far, marked as partial; Ctrl-C does the same; see cancel.go")
+
+	argsGroupBy := flag.String("group", "", "aggregate file count and size by: dir, ext, owner, month, year")
+	argsGroupDepth := flag.Int("groupdepth", 0, "with '-group dir', only use this many leading path components")
+	argsHistogram := flag.Bool("hist", false, "output a histogram of file sizes")
+	argsTimeline := flag.String("timeline", "", "output a timeline of modified file counts per: day, week")
+
+	flag.Usage = func() {
+		pgmName := os.Args[0]
+		if strings.HasPrefix(os.Args[0], "./") {
+			pgmName = os.Args[0][2:]
+		}
+		fmt.Fprintf(os.Stderr, "\n%s: Get info for a list of files across multiple directories\n", pgmName)
+		fmt.Fprintf(os.Stderr, "usage: %s [command] [options] [filename|or blank for STDIN]\n", pgmName)
+		fmt.Fprintf(os.Stderr, "       (this file should contain a list of files to process)\n\n")
+		printSubcommands(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nNotes:\n")
+		fmt.Fprintf(os.Stderr, "  (1) -er precedes -ir\n")
+		fmt.Fprintf(os.Stderr, "  (2) Use '(?i)' at the beginning of a regex to make it case insensitive\n")
+		fmt.Fprintf(os.Stderr, "  (3) Remote files can be listed as sftp://user@host/path or webdavs://user@host/path; see remote.go\n")
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+
+	expanded, err := expandSubcommand(os.Args)
+	if err != nil {
+		usageError("%s", err)
+		os.Exit(2)
+	}
+	os.Args = expanded
+	flag.Parse()
+	if err := ApplyConfig(!*argsNoConfig, *argsProfile); err != nil {
+		usageError("%s", err)
+		os.Exit(2)
+	}
+	if err := setupLogging(*argsLog, *argsLogLevel); err != nil {
+		usageError("%s", err)
+		os.Exit(2)
+	}
+	if err := setVerbosity(*argsVerbosity, *argsQuiet); err != nil {
+		usageError("%s", err)
+		os.Exit(2)
+	}
+	quiet := verbosityQuiet == verbosity
+	if *argsVersion {
+		fmt.Fprintf(os.Stderr, "version %s\n", version)
+		os.Exit(1)
+	}
+
+	onlyTypes, err := parseTypes(*argsType, map[string]bool{"F": *argsOnlyFiles, "D": *argsOnlyDirs, "L": *argsOnlyLinks, "P": *argsOnlyPipes, "S": *argsOnlySockets})
+	if err != nil {
+		usageError("%s", err)
+		os.Exit(2)
+	}
+	ValidateArgs(validationArgs{
+		sortSize:              *argsSortSize,
+		sortSizeDesc:          *argsSortSizeDesc,
+		sortModTime:           *argsSortModTime,
+		sortModTimeDesc:       *argsSortModTimeDesc,
+		sortName:              *argsSortName,
+		sortNameDesc:          *argsSortNameDesc,
+		sortNameCaseInsen:     *argsSortNameCaseInsen,
+		sortNameCaseInsenDesc: *argsSortNameCaseInsenDesc,
+		totals:                *argsTotals,
+		extendedTotals:        *argsExtendedTotals,
+		mountTotals:           *argsMountTotals,
+		outputCSV:             *argsOutputCSV,
+		outputTSV:             *argsOutputTSV,
+		outputHTML:            *argsOutputHTML,
+		outputJSON:            *argsOutputJSON,
+		outputJSONL:           *argsOutputJSONL,
+		outputCBOR:            *argsOutputCBOR,
+		outputProtobuf:        *argsOutputProtobuf,
+		outputTreemap:         *argsOutputTreemap,
+		outputNcdu:            *argsOutputNcdu,
+		outputNames:           *argsOutputNames,
+		outputPrint0:          *argsOutputPrint0,
+		outputXargs:           *argsOutputXargs,
+		xargsMax:              *argsXargsMax,
+		outputFormat:          *argsOutputFormat,
+		outputPrintf:          *argsOutputPrintf,
+		outputSQLite:          *argsOutputSQLite,
+		outputParquet:         *argsOutputParquet,
+		summaryOnly:           *argsSummaryOnly,
+		summaryFile:           *argsSummaryFile,
+		streaming:             *argsStream,
+		sortChunk:             *argsSortChunk,
+		recursive:             *argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0,
+		cleanNames:            *argsClean || *argsCleanAbs,
+		dateNewer:             *argsDateNewer,
+		dateOlder:             *argsDateOlder,
+		accessNewer:           *argsAccessNewer,
+		accessOlder:           *argsAccessOlder,
+		sizeSmaller:           *argsSizeSmaller,
+		sizeLarger:            *argsSizeLarger,
+		longFileNames:         *argsLongFileNames,
+		longWidth:             *argsLongWidth,
+		groupBy:               *argsGroupBy,
+		groupDepth:            *argsGroupDepth,
+		histogram:             *argsHistogram,
+		timeline:              *argsTimeline,
+		convertToMiB:          *argsMebibytes,
+		useSI:                 *argsSI,
+		relative:              *argsRelative,
+		relativeOnly:          *argsRelativeOnly,
+		addMilliseconds:       *argsMilliseconds,
+		customDateFormat:      *argsDateFormat,
+		useUTC:                *argsUTC,
+		timeZone:              *argsTimeZone,
+		useRFC3339:            *argsRFC3339,
+		addNanoseconds:        *argsNanoseconds,
+		minDepth:              *argsMinDepth,
+		maxDepth:              *argsMaxDepth,
+	})
+	csvDelimiter, err := parseCSVDelimiter(*argsCSVDelimiter)
+	if err != nil {
+		logError("'-csvdelim' %s", err)
+		os.Exit(2)
+	}
+	if "," != *argsCSVDelimiter && !*argsOutputCSV {
+		usageError("-csvdelim can only be used with: -oc")
+		os.Exit(2)
+	}
+
+	linkBase := *argsLinkBase
+	if *argsLinks && 0 == len(linkBase) {
+		linkBase = "file://"
+	}
+	if len(linkBase) > 0 && !*argsOutputHTML {
+		usageError("-links and -linkbase can only be used with: -oh")
+		os.Exit(2)
+	}
+	if !validHTMLTheme(*argsTheme) {
+		usageError("-theme must be one of: %s", strings.Join(htmlThemes, ", "))
+		os.Exit(2)
+	}
+	if (len(*argsTitle) > 0 || "light" != *argsTheme) && !*argsOutputHTML {
+		usageError("-title and -theme can only be used with: -oh")
+		os.Exit(2)
+	}
+	if len(*argsSnapshotLoad) > 0 && (len(*argsFilenames) > 0 || len(flag.Args()) > 0 || *argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0 || len(*argsCache) > 0 || *argsStream) {
+		usageError("-snapshot-load can not be used with: -f, a file list, -r, -mindepth, -maxdepth, -cache, or -stream")
+		os.Exit(2)
+	}
+	if len(*argsBaseline) > 0 {
+		if len(*argsDiff) > 0 {
+			usageError("-baseline can not be used with: -diff")
+			os.Exit(2)
+		}
+		*argsDiff = *argsBaseline
+		if !*argsOutputCSV && !*argsOutputTSV {
+			*argsOutputJSON = true
+		}
+	}
+	// changesConflict - options that can not be used when only changes are output, by -diff, -baseline, -cmp and -deltas
+	changesConflict := *argsOutputHTML || *argsOutputJSONL || *argsOutputCBOR || *argsOutputProtobuf || *argsOutputTreemap || *argsOutputNcdu || *argsOutputNames || *argsOutputPrint0 || *argsOutputXargs || len(*argsOutputFormat) > 0 || len(*argsOutputPrintf) > 0 || len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || *argsSummaryOnly || *argsTotals || len(*argsGroupBy) > 0 || *argsHistogram || len(*argsTimeline) > 0 || *argsStream
+	if len(*argsDiff) > 0 && changesConflict {
+		usageError("-diff and -baseline can only be used with the default table, -oc, -ot, or -oj output")
+		os.Exit(2)
+	}
+	if *argsCmp && (len(*argsFilenames) > 0 || *argsRecursive || *argsMinDepth > 0 || len(*argsSnapshotSave) > 0 || len(*argsSnapshotLoad) > 0 || len(*argsDiff) > 0 || len(*argsSummaryFile) > 0 || changesConflict) {
+		usageError("-cmp can only be used with the default table, -oc, -ot, or -oj output, and not with: -f, -r, -mindepth, -snapshot-save, -snapshot-load, or -diff")
+		os.Exit(2)
+	}
+	if *argsFollowLinks && !(*argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0 || *argsCmp) {
+		usageError("-L can only be used with: -r, -mindepth, -maxdepth, or -cmp")
+		os.Exit(2)
+	}
+	if *argsArchives && (*argsDups || *argsWatch || *argsCmp || len(*argsSnapshotSave) > 0 || len(*argsSnapshotLoad) > 0) {
+		usageError("-archives can not be used with: -dups, -watch, -cmp, -snapshot-save, or -snapshot-load")
+		os.Exit(2)
+	}
+	if *argsGit {
+		if _, err := exec.LookPath("git"); err != nil {
+			usageError("-git requires the git command to be in the PATH")
+			os.Exit(2)
+		}
+	}
+	if *argsText && *argsBinary {
+		usageError("-text can not be used with: -binary")
+		os.Exit(2)
+	}
+	onlyClass := ""
+	if *argsText {
+		onlyClass = classText
+	} else if *argsBinary {
+		onlyClass = classBinary
+	}
+	if *argsCmp && 2 != len(flag.Args()) {
+		usageError("-cmp requires two directories")
+		os.Exit(2)
+	}
+	if *argsCmpHash && !*argsCmp {
+		usageError("-cmphash can only be used with: -cmp")
+		os.Exit(2)
+	}
+	if *argsWatch && (*argsOutputCSV || *argsOutputTSV || *argsOutputHTML || *argsOutputJSON || *argsOutputCBOR || *argsOutputProtobuf || *argsOutputTreemap || *argsOutputNcdu || *argsOutputNames || *argsOutputPrint0 || *argsOutputXargs || len(*argsOutputFormat) > 0 || len(*argsOutputPrintf) > 0 || len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || *argsSummaryOnly || len(*argsGroupBy) > 0 || *argsHistogram || len(*argsTimeline) > 0 || *argsStream || len(*argsSnapshotLoad) > 0 || len(*argsDiff) > 0 || *argsCmp) {
+		usageError("-watch can only be used with the default table or -ojl output, and not with: -stream, -snapshot-load, -diff, -baseline, or -cmp")
+		os.Exit(2)
+	}
+	if len(*argsSnapshotSave) > 0 && (*argsStream || *argsOutputJSONL || *argsOutputCBOR) {
+		usageError("-snapshot-save can not be used with: -stream, -ojl, or -ocbor")
+		os.Exit(2)
+	}
+
+	if len(*argsNotify) > 0 {
+		if u, err := url.Parse(*argsNotify); err != nil || ("http" != u.Scheme && "https" != u.Scheme) || 0 == len(u.Host) {
+			usageError("-notify must be an http or https URL")
+			os.Exit(2)
+		}
+		if *argsStream || *argsOutputJSONL || *argsOutputCBOR {
+			usageError("-notify can not be used with: -stream, -ojl, or -ocbor")
+			os.Exit(2)
+		}
+	}
+	if *argsExplain && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0) {
+		usageError("-explain can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, or -every")
+		os.Exit(2)
+	}
+	if *argsDups && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain) {
+		usageError("-dups can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, or -explain")
+		os.Exit(2)
+	}
+	if *argsTop < 0 {
+		usageError("-top must be at least 1")
+		os.Exit(2)
+	}
+	if *argsTop > 0 && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups) {
+		usageError("-top can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, or -dups")
+		os.Exit(2)
+	}
+	if *argsOldNew < 0 {
+		usageError("-oldnew must be at least 1")
+		os.Exit(2)
+	}
+	if *argsOldNew > 0 && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0) {
+		usageError("-oldnew can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, or -top")
+		os.Exit(2)
+	}
+	if *argsSameName && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0) {
+		usageError("-samename can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, -top, or -oldnew")
+		os.Exit(2)
+	}
+	if *argsBadNames && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0 || *argsSameName) {
+		usageError("-badnames can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, -top, -oldnew, or -samename")
+		os.Exit(2)
+	}
+	if *argsMaxPath < 0 {
+		usageError("-maxpath must be at least 1")
+		os.Exit(2)
+	}
+	if *argsMaxPath > 0 && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0 || *argsSameName || *argsBadNames) {
+		usageError("-maxpath can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, -top, -oldnew, -samename, or -badnames")
+		os.Exit(2)
+	}
+	if *argsPortable && (changesConflict || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0 || *argsSameName || *argsBadNames || *argsMaxPath > 0) {
+		usageError("-portable can only be used with the default table, -oc, -ot, or -oj output, and not with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, -top, -oldnew, -samename, -badnames, or -maxpath")
+		os.Exit(2)
+	}
+	if *argsTUI && (changesConflict || *argsOutputCSV || *argsOutputTSV || *argsOutputJSON || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0 || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0 || *argsSameName || *argsBadNames || *argsMaxPath > 0 || *argsPortable || *argsFailIfNone || *argsFailIfAny) {
+		usageError("-tui can not be used with other output options, or with: -diff, -baseline, -cmp, -watch, -every, -explain, -dups, -top, -oldnew, -samename, -badnames, -maxpath, -portable, -fail-if-none, or -fail-if-any")
+		os.Exit(2)
+	}
+	if *argsAbsolute && len(*argsRelativeTo) > 0 {
+		usageError("-abs and -relto are mutually exclusive")
+		os.Exit(2)
+	}
+	if (*argsAbsolute || len(*argsRelativeTo) > 0 || *argsUNC) && (*argsCmp || *argsWatch) {
+		usageError("-abs, -relto and -unc can not be used with: -cmp or -watch")
+		os.Exit(2)
+	}
+	if len(*argsRelativeTo) > 0 && *argsDups {
+		usageError("-relto can not be used with: -dups")
+		os.Exit(2)
+	}
+	if *argsSplitName && (*argsOutputNames || *argsOutputPrint0 || *argsOutputXargs || *argsOutputTreemap || *argsOutputNcdu || *argsSummaryOnly || len(*argsGroupBy) > 0 || *argsHistogram || len(*argsTimeline) > 0) {
+		usageError("-splitname can not be used with: -names, -print0, -xargs, -oh-treemap, -oncdu, -ts, -group, -hist, or -timeline")
+		os.Exit(2)
+	}
+	if *argsUNC && !uncSupported {
+		usageError("-unc is only supported on Windows")
+		os.Exit(2)
+	}
+	rename, err := pathRenamer(*argsAbsolute, *argsRelativeTo, *argsUNC)
+	if err != nil {
+		usageError("invalid -relto: %s", err)
+		os.Exit(2)
+	}
+
+	// options that only apply to the default table
+	notTable := *argsOutputCSV || *argsOutputTSV || *argsOutputHTML || *argsOutputJSON || *argsOutputJSONL || *argsOutputCBOR || *argsOutputProtobuf || *argsOutputTreemap || *argsOutputNcdu || *argsOutputNames || *argsOutputPrint0 || *argsOutputXargs || len(*argsOutputFormat) > 0 || len(*argsOutputPrintf) > 0 || len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || *argsSummaryOnly || len(*argsGroupBy) > 0 || *argsHistogram || len(*argsTimeline) > 0 || *argsStream || len(*argsDiff) > 0 || *argsCmp || *argsExplain || *argsDups || *argsTop > 0 || *argsOldNew > 0 || *argsSameName || *argsBadNames || *argsMaxPath > 0 || *argsPortable || *argsTUI
+	var highlight time.Duration
+	if len(*argsHighlight) > 0 {
+		highlight, err = parseAge(*argsHighlight)
+		if err != nil || highlight <= 0 {
+			usageError("invalid -highlight: %s; use a time such as: 15m, 2h, 1d", *argsHighlight)
+			os.Exit(2)
+		}
+		if notTable {
+			usageError("-highlight can only be used with the default table output, and not with: -diff, -baseline, -cmp, -explain, -dups, -top, -oldnew, -samename, -badnames, -maxpath, -portable, or -tui")
+			os.Exit(2)
+		}
+	}
+	if *argsErrors && (*argsOutputJSONL || *argsOutputCBOR || *argsOutputProtobuf || *argsOutputTreemap || *argsOutputNcdu || *argsOutputNames || *argsOutputPrint0 || *argsOutputXargs || len(*argsOutputFormat) > 0 || len(*argsOutputPrintf) > 0 || len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || *argsSummaryOnly || len(*argsGroupBy) > 0 || *argsHistogram || len(*argsTimeline) > 0 || *argsStream || len(*argsDiff) > 0 || *argsCmp || *argsExplain || *argsDups) {
+		usageError("-errors can only be used with the default table, -oc, -ot, -oh, or -oj output, and not with: -stream, -diff, -baseline, -cmp, -explain, or -dups")
+		os.Exit(2)
+	}
+	if (*argsFailIfNone || *argsFailIfAny) && (*argsFailIfNone == *argsFailIfAny || len(*argsDiff) > 0 || *argsCmp || *argsWatch || len(*argsEvery) > 0) {
+		usageError("only one of -fail-if-none and -fail-if-any can be given, and not with: -diff, -baseline, -cmp, -watch, or -every")
+		os.Exit(2)
+	}
+	if len(*argsEvery) > 0 && 0 == len(flag.Args()) && 0 == len(*argsFilenames) && 0 == len(*argsSnapshotLoad) {
+		usageError("-every requires a file list or -f, since STDIN can only be read once")
+		os.Exit(2)
+	}
+	if len(*argsEvery) > 0 && *argsWatch {
+		usageError("-every can not be used with: -watch")
+		os.Exit(2)
+	}
+	if *argsDeltas && (0 == len(*argsEvery) || len(*argsSnapshotSave) > 0 || len(*argsDiff) > 0 || *argsCmp || changesConflict) {
+		usageError("-deltas requires -every, can only be used with the default table, -oc, -ot, or -oj output, and not with: -snapshot-save, -diff, -baseline, or -cmp")
+		os.Exit(2)
 	}
-	if argsSortNameCaseInsenDesc {
-		sortNameCaseInsensitive(allEntries, false)
+	if len(*argsEvery) > 0 {
+		interval, err := parseAge(*argsEvery)
+		if err != nil || interval <= 0 {
+			usageError("invalid -every: %s", *argsEvery)
+			os.Exit(2)
+		}
+		RunEvery(interval, *argsDeltas, quiet)
 		return
 	}
-}
-
-/*
-main processes & validates cmd line arguments, reads in file names thus creating allEntries
-Next, it sorts the entries and finally renders the results to STDOUT
-*/
-func main() {
-	argsSortSize := flag.Bool("ss", false, "sort by file size")
-	argsSortSizeDesc := flag.Bool("sS", false, "sort by file size, descending")
-
-	argsSortModTime := flag.Bool("sd", false, "sort by file modified date")
-	argsSortModTimeDesc := flag.Bool("sD", false, "sort by file modified date, newest first")
-
-	argsSortName := flag.Bool("sn", false, "sort by file name")
-	argsSortNameDesc := flag.Bool("sN", false, "sort by file name, reverse alphabetical order")
-
-	argsSortNameCaseInsen := flag.Bool("si", false, "sort by file name, ignore case")
-	argsSortNameCaseInsenDesc := flag.Bool("sI", false, "sort by file name, ignore case, reverse alphabetical order")
-
-	argsVersion := flag.Bool("v", false, "show program version and then exit")
-	argsQuiet := flag.Bool("q", false, "do not display file errors")
-	argsCommas := flag.Bool("c", false, "add comma thousands separator to file sizes")
-	argsMebibytes := flag.Bool("m", false, "convert file sizes to mebibytes")
-	argsMilliseconds := flag.Bool("M", false, "add milliseconds to file time stamps")
-	argsTotals := flag.Bool("t", false, "append total file size and file count")
-
-	argsOnlyFiles := flag.Bool("if", false, "include only files")
-	argsOnlyDirs := flag.Bool("id", false, "include only directories")
-	argsOnlyLinks := flag.Bool("il", false, "include only symbolic links")
-
-	argsOutputCSV := flag.Bool("oc", false, "output to CSV format")
-	argsOutputHTML := flag.Bool("oh", false, "output to HTML format")
-	argsOutputJSON := flag.Bool("oj", false, "output to JSON format")
-
-	argsFilenames := flag.String("f", "", "use these files instead of from a file or STDIN, can include wildcards")
-	argsExcludeDot := flag.Bool("ed", false, "exclude-dot, exclude all dot files and directories")
-	argsExcludeRE := flag.String("er", "", "exclude-regexp, exclude based on given regular expression; use .* instead of just *")
-	argsIncludeRE := flag.String("ir", "", "include-regexp, only include based on given regular expression; use .* instead of just *")
-
-	argsDateNewer := flag.String("dn", "", "only include if date is equal or newer than given YYYYMMDD date")
-	argsDateOlder := flag.String("do", "", "only include if date is equal or older than given YYYYMMDD date")
-
-	argsSizeSmaller := flag.Int64("szs", 0, "only include if file size is equal or smaller than given value (in bytes)")
-	argsSizeLarger := flag.Int64("szl", 0, "only include if file size is equal or larger than given value (in bytes)")
-
-	argsLongFileNames := flag.Bool("long", false, "Don't use ellipses for long file names; useful when piping or using redirection")
-	argsLongWidth := flag.Int("longwidth", 0, "Set max width; Useful when piping or using redirection")
 
-	flag.Usage = func() {
-		pgmName := os.Args[0]
-		if strings.HasPrefix(os.Args[0], "./") {
-			pgmName = os.Args[0][2:]
+	var printfParts []printfPart
+	if len(*argsOutputPrintf) > 0 {
+		printfParts, err = compilePrintf(*argsOutputPrintf)
+		if err != nil {
+			logError("invalid 'printf' format: %s", err)
+			os.Exit(2)
 		}
-		fmt.Fprintf(os.Stderr, "\n%s: Get info for a list of files across multiple directories\n", pgmName)
-		fmt.Fprintf(os.Stderr, "usage: %s [options] [filename|or blank for STDIN]\n", pgmName)
-		fmt.Fprintf(os.Stderr, "       (this file should contain a list of files to process)\n\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nNotes:\n")
-		fmt.Fprintf(os.Stderr, "  (1) -er precedes -ir\n")
-		fmt.Fprintf(os.Stderr, "  (2) Use '(?i)' at the beginning of a regex to make it case insensitive\n")
-		fmt.Fprintf(os.Stderr, "\n")
-	}
-
-	flag.Parse()
-	if *argsVersion {
-		fmt.Fprintf(os.Stderr, "version %s\n", version)
-		os.Exit(1)
 	}
 
-	ValidateArgs(*argsSortSize, *argsSortSizeDesc, *argsSortModTime, *argsSortModTimeDesc, *argsSortName, *argsSortNameDesc, *argsSortNameCaseInsen, *argsSortNameCaseInsenDesc, *argsOnlyFiles, *argsOnlyDirs, *argsOnlyLinks, *argsTotals, *argsOutputCSV, *argsOutputHTML, *argsOutputJSON, *argsDateNewer, *argsDateOlder, *argsSizeSmaller, *argsSizeLarger, *argsLongFileNames, *argsLongWidth)
 	args := flag.Args()
 	var allFilenames []string
+	var nextName func() (string, bool)
 
 	// get a list of filenames by either using -f
 	// or by reading from a file
 	// or by reading from STDIN
-	if len(*argsFilenames) > 0 { // using -f
+	var cache *statCache
+	if len(*argsSnapshotLoad) > 0 { // using a previous scan
+		allFilenames, cache, err = LoadSnapshot(*argsSnapshotLoad)
+		if err != nil {
+			logError("unable to load snapshot: %s", err)
+			os.Exit(1)
+		}
+	} else if *argsCmp { // CompareDirs() walks both directories
+		for _, dir := range args {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				usageError("-cmp: not a directory: %s", dir)
+				os.Exit(2)
+			}
+		}
+	} else if len(*argsFilenames) > 0 { // using -f
 		// -f can be a space delimited list of filename wildcards (aka Globs)
 		// iterate through all of these globs to create a unique list of files named allFilenames
 		// (this is done by using a temporary map named allGlobbedNames
@@ -751,9 +2328,19 @@ func main() {
 
 		// create a slice of files in one of those wildcard entries named currentFilelist
 		for n = 0; n < len(allGlobs); n++ {
+			// remote names are not globbed, see remote.go
+			if isRemote(allGlobs[n]) {
+				allGlobbedNames[allGlobs[n]] = 0
+				continue
+			}
+			// the server and share of a UNC path are not searched, so they can not have wildcards
+			if volumeHasWildcard(allGlobs[n]) {
+				logError("wildcards can not be used in the server or share name of: %s", allGlobs[n])
+				continue
+			}
 			currentFilelist, err := filepath.Glob(allGlobs[n])
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err)
+				logError("%s", err)
 				continue
 			}
 			// add all of these file names to a 'global' map of files
@@ -768,11 +2355,11 @@ func main() {
 			allFilenames = append(allFilenames, key)
 		}
 		if len(allFilenames) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: -f did not match any file names.\n\n")
+			usageError("-f did not match any file names.")
 			os.Exit(3)
 		}
 		if len(allFilenames) == 1 {
-			fmt.Fprintf(os.Stderr, "Warning: -f only matched one file name.\n\n")
+			logWarn("-f only matched one file name.")
 		}
 	} else { // using a filename or STDIN
 		var input *bufio.Scanner
@@ -785,20 +2372,583 @@ func main() {
 			usingFile = fname
 			file, err := os.Open(fname)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err)
+				logError("%s", err)
 				os.Exit(1)
 			}
 			defer file.Close()
 			input = bufio.NewScanner(file)
 		}
-		allFilenames = GetFileList(input)
-		if len(allFilenames) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: No files were listed in '%s'\n\n", usingFile)
-			os.Exit(3)
+		if *argsStream {
+			nextName = scanNames(input, usingFile)
+		} else {
+			allFilenames = GetFileList(input)
+			if len(allFilenames) == 0 {
+				usageError("No files were listed in '%s'", usingFile)
+				os.Exit(3)
+			}
+		}
+	}
+	var ignorePatterns []string
+	if !*argsNoIgnore {
+		ignorePatterns = LoadIgnorePatterns(quiet)
+	}
+
+	// a hung network file system is skipped instead of freezing the scan; -nettimeout
+	netTimeout, err := parseAge(*argsNetTimeout)
+	if err != nil {
+		usageError("invalid -nettimeout: %s", err)
+		os.Exit(2)
+	}
+	netfs := newNetFileSystems(netTimeout)
+
+	// Ctrl-C and -timeout stop the walk and the examining of files, and the entries examined so far are output
+	var timeout time.Duration
+	if len(*argsTimeout) > 0 {
+		timeout, err = parseAge(*argsTimeout)
+		if err != nil || timeout <= 0 {
+			usageError("invalid -timeout: %s; use a time such as: 30s, 5m, 1h", *argsTimeout)
+			os.Exit(2)
+		}
+	}
+	ctx, stopRun := newRunContext(timeout)
+
+	// remote names are examined over one session for each server, and are walked with -r
+	var remoteRecords map[string]statRecord
+	if hasRemote(allFilenames) {
+		if *argsWatch || len(*argsSnapshotSave) > 0 {
+			usageError("remote names can not be used with: -watch or -snapshot-save")
+			os.Exit(2)
+		}
+		allFilenames, remoteRecords = ExpandRemoteFilenames(ctx, allFilenames, quiet, *argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0, *argsMinDepth, *argsMaxDepth, ignorePatterns)
+	}
+
+	if *argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0 {
+		// each name is examined as soon as it is found, so that a Ctrl-C or -timeout during a long walk still
+		// outputs the entries found so far; the names are kept for -watch, and the -progress total is not known
+		nextName = walkNames(ctx, allFilenames, quiet, *argsMinDepth, *argsMaxDepth, ignorePatterns, *argsSameDevice, *argsFollowLinks, netfs)
+		if *argsClean || *argsCleanAbs {
+			nextName = cleanNames(nextName, quiet, *argsCleanAbs)
+		}
+		walked := nextName
+		allFilenames = nil
+		nextName = func() (string, bool) {
+			fname, ok := walked()
+			if ok {
+				allFilenames = append(allFilenames, fname)
+			}
+			return fname, ok
+		}
+	} else if *argsClean || *argsCleanAbs {
+		allFilenames = CleanAllFilenames(allFilenames, quiet, *argsCleanAbs)
+	}
+	if nil == nextName {
+		nextName = sliceNames(allFilenames)
+	}
+
+	// owner and group names are only looked up when needed, since this can be slow
+	lookupOwner := "owner" == *argsGroupBy || len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || strings.Contains(*argsOutputFormat, ".Owner") || printfUses(printfParts, 'u')
+	lookupGroup := len(*argsOutputSQLite) > 0 || len(*argsOutputParquet) > 0 || strings.Contains(*argsOutputFormat, ".Group") || printfUses(printfParts, 'g')
+
+	loc := getLocation(*argsUTC, *argsTimeZone)
+	timeLayout := getTimeLayout(*argsMilliseconds, *argsNanoseconds, *argsDateFormat, *argsRFC3339)
+	var extraColumns []extraColumn
+	if *argsMode {
+		extraColumns = append(extraColumns, modeColumn())
+	}
+	if *argsDeviceNumber {
+		extraColumns = append(extraColumns, deviceNumberColumn())
+	}
+	if *argsRelative {
+		extraColumns = append(extraColumns, ageColumn())
+	}
+	if *argsAllocated {
+		extraColumns = append(extraColumns, allocColumn(*argsCommas, *argsMebibytes, *argsSI))
+	}
+	if *argsGzipSize {
+		extraColumns = append(extraColumns, gzipSizeColumn(*argsCommas, *argsMebibytes, *argsSI))
+	}
+	if *argsEntropy {
+		extraColumns = append(extraColumns, entropyColumn())
+	}
+	if *argsClass {
+		extraColumns = append(extraColumns, classColumn())
+	}
+	if *argsShebang {
+		extraColumns = append(extraColumns, interpreterColumn())
+	}
+	if *argsImageSize {
+		extraColumns = append(extraColumns, imageSizeColumn())
+	}
+	if *argsDuration {
+		extraColumns = append(extraColumns, durationColumn())
+	}
+	if *argsPages {
+		extraColumns = append(extraColumns, pagesColumn())
+	}
+	if *argsGit {
+		extraColumns = append(extraColumns, gitColumns(timeLayout, *argsRelativeOnly)...)
+	}
+
+	var widths columnWidths
+	if len(*argsTruncate) > 0 || len(*argsShorten) > 0 || len(*argsMinWidth) > 0 {
+		if notTable {
+			usageError("-truncate, -shorten and -minwidth can only be used with the default table output, and not with: -diff, -baseline, -cmp, -explain, -dups, -top, -oldnew, -samename, -badnames, -maxpath, -portable, or -tui")
+			os.Exit(2)
+		}
+		if (len(*argsTruncate) > 0 || len(*argsShorten) > 0) && *argsLongFileNames {
+			usageError("-truncate and -shorten can not be used with: -long")
+			os.Exit(2)
+		}
+		header, _ := tableHeader(*argsRelativeOnly, extraColumns, *argsSplitName)
+		widths, err = parseColumnWidths(*argsTruncate, *argsShorten, *argsMinWidth, header)
+		if err != nil {
+			usageError("%s", err)
+			os.Exit(2)
+		}
+	}
+
+	render := renderOptions{
+		addCommas:      *argsCommas,
+		convertToMiB:   *argsMebibytes,
+		useSI:          *argsSI,
+		timeLayout:     timeLayout,
+		relativeOnly:   *argsRelativeOnly,
+		includeTotals:  *argsTotals,
+		extendedTotals: *argsExtendedTotals,
+		onlyTypes:      onlyTypes,
+		outputCSV:      *argsOutputCSV,
+		outputTSV:      *argsOutputTSV,
+		outputHTML:     *argsOutputHTML,
+		outputJSON:     *argsOutputJSON,
+		csvDelimiter:   csvDelimiter,
+		linkBase:       linkBase,
+		htmlTitle:      *argsTitle,
+		htmlTheme:      *argsTheme,
+		longFileNames:  *argsLongFileNames,
+		longWidth:      *argsLongWidth,
+		widths:         widths,
+		extraColumns:   extraColumns,
+		highlight:      highlight,
+		splitName:      *argsSplitName,
+	}
+
+	var stream func(e FileStat)
+	flushStream := func() {}
+	if *argsOutputJSONL || *argsOutputCBOR {
+		stream = newStreamWriter(newStreamEncoder(*argsOutputCBOR), loc, onlyTypes)
+	}
+	if *argsStream {
+		// each batch is output with the same renderers used for the complete list of entries
+		stream, flushStream = newBatchWriter(streamBatchSize, loc, func(batch []FileStat, first bool) {
+			switch {
+			case *argsOutputNames || *argsOutputPrint0:
+				separator := byte('\n')
+				if *argsOutputPrint0 {
+					separator = 0
+				}
+				RenderNames(batch, onlyTypes, separator)
+			case *argsOutputXargs:
+				RenderXargs(batch, onlyTypes, *argsXargsMax, quiet)
+			case len(*argsOutputFormat) > 0:
+				RenderTemplate(batch, *argsOutputFormat, onlyTypes)
+			case len(printfParts) > 0:
+				RenderPrintf(batch, printfParts, onlyTypes)
+			default:
+				// -stream is only used with -oc and -ot, without totals
+				batchOptions := render
+				batchOptions.omitHeader = !first
+				RenderAllEntries(batch, batchOptions, nil, 0, "")
+			}
+		})
+	}
+
+	if less := entryLess(*argsSortSize, *argsSortSizeDesc, *argsSortModTime, *argsSortModTimeDesc, *argsSortName, *argsSortNameDesc, *argsSortNameCaseInsen, *argsSortNameCaseInsenDesc); *argsStream && less != nil {
+		// sorted entries are only output after the last entry has been examined
+		sorter := newExternalSorter(*argsSortChunk, less)
+		output, flushOutput := stream, flushStream
+		stream = sorter.add
+		flushStream = func() {
+			sorter.merge(output)
+			flushOutput()
+		}
+	}
+
+	var watchEntries []FileStat
+	if *argsWatch && stream != nil {
+		// -ojl does not otherwise keep the entries, which -watch compares events to
+		output := stream
+		stream = func(e FileStat) {
+			watchEntries = append(watchEntries, e)
+			output(e)
+		}
+	}
+
+	if len(*argsCache) > 0 {
+		ttl, err := parseAge(*argsCacheTTL)
+		if err != nil {
+			usageError("invalid -cachettl: %s", err)
+			os.Exit(2)
+		}
+		if cache, err = LoadStatCache(*argsCache, ttl); err != nil {
+			logError("unable to use -cache: %s", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(*argsSnapshotSave) > 0 {
+		// a snapshot holds everything, so that any output can be created when it is loaded
+		lookupOwner, lookupGroup = true, true
+		if nil == cache {
+			cache = newMemoryStatCache()
+		}
+	}
+
+	// exitStatus - set when a -baseline, -fail-if-none, or -fail-if-any condition is met
+	// deferred before -stats, so that os.Exit() is called after all other output
+	exitStatus := 0
+	// partial - why the scan was stopped early by Ctrl-C or -timeout, which overrides any other exit status
+	var partial string
+	defer func() {
+		if len(partial) > 0 {
+			logWarn("partial results: %s, only the files examined so far were output", partial)
+			exitStatus = exitPartial
+		}
+		if exitStatus != 0 {
+			os.Exit(exitStatus)
+		}
+	}()
+	var shownCount int
+	countShown := func(e FileStat) {
+		if typeIncluded(onlyTypes, e.FileType) {
+			shownCount++
+		}
+	}
+	if (*argsFailIfNone || *argsFailIfAny) && stream != nil {
+		output := stream
+		stream = func(e FileStat) {
+			countShown(e)
+			output(e)
+		}
+	}
+	setFailIfStatus := func() {
+		if (*argsFailIfNone && 0 == shownCount) || (*argsFailIfAny && shownCount > 0) {
+			exitStatus = exitConditionMet
+		}
+	}
+
+	var counters *scanCounters
+	var progress *scanProgress
+	// the totals include the number of files that could not be examined
+	if *argsProgress || *argsStats || *argsErrors || *argsTotals || *argsExtendedTotals || *argsSummaryOnly || len(*argsSummaryFile) > 0 {
+		counters = newScanCounters(*argsStats, *argsErrors)
+	}
+	if *argsStats {
+		// deferred so that the time taken to output all entries is included
+		defer RenderRunStats(counters)
+	}
+	if *argsProgress {
+		progress = startProgress(counters, int64(len(allFilenames)))
+	}
+
+	var explanations []explanation
+	var explain func(fname string, reason string)
+	if *argsExplain {
+		explain = func(fname string, reason string) {
+			explanations = append(explanations, explanation{FullName: fname, Included: 0 == len(reason), Reason: reason})
+		}
+	} else if verbosity >= verbosityVerbose {
+		explain = func(fname string, reason string) {
+			if len(reason) > 0 {
+				logVerbose("skipped %s: %s", fname, reason)
+			} else {
+				logDebug("included %s", fname)
+			}
+		}
+	}
+
+	options := scanOptions{
+		quiet:          quiet,
+		excludeDot:     *argsExcludeDot,
+		excludeRE:      *argsExcludeRE,
+		includeRE:      *argsIncludeRE,
+		fuzzy:          *argsFuzzy,
+		ignorePatterns: ignorePatterns,
+		extensions:     *argsExtensions,
+		dateNewer:      *argsDateNewer,
+		dateOlder:      *argsDateOlder,
+		accessNewer:    *argsAccessNewer,
+		accessOlder:    *argsAccessOlder,
+		sizeSmaller:    *argsSizeSmaller,
+		sizeLarger:     *argsSizeLarger,
+		lookupOwner:    lookupOwner,
+		lookupGroup:    lookupGroup,
+		userFilter:     *argsUser,
+		groupFilter:    *argsGroupFilter,
+		permission:     *argsPerm,
+		where:          *argsWhere,
+		dirUsage:       *argsDirUsage,
+		gzSize:         *argsGzipSize,
+		findEntropy:    *argsEntropy,
+		classify:       *argsClass,
+		onlyClass:      onlyClass,
+		findShebang:    *argsShebang,
+		findImageSize:  *argsImageSize,
+		findDuration:   *argsDuration,
+		findPages:      *argsPages,
+		gitLog:         *argsGit,
+		onlySparse:     *argsOnlySparse,
+		sameDevice:     *argsSameDevice,
+		archives:       *argsArchives,
+		explain:        explain,
+		rename:         rename,
+		splitName:      *argsSplitName,
+	}
+	// scan - examine files with all of the filter options; also used for the old side of -diff and both sides of -cmp
+	scan := func(nextName func() (string, bool), cache *statCache, counters *scanCounters, stream func(e FileStat)) []FileStat {
+		return GetFileInfo(ctx, nextName, options, remoteRecords, netfs, cache, counters, stream)
+	}
+	// watch - output events for the listed entries until interrupted; files are always examined again instead of using the cache
+	watch := func(allEntries []FileStat) {
+		stopRun()
+		WatchEntries(allFilenames, allEntries, *argsRecursive || *argsMinDepth > 0 || *argsMaxDepth >= 0, func(nextName func() (string, bool)) []FileStat {
+			return scan(nextName, nil, nil, nil)
+		}, quiet, *argsOutputJSONL, loc, *argsCommas, *argsMebibytes, *argsSI, timeLayout, onlyTypes, *argsNotify)
+	}
+	var allEntries []FileStat
+	var dirChanges []FileChange
+	scanStart := time.Now()
+	if *argsCmp {
+		dirChanges = CompareDirs(ctx, args[0], args[1], func(nextName func() (string, bool)) []FileStat {
+			entries := scan(nextName, cache, counters, nil)
+			if loc != nil {
+				for i := range entries {
+					entries[i].ModTime = entries[i].ModTime.In(loc)
+				}
+			}
+			return entries
+		}, quiet, *argsMaxDepth, ignorePatterns, *argsSameDevice, *argsFollowLinks, netfs, *argsCmpHash)
+	} else {
+		allEntries = scan(nextName, cache, counters, stream)
+	}
+	progress.stop()
+	partial = partialReason(ctx)
+	logVerbose("examined file names in %s", time.Since(scanStart).Round(time.Microsecond))
+	if verbosity >= verbosityVerbose {
+		outputStart := time.Now()
+		defer func() {
+			logVerbose("output in %s", time.Since(outputStart).Round(time.Microsecond))
+		}()
+	}
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			logError("unable to save -cache: %s", err)
+		}
+	}
+	if stream != nil {
+		flushStream()
+		setFailIfStatus()
+		if *argsWatch {
+			watch(watchEntries)
+		}
+		return
+	}
+	// a partial snapshot would make -diff report every file which was not examined as removed
+	if len(*argsSnapshotSave) > 0 && len(partial) > 0 {
+		logError("snapshot not saved: %s", partial)
+	} else if len(*argsSnapshotSave) > 0 {
+		if err := SaveSnapshot(*argsSnapshotSave, allEntries, cache); err != nil {
+			logError("unable to save snapshot: %s", err)
+			os.Exit(1)
+		}
+	}
+	if loc != nil {
+		for i := range allEntries {
+			allEntries[i].ModTime = allEntries[i].ModTime.In(loc)
+			commitTimeIn(&allEntries[i], loc)
+		}
+	}
+
+	if *argsExplain {
+		excludeByType(explanations, allEntries, onlyTypes)
+		RenderExplanations(explanations, *argsOutputCSV, csvDelimiter, *argsOutputTSV, *argsOutputJSON)
+		return
+	}
+
+	if *argsDups {
+		groups := FindDuplicates(ctx, allEntries, quiet)
+		partial = partialReason(ctx)
+		shownCount = RenderDuplicates(groups, render)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsTop > 0 {
+		shownCount = RenderLargest(FindLargest(allEntries, *argsTop), render)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsOldNew > 0 {
+		shownCount = RenderOldNew(FindOldNew(allEntries, *argsOldNew, onlyTypes), render)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsSameName {
+		shownCount = RenderSameNames(FindSameNames(allEntries), render)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsBadNames {
+		shownCount = RenderBadNames(FindBadNames(allEntries, onlyTypes), "bad names", *argsOutputCSV, csvDelimiter, *argsOutputTSV, *argsOutputJSON)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsMaxPath > 0 {
+		shownCount = RenderLongPaths(FindLongPaths(allEntries, *argsMaxPath, onlyTypes), *argsOutputCSV, csvDelimiter, *argsOutputTSV, *argsOutputJSON)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsPortable {
+		shownCount = RenderBadNames(FindNonPortable(allEntries, onlyTypes), "not portable", *argsOutputCSV, csvDelimiter, *argsOutputTSV, *argsOutputJSON)
+		setFailIfStatus()
+		return
+	}
+
+	if *argsTUI {
+		SortAllEntries(allEntries, *argsSortSize, *argsSortSizeDesc, *argsSortModTime, *argsSortModTimeDesc, *argsSortName, *argsSortNameDesc, *argsSortNameCaseInsen, *argsSortNameCaseInsenDesc)
+		marked, err := RunTUI(allEntries, *argsCommas, *argsMebibytes, *argsSI, timeLayout, onlyTypes)
+		if err != nil {
+			logError("unable to start -tui: %s", err)
+			os.Exit(1)
+		}
+		for _, name := range marked {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	if *argsCmp {
+		if len(*argsNotify) > 0 {
+			NotifyChanges(*argsNotify, dirChanges, onlyTypes, quiet)
+		}
+		RenderChanges(dirChanges, cmpKinds, "Left", "Right", render)
+		return
+	}
+
+	if len(*argsDiff) > 0 {
+		oldFilenames, oldCache, err := LoadSnapshot(*argsDiff)
+		if err != nil {
+			logError("unable to load snapshot: %s", err)
+			os.Exit(1)
+		}
+		oldEntries := scan(sliceNames(oldFilenames), oldCache, nil, nil)
+		partial = partialReason(ctx)
+		if loc != nil {
+			for i := range oldEntries {
+				oldEntries[i].ModTime = oldEntries[i].ModTime.In(loc)
+			}
+		}
+		changes := ComputeChanges(oldEntries, allEntries)
+		if len(*argsNotify) > 0 {
+			NotifyChanges(*argsNotify, changes, onlyTypes, quiet)
+		}
+		changed := RenderChanges(changes, changeKinds, "Old", "New", render)
+		if len(*argsBaseline) > 0 && changed > 0 {
+			exitStatus = exitConditionMet
+		}
+		return
+	}
+
+	for _, e := range allEntries {
+		countShown(e)
+	}
+	setFailIfStatus()
+
+	if len(*argsNotify) > 0 {
+		NotifyEntries(*argsNotify, allEntries, onlyTypes, quiet)
+	}
+
+	if *argsSummaryOnly || len(*argsSummaryFile) > 0 {
+		if err := WriteScanSummary(ComputeScanSummary(allEntries, onlyTypes, counters.failedCount(), partial), *argsSummaryFile); err != nil {
+			logError("unable to write summary: %s", err)
+			os.Exit(1)
+		}
+		if *argsSummaryOnly {
+			return
 		}
 	}
 
-	allEntries := GetFileInfo(allFilenames, *argsQuiet, *argsExcludeDot, *argsExcludeRE, *argsIncludeRE, *argsDateNewer, *argsDateOlder, *argsSizeSmaller, *argsSizeLarger)
+	if *argsOutputTreemap {
+		RenderTreemap(allEntries)
+		return
+	}
+	if *argsOutputNcdu {
+		RenderNcdu(allEntries)
+		return
+	}
+	if len(*argsGroupBy) > 0 {
+		groups := GroupAllEntries(allEntries, *argsGroupBy, *argsGroupDepth)
+		SortAllGroups(groups, *argsGroupBy, *argsSortSize, *argsSortSizeDesc, *argsSortName, *argsSortNameDesc)
+		RenderGroups(groups, *argsGroupBy, *argsCommas, *argsMebibytes, *argsSI, *argsOutputCSV, csvDelimiter, *argsOutputTSV, *argsOutputHTML, *argsTitle, *argsTheme, *argsOutputJSON)
+		return
+	}
+	if *argsHistogram {
+		RenderSizeHistogram(allEntries, *argsCommas, *argsMebibytes, *argsSI)
+		return
+	}
+	if len(*argsTimeline) > 0 {
+		RenderTimeline(allEntries, *argsTimeline, *argsCommas, *argsMebibytes, *argsSI)
+		return
+	}
 	SortAllEntries(allEntries, *argsSortSize, *argsSortSizeDesc, *argsSortModTime, *argsSortModTimeDesc, *argsSortName, *argsSortNameDesc, *argsSortNameCaseInsen, *argsSortNameCaseInsenDesc)
-	RenderAllEntries(allEntries, *argsCommas, *argsMebibytes, *argsMilliseconds, *argsTotals, *argsOnlyFiles, *argsOnlyDirs, *argsOnlyLinks, *argsOutputCSV, *argsOutputHTML, *argsOutputJSON, *argsLongFileNames, *argsLongWidth)
+	if len(*argsFuzzy) > 0 && nil == entryLess(*argsSortSize, *argsSortSizeDesc, *argsSortModTime, *argsSortModTimeDesc, *argsSortName, *argsSortNameDesc, *argsSortNameCaseInsen, *argsSortNameCaseInsenDesc) {
+		sortFuzzy(allEntries, newFuzzyQuery(*argsFuzzy))
+	}
+	if *argsOutputNames || *argsOutputPrint0 {
+		separator := byte('\n')
+		if *argsOutputPrint0 {
+			separator = 0
+		}
+		RenderNames(allEntries, onlyTypes, separator)
+		return
+	}
+	if *argsOutputXargs {
+		RenderXargs(allEntries, onlyTypes, *argsXargsMax, quiet)
+		return
+	}
+	if len(*argsOutputFormat) > 0 {
+		RenderTemplate(allEntries, *argsOutputFormat, onlyTypes)
+		return
+	}
+	if len(*argsOutputSQLite) > 0 {
+		RenderSQLite(*argsOutputSQLite, allEntries, quiet, onlyTypes)
+		return
+	}
+	if len(*argsOutputParquet) > 0 {
+		RenderParquet(*argsOutputParquet, allEntries, quiet, onlyTypes)
+		return
+	}
+	if *argsOutputProtobuf {
+		RenderProtobuf(allEntries, *argsTotals, *argsExtendedTotals, onlyTypes, counters.failedCount())
+		return
+	}
+	if len(printfParts) > 0 {
+		RenderPrintf(allEntries, printfParts, onlyTypes)
+		return
+	}
+	var allErrors []scanError
+	if *argsErrors {
+		allErrors = counters.allErrors()
+	}
+	RenderAllEntries(allEntries, render, allErrors, counters.failedCount(), partial)
+	if *argsMountTotals {
+		RenderMountStats(GetMountStats(allEntries, quiet), *argsCommas, *argsMebibytes, *argsSI)
+	}
+	if *argsWatch {
+		watch(allEntries)
+	}
 }
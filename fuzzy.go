@@ -0,0 +1,201 @@
+/*
+
+fuzzy.go
+
+Match file names against a query in the style of fzf, used by the -fuzzy cmd line option and the -tui filter
+
+Example: fstat -fuzzy 'rpt q3 xlsx' -r dirs.txt
+
+Each space separated term of the query must match: the characters of a term must all appear in the file name,
+in the same order, but not necessarily next to each other. Matching ignores case unless the query contains an
+upper case letter. Matches that are consecutive, or that start at the beginning of a path component or word,
+score higher, as do matches within the base name. Without a sort option, the best matches are output first.
+
+*/
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// scores used by fuzzyTermScore
+const (
+	fuzzyMatch       = 16
+	fuzzyConsecutive = 8
+	fuzzyBoundary    = 8
+	fuzzyCamelCase   = 6
+	fuzzyBaseName    = 2
+	fuzzyGap         = 1
+)
+
+// fuzzyNoMatch - the score of a position that can not be matched
+const fuzzyNoMatch = -1 << 30
+
+// fuzzyQuery - a parsed query; nil matches every name
+type fuzzyQuery struct {
+	terms         [][]rune
+	caseSensitive bool
+}
+
+// newFuzzyQuery - parse query into terms; returns nil when query is blank
+func newFuzzyQuery(query string) *fuzzyQuery {
+	fields := strings.Fields(query)
+	if 0 == len(fields) {
+		return nil
+	}
+	q := &fuzzyQuery{caseSensitive: strings.ToLower(query) != query}
+	for _, f := range fields {
+		if !q.caseSensitive {
+			f = strings.ToLower(f)
+		}
+		q.terms = append(q.terms, []rune(f))
+	}
+	return q
+}
+
+/*
+score reports how well fname matches the query
+
+Args:
+    fname: the file name
+
+Returns:
+    the total score of all terms, where higher is better
+
+    true when every term matches
+*/
+func (q *fuzzyQuery) score(fname string) (int, bool) {
+	if nil == q {
+		return 0, true
+	}
+	name := []rune(fname)
+	folded := name
+	if !q.caseSensitive {
+		folded = []rune(strings.ToLower(fname))
+	}
+	if len(folded) != len(name) {
+		// such as a lower case letter that is longer than its upper case letter
+		name = folded
+	}
+
+	var total int
+	for _, term := range q.terms {
+		s, ok := fuzzyTermScore(term, folded, name)
+		if !ok {
+			return 0, false
+		}
+		total += s
+	}
+	return total, true
+}
+
+/*
+fuzzyBonus returns the extra score for a match at position i
+
+Args:
+    name: the original file name, used to find word boundaries and camel case
+
+    i: the position of the matched character
+
+    baseStart: the position where the base name starts
+*/
+func fuzzyBonus(name []rune, i int, baseStart int) int {
+	var bonus int
+	if i >= baseStart {
+		bonus += fuzzyBaseName
+	}
+	if 0 == i {
+		return bonus + fuzzyBoundary
+	}
+	prev, cur := name[i-1], name[i]
+	switch {
+	case '/' == prev || '\\' == prev || '_' == prev || '-' == prev || '.' == prev || ' ' == prev:
+		bonus += fuzzyBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur), !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		bonus += fuzzyCamelCase
+	}
+	return bonus
+}
+
+/*
+fuzzyTermScore finds the best scoring way to match term within name, allowing gaps between characters
+
+Args:
+    term: the characters to match, already folded to lower case when matching ignores case
+
+    folded: the file name, folded the same way as term
+
+    name: the original file name
+
+Returns:
+    the best score, and true when every character of term was found in order
+*/
+func fuzzyTermScore(term []rune, folded []rune, name []rune) (int, bool) {
+	n := len(folded)
+	if len(term) > n {
+		return 0, false
+	}
+	baseStart := 0
+	for i, r := range name {
+		if '/' == r || '\\' == r {
+			baseStart = i + 1
+		}
+	}
+
+	// prev[j] is the best score with the previous term character matched at position j
+	prev := make([]int, n)
+	cur := make([]int, n)
+	for j := range prev {
+		prev[j] = fuzzyNoMatch
+		if folded[j] == term[0] {
+			prev[j] = fuzzyMatch + fuzzyBonus(name, j, baseStart)
+		}
+	}
+
+	for t := 1; t < len(term); t++ {
+		// best score for a match after a gap, less one point for each skipped character
+		gapped := fuzzyNoMatch
+		for j := 0; j < n; j++ {
+			cur[j] = fuzzyNoMatch
+			if gapped > fuzzyNoMatch {
+				gapped -= fuzzyGap
+			}
+			if j >= 2 && prev[j-2] > fuzzyNoMatch && prev[j-2]-fuzzyGap > gapped {
+				gapped = prev[j-2] - fuzzyGap
+			}
+			if folded[j] != term[t] {
+				continue
+			}
+			best := gapped
+			if j >= 1 && prev[j-1] > fuzzyNoMatch && prev[j-1]+fuzzyConsecutive > best {
+				best = prev[j-1] + fuzzyConsecutive
+			}
+			if best > fuzzyNoMatch {
+				cur[j] = best + fuzzyMatch + fuzzyBonus(name, j, baseStart)
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	best := fuzzyNoMatch
+	for _, s := range prev {
+		if s > best {
+			best = s
+		}
+	}
+	return best, best > fuzzyNoMatch
+}
+
+// sortFuzzy - sort the entries by how well they match the query, best first; equal scores keep their order
+func sortFuzzy(allEntries []FileStat, q *fuzzyQuery) {
+	scores := make(map[string]int, len(allEntries))
+	for _, e := range allEntries {
+		scores[e.FullName], _ = q.score(e.FullName)
+	}
+	sort.SliceStable(allEntries, func(i, j int) bool {
+		return scores[allEntries[i].FullName] > scores[allEntries[j].FullName]
+	})
+}
@@ -0,0 +1,222 @@
+/*
+
+gitlog.go
+
+Find the last commit of each file and directory in a git repository, used by the -git cmd line option
+
+Example: fstat -git -r src/
+Example: fstat -git -where 'committed > 2y' -type f -r .
+
+git log is run once for each repository, and the first commit which lists a file is its last commit. The last
+commit of a directory is the most recent one of any file within it. Untracked and ignored files, and entries
+outside of a repository, have no commit. The git command must be in the PATH. Committing does not change a
+file's modification time, so these are never kept with -cache.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitShortHash - the number of characters of a commit hash which are shown, the same as git log --oneline
+const gitShortHash = 7
+
+// gitCommit - the last commit which changed a file
+type gitCommit struct {
+	hash   string
+	author string
+	date   time.Time
+}
+
+// gitHistory - the last commit of every path, loaded once for each repository
+type gitHistory struct {
+	// the top level directory of the repository for each directory, or "" when it is not in one
+	roots map[string]string
+	// the last commit of each slash separated path relative to its top level directory, for each repository
+	commits map[string]map[string]gitCommit
+}
+
+// newGitHistory - create an empty gitHistory
+func newGitHistory() *gitHistory {
+	return &gitHistory{roots: make(map[string]string), commits: make(map[string]map[string]gitCommit)}
+}
+
+/*
+repoRoot returns the top level directory of the repository which contains a directory
+
+Args:
+    dir: an absolute directory name with its symbolic links resolved
+
+Returns:
+    the top level directory; or "" when dir is not in a repository
+*/
+func (g *gitHistory) repoRoot(dir string) string {
+	if root, ok := g.roots[dir]; ok {
+		return root
+	}
+	root := ""
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if nil == err {
+		root = filepath.FromSlash(strings.TrimSpace(string(out)))
+	}
+	g.roots[dir] = root
+	return root
+}
+
+// splitNUL - a bufio.SplitFunc for the NUL terminated output of git log -z
+func splitNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+/*
+readLog runs git log for a repository and finds the last commit of each path
+
+Args:
+    ctx: once done, git log is stopped
+
+    root: the top level directory of the repository
+
+Returns:
+    the last commit of each slash separated path relative to root, including directories; or an error when git log fails
+*/
+func readLog(ctx context.Context, root string) (map[string]gitCommit, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", root, "log", "-z", "--name-only", "--no-renames", "--format=%H%x1f%an%x1f%ct")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// each commit is a header of hash, author and time, followed by the names of the files it changed
+	commits := make(map[string]gitCommit)
+	var current gitCommit
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(splitNUL)
+	for scanner.Scan() {
+		token := strings.TrimPrefix(scanner.Text(), "\n")
+		if header := strings.Split(token, "\x1f"); 3 == len(header) && isHexHash(header[0]) {
+			seconds, err := strconv.ParseInt(header[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			current = gitCommit{hash: header[0], author: header[1], date: time.Unix(seconds, 0)}
+			continue
+		}
+		if 0 == len(token) || 0 == len(current.hash) {
+			continue
+		}
+
+		// an earlier commit is more recent, so stop at the first directory which already has one
+		for name := token; ; name = path.Dir(name) {
+			if _, ok := commits[name]; ok {
+				break
+			}
+			commits[name] = current
+			if "." == name {
+				break
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		cmd.Wait()
+		return nil, err
+	}
+	if err = cmd.Wait(); err != nil && stderr.Len() > 0 {
+		err = errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return commits, err
+}
+
+// isHexHash - true when s is a full SHA-1 or SHA-256 commit hash
+func isHexHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+lastCommit returns the last commit which changed a file, or any file within a directory
+
+Args:
+    ctx: passed to readLog()
+
+    fname: the file name
+
+    isDir: true when fname is a directory
+
+Returns:
+    the last commit, or nil when there is none; or an error when git log fails
+*/
+func (g *gitHistory) lastCommit(ctx context.Context, fname string, isDir bool) (*gitCommit, error) {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	// only resolve the symbolic links of the directory, as a symbolic link can itself be committed
+	dir, base := abs, "."
+	if !isDir {
+		dir, base = filepath.Split(abs)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, err
+	}
+	root := g.repoRoot(dir)
+	if 0 == len(root) {
+		return nil, nil
+	}
+
+	// only report a failed git log once for each repository, such as one without any commits
+	commits, ok := g.commits[root]
+	if !ok {
+		commits, err = readLog(ctx, root)
+		g.commits[root] = commits
+		if err != nil {
+			return nil, err
+		}
+	}
+	rel, err := filepath.Rel(root, filepath.Join(dir, base))
+	if err != nil {
+		return nil, err
+	}
+	if commit, ok := commits[filepath.ToSlash(rel)]; ok {
+		return &commit, nil
+	}
+	return nil, nil
+}
+
+// commitTimeIn - convert the commit time of e, when it has one, to the time zone loc (-utc and -tz cmd line options)
+func commitTimeIn(e *FileStat, loc *time.Location) {
+	if e.CommitTime != nil {
+		t := e.CommitTime.In(loc)
+		e.CommitTime = &t
+	}
+}
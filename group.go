@@ -0,0 +1,275 @@
+/*
+
+group.go
+
+Aggregate the collected FileStat entries into subtotals, used by the -group cmd line option
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// GroupStat - subtotal for all regular files sharing the same group key
+type GroupStat struct {
+	Name    string  `json:"name"`
+	Count   int64   `json:"count"`
+	Size    int64   `json:"size"`
+	Average float64 `json:"average"`
+	// only set with -duration, the total of the audio and video files in the group, in seconds
+	Duration float64 `json:"duration,omitempty"`
+	// only set with -pages, the total of the PDF files in the group
+	Pages int64 `json:"pages,omitempty"`
+}
+
+// validGroupModes - allowed values for the -group cmd line option
+var validGroupModes = []string{"dir", "ext", "owner", "month", "year"}
+
+// noExtension - group key used for files without an extension
+const noExtension = "(none)"
+
+/*
+dirGroupKey returns the parent directory of fname
+
+Args:
+    fname: the file name
+
+    depth: when greater than zero, only keep this many leading path components of the parent directory
+
+Returns:
+    the (possibly truncated) parent directory
+*/
+func dirGroupKey(fname string, depth int) string {
+	dir := filepath.Dir(fname)
+	if depth <= 0 {
+		return dir
+	}
+
+	volume := filepath.VolumeName(dir)
+	rest := dir[len(volume):]
+	prefix := volume
+	if strings.HasPrefix(rest, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+		rest = rest[1:]
+	}
+
+	parts := strings.Split(rest, string(os.PathSeparator))
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return prefix + strings.Join(parts, string(os.PathSeparator))
+}
+
+// extGroupKey returns the lower-cased extension of fname, such as ".log"
+// dot files such as ".bashrc" are considered to not have an extension
+func extGroupKey(fname string) string {
+	base := filepath.Base(fname)
+	ext := filepath.Ext(base)
+	if len(ext) == 0 || ext == base {
+		return noExtension
+	}
+	return strings.ToLower(ext)
+}
+
+/*
+GroupAllEntries aggregates the size and count of regular files by the given group mode
+
+Args:
+    allEntries: a slice of all files
+
+    groupBy: one of validGroupModes (-group cmd line option)
+
+    groupDepth: used with 'dir' to limit the number of leading path components (-groupdepth cmd line option)
+
+Returns:
+    a slice of GroupStat, one for each unique group key
+*/
+func GroupAllEntries(allEntries []FileStat, groupBy string, groupDepth int) []GroupStat {
+	allGroups := make(map[string]*GroupStat)
+
+	for _, e := range allEntries {
+		if "F" != e.FileType {
+			continue
+		}
+
+		var key string
+		switch groupBy {
+		case "dir":
+			key = dirGroupKey(e.FullName, groupDepth)
+		case "ext":
+			key = extGroupKey(e.FullName)
+		case "owner":
+			key = e.Owner
+		case "month":
+			key = e.ModTime.Format("2006-01")
+		case "year":
+			key = e.ModTime.Format("2006")
+		}
+
+		g, ok := allGroups[key]
+		if !ok {
+			g = &GroupStat{Name: key}
+			allGroups[key] = g
+		}
+		g.Count++
+		g.Size += e.Size
+		g.Duration += e.Duration
+		g.Pages += e.Pages
+	}
+
+	var groups []GroupStat
+	for _, g := range allGroups {
+		g.Average = float64(g.Size) / float64(g.Count)
+		groups = append(groups, *g)
+	}
+	return groups
+}
+
+/*
+sortGroups sorts the GroupStat slice by group name or by total size
+When multiple groups have the same size, they are alphabetized by name
+*/
+func sortGroups(groups []GroupStat, bySize bool, ascending bool) {
+	sort.Slice(groups, func(i, j int) bool {
+		if bySize && groups[i].Size != groups[j].Size {
+			if ascending {
+				return groups[i].Size < groups[j].Size
+			}
+			return groups[i].Size > groups[j].Size
+		}
+		if ascending || bySize {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].Name > groups[j].Name
+	})
+}
+
+/*
+SortAllGroups is used to determine the order of the subtotals
+-ss, -sS, -sn and -sN are honored; otherwise 'ext' groups are ordered by
+largest total size first and all other groups are ordered by name
+*/
+func SortAllGroups(groups []GroupStat, groupBy string, argsSortSize bool, argsSortSizeDesc bool, argsSortName bool, argsSortNameDesc bool) {
+	switch {
+	case argsSortSize:
+		sortGroups(groups, true, true)
+	case argsSortSizeDesc:
+		sortGroups(groups, true, false)
+	case argsSortName:
+		sortGroups(groups, false, true)
+	case argsSortNameDesc:
+		sortGroups(groups, false, false)
+	case "ext" == groupBy:
+		sortGroups(groups, true, false)
+	default:
+		sortGroups(groups, false, true)
+	}
+}
+
+/*
+RenderGroups outputs a subtotal table for each group
+
+Args:
+    groups: a slice of GroupStat created by GroupAllEntries
+
+    groupBy: used for the name of the first column
+
+    addCommas: when set, add a comma as a thousands separator (-c cmd line option)
+
+    convertToMiB: when set, output sizes in Mebibytes (-m cmd line option)
+
+    useSI: when set, output sizes in decimal KB, MB, GB units (-dec cmd line option)
+
+    outputCSV, outputTSV, outputHTML, outputJSON: alternate output formats (-oc, -ot, -oh, -oj cmd line options)
+
+    csvDelimiter: the field delimiter used with outputCSV (-csvdelim cmd line option)
+
+    htmlTitle, htmlTheme: the heading and color theme of the outputHTML page (-title and -theme cmd line options)
+*/
+func RenderGroups(groups []GroupStat, groupBy string, addCommas bool, convertToMiB bool, useSI bool, outputCSV bool, csvDelimiter rune, outputTSV bool, outputHTML bool, htmlTitle string, htmlTheme string, outputJSON bool) {
+	if outputJSON {
+		j, _ := json.MarshalIndent(groups, "", "    ")
+		fmt.Println(string(j))
+		return
+	}
+
+	// the total duration and pages are only shown when there are audio, video or PDF files; -duration and -pages
+	withDuration, withPages := false, false
+	for _, g := range groups {
+		withDuration = withDuration || g.Duration > 0
+		withPages = withPages || g.Pages > 0
+	}
+
+	var allRows [][]string
+	for _, g := range groups {
+		row := []string{g.Name, formatSize(g.Count, addCommas, false, false), formatSize(g.Size, addCommas, convertToMiB, useSI), formatSize(int64(g.Average), addCommas, convertToMiB, useSI)}
+		if withDuration {
+			row = append(row, formatDuration(g.Duration))
+		}
+		if withPages {
+			row = append(row, formatSize(g.Pages, addCommas, false, false))
+		}
+		allRows = append(allRows, row)
+	}
+
+	var firstColumn string
+	switch groupBy {
+	case "dir":
+		firstColumn = "Directory"
+	case "ext":
+		firstColumn = "Extension"
+	case "owner":
+		firstColumn = "Owner"
+	case "month":
+		firstColumn = "Month"
+	case "year":
+		firstColumn = "Year"
+	}
+	header := []string{firstColumn, "Files", "Size", "Average"}
+	alignments := []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT}
+	if withDuration {
+		header = append(header, "Duration")
+		alignments = append(alignments, tablewriter.ALIGN_RIGHT)
+	}
+	if withPages {
+		header = append(header, "Pages")
+		alignments = append(alignments, tablewriter.ALIGN_RIGHT)
+	}
+
+	if outputCSV {
+		renderCSV(header, allRows, csvDelimiter, nil)
+		return
+	}
+
+	if outputTSV {
+		renderTSV(header, allRows, nil)
+		return
+	}
+
+	if outputHTML {
+		var totalCount, totalSize int64
+		for _, g := range groups {
+			totalCount += g.Count
+			totalSize += g.Size
+		}
+		cards := []htmlCard{
+			{Label: "Total Files", Value: RenderInteger("#,###.", totalCount)},
+			{Label: "Total Size", Value: formatSize(totalSize, addCommas, convertToMiB, useSI), Detail: RenderInteger("#,###.", totalSize) + " bytes"},
+		}
+		renderHTML(header, allRows, nil, cards, nil, htmlTitle, htmlTheme)
+		return
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, alignments)
+	}
+}
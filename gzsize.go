@@ -0,0 +1,74 @@
+/*
+
+gzsize.go
+
+Find the uncompressed size of gzip files, used by the -gzsize cmd line option
+
+Example: fstat -gzsize -ext .gz -r /var/log
+
+The last four bytes of a gzip file, its ISIZE trailer, hold the uncompressed size modulo 4 GiB, so that the
+size of most files is found without reading them. When the trailer is smaller than the compressed data, which
+happens once the uncompressed size reaches 4 GiB, the file is decompressed to count its bytes instead. The
+trailer of a file made of several concatenated gzip members, such as one written by logrotate with
+delaycompress and then appended to, only holds the size of the last member.
+
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipTrailerSize - the size of the CRC-32 and ISIZE fields at the end of a gzip file
+const gzipTrailerSize = 8
+
+// isGzip - true when fname ends with .gz, ignoring case
+func isGzip(fname string) bool {
+	return strings.HasSuffix(strings.ToLower(fname), ".gz")
+}
+
+/*
+gzipSize returns the uncompressed size of a gzip file
+
+Args:
+    fname: the file name
+
+    size: the size of the file
+
+Returns:
+    the uncompressed size; or an error when fname is not a gzip file or can not be read
+*/
+func gzipSize(fname string, size int64) (int64, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// the header is checked first, so that a file which is not gzip is reported instead of sized
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	if size < gzipTrailerSize {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	trailer := make([]byte, 4)
+	if _, err := file.ReadAt(trailer, size-4); err != nil {
+		return 0, err
+	}
+	isize := int64(binary.LittleEndian.Uint32(trailer))
+	if isize >= size {
+		return isize, nil
+	}
+
+	// the trailer has wrapped around, or the data is incompressible; either way, count the bytes
+	return io.Copy(io.Discard, gz)
+}
@@ -0,0 +1,42 @@
+/*
+
+highlight.go
+
+Mark recently modified entries in the default table, used by the -highlight cmd line option
+
+Example: fstat -highlight 1h -r dirs.txt
+
+When STDOUT is a terminal, each entry modified within the given time is shown in bold yellow, unless the
+NO_COLOR environment variable is set. Otherwise, a * is added after its modified time, so that the mark is
+kept when the table is piped or saved to a file.
+
+*/
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
+)
+
+// highlightMarker - added after the modified time of recent entries when colors are not used
+const highlightMarker = " *"
+
+// highlightColors - the style of each cell of a recent entry when colors are used
+var highlightColors = tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiYellowColor}
+
+// useColor - true when STDOUT is a terminal and colors have not been turned off with NO_COLOR
+func useColor() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// isRecent - true when e was modified within window before now; a window of 0 turns this off
+func isRecent(e FileStat, window time.Duration, now time.Time) bool {
+	return window > 0 && now.Sub(e.ModTime) <= window
+}
@@ -0,0 +1,280 @@
+/*
+
+html.go
+
+Standalone HTML report, used by the -oh cmd line option
+
+The page has no external resources; columns can be sorted by clicking their header and rows can be filtered
+
+*/
+
+package main
+
+import (
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// htmlCell - a table cell, which is a hyperlink when Href is set
+type htmlCell struct {
+	Text string
+	Href template.URL
+}
+
+// htmlCard - a summary shown above the table, such as the total size
+type htmlCard struct {
+	Label  string
+	Value  string
+	Detail string
+}
+
+// htmlPage - the data available to htmlTemplate
+type htmlPage struct {
+	Title   string
+	Theme   string
+	Cards   []htmlCard
+	Summary []summaryField
+	Header  []string
+	Rows    [][]htmlCell
+}
+
+// htmlThemes - the allowed values of the -theme cmd line option; auto follows the browser's color scheme
+var htmlThemes = []string{"light", "dark", "auto"}
+
+/*
+validHTMLTheme returns true when theme is one of htmlThemes
+
+Args:
+    theme: the value of the -theme cmd line option
+*/
+func validHTMLTheme(theme string) bool {
+	for _, t := range htmlThemes {
+		if t == theme {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+entryCards returns the summary cards for a list of entries: total files, total size and newest file
+
+Args:
+    allEntries: the entries output in the table
+
+    timeLayout: used to format the modified time of the newest file
+
+    addCommas, convertToMiB, useSI: how the total size is shown, the same as in the table (-c, -m, -dec cmd line options)
+
+Returns:
+    the cards; the newest file card is omitted when there are no files
+*/
+func entryCards(allEntries []FileStat, timeLayout string, addCommas bool, convertToMiB bool, useSI bool) []htmlCard {
+	var totalSize int64
+	var fileCount int64
+	var newest *FileStat
+	for i, e := range allEntries {
+		if "F" != e.FileType {
+			continue
+		}
+		fileCount++
+		totalSize += e.Size
+		if newest == nil || e.ModTime.After(newest.ModTime) {
+			newest = &allEntries[i]
+		}
+	}
+
+	cards := []htmlCard{
+		{Label: "Total Files", Value: RenderInteger("#,###.", fileCount)},
+		{Label: "Total Size", Value: formatSize(totalSize, addCommas, convertToMiB, useSI), Detail: RenderInteger("#,###.", totalSize) + " bytes"},
+	}
+	if newest != nil {
+		cards = append(cards, htmlCard{Label: "Newest File", Value: filepath.Base(newest.FullName), Detail: newest.ModTime.Format(timeLayout)})
+	}
+	return cards
+}
+
+/*
+fileLink returns the hyperlink for a file name
+
+Args:
+    fname: the file name
+
+    linkBase: either "file://" to link to the file itself, or a base URL that the file name is appended to (-links and -linkbase cmd line options)
+
+Returns:
+    the URL, with each path component escaped
+*/
+func fileLink(fname string, linkBase string) string {
+	if "file://" == linkBase {
+		if abs, err := filepath.Abs(fname); err == nil {
+			fname = abs
+		}
+		p := filepath.ToSlash(fname)
+		if "windows" == runtime.GOOS && !strings.HasPrefix(p, "/") {
+			// such as C:/dir becoming file:///C:/dir
+			p = "/" + p
+		}
+		return (&url.URL{Scheme: "file", Path: p}).String()
+	}
+
+	parts := strings.Split(strings.TrimLeft(filepath.ToSlash(fname), "/"), "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.TrimRight(linkBase, "/") + "/" + strings.Join(parts, "/")
+}
+
+/*
+renderHTML outputs header and allRows as a standalone HTML page with a sortable, filterable table
+
+Args:
+    header: the column names
+
+    allRows: the table cells, which are HTML escaped
+
+    allLinks: when not empty, the hyperlink for the last column of each row; rows without a link can use ""
+
+    cards: summary cards shown above the table
+
+    summary: the totals shown below the table (-t cmd line option)
+
+    title: the page title and heading, "fstat" when empty (-title cmd line option)
+
+    theme: one of htmlThemes (-theme cmd line option)
+*/
+func renderHTML(header []string, allRows [][]string, allLinks []string, cards []htmlCard, summary []summaryField, title string, theme string) {
+	page := htmlPage{Title: title, Theme: theme, Cards: cards, Summary: summary, Header: header}
+	if 0 == len(page.Title) {
+		page.Title = "fstat"
+	}
+	for i, row := range allRows {
+		cells := make([]htmlCell, len(row))
+		for j, text := range row {
+			cells[j].Text = text
+		}
+		if i < len(allLinks) && len(cells) > 0 {
+			cells[len(cells)-1].Href = template.URL(allLinks[i])
+		}
+		page.Rows = append(page.Rows, cells)
+	}
+
+	tmpl := template.Must(template.New("html").Parse(htmlTemplate))
+	if err := tmpl.Execute(os.Stdout, page); err != nil {
+		logError("%s", err)
+		os.Exit(1)
+	}
+}
+
+// htmlTemplate - numbers with thousands separators or size units, such as 1.5 MiB, are sorted numerically
+const htmlTemplate = `<!DOCTYPE html>
+<html data-theme="{{.Theme}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+:root { --bg: #fff; --fg: #222; --muted: #666; --border: #ccc; --head: #eee; --stripe: #f8f8f8; --hover: #eef4ff; --card: #f4f6f8; --link: #0645ad; }
+html[data-theme="dark"] { --bg: #1e1f22; --fg: #ddd; --muted: #999; --border: #444; --head: #2b2d31; --stripe: #25262a; --hover: #2f3a4f; --card: #2b2d31; --link: #8ab4f8; }
+@media (prefers-color-scheme: dark) {
+  html[data-theme="auto"] { --bg: #1e1f22; --fg: #ddd; --muted: #999; --border: #444; --head: #2b2d31; --stripe: #25262a; --hover: #2f3a4f; --card: #2b2d31; --link: #8ab4f8; }
+}
+body { margin: 1em; font-family: sans-serif; font-size: 13px; background: var(--bg); color: var(--fg); }
+a { color: var(--link); }
+h1 { font-size: 1.6em; font-weight: normal; margin: 0 0 0.6em 0; }
+#cards { display: flex; flex-wrap: wrap; gap: 0.8em; margin-bottom: 1em; }
+.card { background: var(--card); border: 1px solid var(--border); border-radius: 6px; padding: 0.6em 1em; min-width: 10em; }
+.card .label { color: var(--muted); font-size: 0.85em; text-transform: uppercase; letter-spacing: 0.05em; }
+.card .value { font-size: 1.5em; margin: 0.15em 0; }
+.card .detail { color: var(--muted); font-size: 0.85em; }
+#filter { margin-bottom: 0.5em; padding: 4px; width: 30em; background: var(--bg); color: var(--fg); border: 1px solid var(--border); }
+#summary h2 { font-size: 1.2em; font-weight: normal; margin: 1em 0 0.4em 0; }
+#summary th { cursor: default; position: static; }
+#count { margin-left: 1em; color: var(--muted); }
+table { border-collapse: collapse; }
+th, td { border: 1px solid var(--border); padding: 3px 6px; text-align: left; white-space: nowrap; }
+th { background: var(--head); cursor: pointer; user-select: none; position: sticky; top: 0; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+tbody tr:nth-child(even) { background: var(--stripe); }
+tbody tr:hover { background: var(--hover); }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Cards}}
+<div id="cards">
+{{- range .Cards}}
+<div class="card"><div class="label">{{.Label}}</div><div class="value">{{.Value}}</div>{{if .Detail}}<div class="detail">{{.Detail}}</div>{{end}}</div>
+{{- end}}
+</div>
+{{- end}}
+<input id="filter" type="search" placeholder="filter rows" autofocus><span id="count"></span>
+<table id="entries">
+<thead>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{if .Href}}<a href="{{.Href}}">{{.Text}}</a>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .Summary}}
+<div id="summary">
+<h2>Summary</h2>
+<table>
+{{- range .Summary}}
+<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+</div>
+{{- end}}
+<script>
+(function () {
+  var table = document.getElementById("entries"), tbody = table.tBodies[0];
+  var rows = Array.prototype.slice.call(tbody.rows);
+  var units = { "": 1, B: 1, KB: 1e3, MB: 1e6, GB: 1e9, TB: 1e12, PB: 1e15, EB: 1e18,
+    KIB: 1024, MIB: Math.pow(1024, 2), GIB: Math.pow(1024, 3), TIB: Math.pow(1024, 4), PIB: Math.pow(1024, 5), EIB: Math.pow(1024, 6) };
+  function key(text) {
+    var m = text.replace(/,/g, "").trim().match(/^(-?\d+(?:\.\d+)?)\s*([KMGTPE]?i?B)?$/i);
+    if (m) { return Number(m[1]) * units[(m[2] || "").toUpperCase()]; }
+    return text;
+  }
+  function compare(a, b) {
+    if (typeof a === "number" && typeof b === "number") { return a - b; }
+    return String(a).localeCompare(String(b));
+  }
+  function count() {
+    var shown = rows.filter(function (r) { return r.style.display !== "none"; }).length;
+    document.getElementById("count").textContent = shown + " of " + rows.length + " rows";
+  }
+  Array.prototype.forEach.call(table.tHead.rows[0].cells, function (th, col) {
+    th.onclick = function () {
+      var asc = !th.classList.contains("asc");
+      Array.prototype.forEach.call(th.parentNode.cells, function (c) { c.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      rows.sort(function (a, b) {
+        var r = compare(key(a.cells[col].textContent), key(b.cells[col].textContent));
+        return asc ? r : -r;
+      });
+      rows.forEach(function (r) { tbody.appendChild(r); });
+    };
+  });
+  document.getElementById("filter").oninput = function () {
+    var words = this.value.toLowerCase().split(/\s+/).filter(Boolean);
+    rows.forEach(function (r) {
+      var text = r.textContent.toLowerCase();
+      r.style.display = words.every(function (w) { return text.indexOf(w) >= 0; }) ? "" : "none";
+    });
+    count();
+  };
+  count();
+})();
+</script>
+</body>
+</html>
+`
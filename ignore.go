@@ -0,0 +1,119 @@
+/*
+
+ignore.go
+
+Exclusion patterns loaded from .fstatignore files, used unless the -noignore cmd line option is given
+
+Patterns are read from .fstatignore in the user's home directory and then from the current directory.
+Each line contains one pattern using filepath.Match syntax; blank lines and lines starting with # are skipped.
+A pattern without a slash, such as *.tmp, is compared to each path component.
+A pattern with a slash, such as build/*.o, is compared to each sequence of path components.
+When a directory matches, everything beneath it is excluded too.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName - the name of the file containing exclusion patterns
+const ignoreFileName = ".fstatignore"
+
+/*
+readIgnoreFile returns all patterns listed in fname
+
+Args:
+    fname: the ignore file; a missing file is not an error
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+Returns:
+    a slice of patterns, with leading and trailing slashes removed
+*/
+func readIgnoreFile(fname string, quiet bool) []string {
+	var allPatterns []string
+	file, err := os.Open(fname)
+	if err != nil {
+		if !quiet && !os.IsNotExist(err) {
+			logError("%s", err)
+		}
+		return allPatterns
+	}
+	defer file.Close()
+
+	input := bufio.NewScanner(file)
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.Trim(filepath.FromSlash(line), string(os.PathSeparator))
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := filepath.Match(line, ""); err != nil {
+			if !quiet {
+				logWarn("invalid pattern in %s: %s", fname, line)
+			}
+			continue
+		}
+		allPatterns = append(allPatterns, line)
+	}
+	return allPatterns
+}
+
+// LoadIgnorePatterns - read the .fstatignore files in the user's home directory and in the current directory
+func LoadIgnorePatterns(quiet bool) []string {
+	var allPatterns []string
+	var home string
+	if dir, err := os.UserHomeDir(); err == nil {
+		home = filepath.Join(dir, ignoreFileName)
+		allPatterns = append(allPatterns, readIgnoreFile(home, quiet)...)
+	}
+	if local, err := filepath.Abs(ignoreFileName); err == nil && local != home {
+		allPatterns = append(allPatterns, readIgnoreFile(local, quiet)...)
+	}
+	return allPatterns
+}
+
+/*
+isIgnored reports whether fname matches any of the ignore patterns
+
+Args:
+    fname: the file name
+
+    allPatterns: patterns created by LoadIgnorePatterns
+
+Returns:
+    true when fname, or one of its parent directories, matches a pattern
+*/
+func isIgnored(fname string, allPatterns []string) bool {
+	sep := string(os.PathSeparator)
+	name := filepath.Clean(fname)
+	name = strings.Trim(name[len(filepath.VolumeName(name)):], sep)
+	parts := strings.Split(name, sep)
+
+	for _, pattern := range allPatterns {
+		if !strings.Contains(pattern, sep) {
+			for _, part := range parts {
+				if matched, _ := filepath.Match(pattern, part); matched {
+					return true
+				}
+			}
+			continue
+		}
+		for i := range parts {
+			for j := i + 1; j <= len(parts); j++ {
+				if matched, _ := filepath.Match(pattern, strings.Join(parts[i:j], sep)); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
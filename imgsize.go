@@ -0,0 +1,56 @@
+/*
+
+imgsize.go
+
+Find the width and height of images, used by the -imgsize cmd line option
+
+Example: fstat -imgsize -r assets/
+Example: fstat -where 'width > 4000 || height > 4000' -r assets/
+
+Only the header of each .png, .jpg, .jpeg and .gif file is read with image.DecodeConfig(), so that large
+images are not decoded. A file which is named as an image but can not be read as one is reported as an error.
+
+*/
+
+package main
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imageExtensions - the file extensions of the image formats which can be read, ignoring case
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
+
+// isImage - true when fname has one of imageExtensions
+func isImage(fname string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(fname))]
+}
+
+/*
+imageSize returns the dimensions of an image
+
+Args:
+    fname: the file name
+
+Returns:
+    the width and height in pixels; or an error when the file can not be read as an image
+*/
+func imageSize(fname string) (int, int, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
@@ -0,0 +1,176 @@
+/*
+
+log.go
+
+Write diagnostics to STDERR with a level, used by the -log, -loglevel, -verbosity and -q cmd line options
+
+By default, each diagnostic is written as plain text, such as: Error: lstat x: no such file or directory
+With -log text or -log json, each diagnostic is a single log/slog record with a time stamp and a level,
+so that it can be collected by a log aggregator when fstat is run by a scheduler.
+
+Reports requested on the cmd line, such as -stats and -progress, are not diagnostics and are not affected.
+
+-verbosity selects how much is reported while the file names are walked, examined and output:
+    quiet:   errors about individual files are not reported, the same as -q
+    normal:  errors are reported
+    verbose: also report each skipped file name with the option that skipped it, and the time taken by each stage
+    debug:   also report each directory walked and each included file name; this lowers -loglevel to debug
+
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logFormats - the values accepted by the -log cmd line option
+var logFormats = []string{"plain", "text", "json"}
+
+// logLevels - the values accepted by the -loglevel cmd line option
+var logLevels = map[string]slog.Level{"debug": slog.LevelDebug, "info": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError}
+
+// verbosity levels, in increasing order of detail
+const (
+	verbosityQuiet = iota
+	verbosityNormal
+	verbosityVerbose
+	verbosityDebug
+)
+
+// verbosityNames - the values accepted by the -verbosity cmd line option, indexed by level
+var verbosityNames = []string{"quiet", "normal", "verbose", "debug"}
+
+// verbosity - set by the -verbosity and -q cmd line options
+var verbosity = verbosityNormal
+
+// logger - nil when diagnostics are written as plain text
+var logger *slog.Logger
+
+// logLevel - diagnostics below this level are not written; shared with the slog handler so that setVerbosity() can lower it
+var logLevel = new(slog.LevelVar)
+
+/*
+setupLogging selects how diagnostics are written
+
+Args:
+    format: one of logFormats (-log cmd line option)
+
+    level: one of the keys of logLevels (-loglevel cmd line option)
+
+Returns:
+    an error when format or level is not valid
+*/
+func setupLogging(format string, level string) error {
+	lvl, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return fmt.Errorf("invalid -loglevel: %s; use one of: debug, info, warn, error", level)
+	}
+	logLevel.Set(lvl)
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	switch strings.ToLower(format) {
+	case "plain":
+		logger = nil
+	case "text":
+		logger = slog.New(slog.NewTextHandler(os.Stderr, options))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, options))
+	default:
+		return fmt.Errorf("invalid -log: %s; use one of: %s", format, strings.Join(logFormats, ", "))
+	}
+	return nil
+}
+
+/*
+setVerbosity selects how much is reported, see the top of this file
+setupLogging() must be called first
+
+Args:
+    name: one of verbosityNames (-verbosity cmd line option)
+
+    quiet: same as a name of quiet (cmd line option: -q)
+
+Returns:
+    an error when name is not valid, or conflicts with quiet
+*/
+func setVerbosity(name string, quiet bool) error {
+	level := -1
+	for i, v := range verbosityNames {
+		if strings.EqualFold(v, name) {
+			level = i
+		}
+	}
+	if level < 0 {
+		return fmt.Errorf("invalid -verbosity: %s; use one of: %s", name, strings.Join(verbosityNames, ", "))
+	}
+	if quiet {
+		if level > verbosityNormal {
+			return fmt.Errorf("-q can not be used with: -verbosity %s", name)
+		}
+		level = verbosityQuiet
+	}
+	verbosity = level
+	if verbosityDebug == verbosity && logLevel.Level() > slog.LevelDebug {
+		logLevel.Set(slog.LevelDebug)
+	}
+	return nil
+}
+
+/*
+logAt writes a single diagnostic
+
+Args:
+    level: the level of the diagnostic
+
+    prefix, suffix: added to the message when written as plain text, such as: "Error: " and "\n"
+
+    format, args: the message, as with fmt.Sprintf()
+*/
+func logAt(level slog.Level, prefix string, suffix string, format string, args ...interface{}) {
+	if level < logLevel.Level() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if logger != nil {
+		logger.Log(context.Background(), level, msg)
+		return
+	}
+	fmt.Fprint(os.Stderr, prefix+msg+suffix)
+}
+
+// logDebug - a diagnostic that is only written with: -loglevel debug
+func logDebug(format string, args ...interface{}) {
+	logAt(slog.LevelDebug, "Debug: ", "\n", format, args...)
+}
+
+// logInfo - a diagnostic about normal progress, such as the number of entries written to a file
+func logInfo(format string, args ...interface{}) {
+	logAt(slog.LevelInfo, "", "\n", format, args...)
+}
+
+// logVerbose - a diagnostic that is only written with: -verbosity verbose or -verbosity debug
+func logVerbose(format string, args ...interface{}) {
+	if verbosity >= verbosityVerbose {
+		logAt(slog.LevelInfo, "", "\n", format, args...)
+	}
+}
+
+// logWarn - a diagnostic about something that may not be what was intended
+func logWarn(format string, args ...interface{}) {
+	logAt(slog.LevelWarn, "Warning: ", "\n", format, args...)
+}
+
+// logError - a diagnostic about a file or operation that failed
+func logError(format string, args ...interface{}) {
+	logAt(slog.LevelError, "Error: ", "\n", format, args...)
+}
+
+// usageError - a diagnostic about invalid cmd line options, which is followed by a blank line when written as plain text
+func usageError(format string, args ...interface{}) {
+	logAt(slog.LevelError, "Error: ", "\n\n", format, args...)
+}
@@ -0,0 +1,131 @@
+/*
+
+longpaths.go
+
+Find paths which are too long for Windows, used by the -maxpath cmd line option and the longpaths subcommand
+
+Example: fstat -maxpath 200 -r dirs.txt
+Example: fstat longpaths -r /srv/share
+
+The length of each absolute path is counted as Windows does, in UTF-16 code units, so that a character outside
+of the Basic Multilingual Plane, such as most emoji, counts as two. Many Windows programs can not open a path
+of 260 or more, which is used by the longpaths subcommand. Trees created on Linux, where paths can be much
+longer, can be checked before they are copied to a Windows client.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// windowsMaxPath - MAX_PATH, the default of the longpaths subcommand
+const windowsMaxPath = 260
+
+// longPath - an entry whose absolute path is longer than the limit
+type longPath struct {
+	FullName string `json:"fullname"`
+	Absolute string `json:"absolute"`
+	Length   int    `json:"length"`
+	Over     int    `json:"over"`
+}
+
+// longPathReport - the entries with long paths, and the number of entries checked
+type longPathReport struct {
+	Limit   int        `json:"limit"`
+	Checked int        `json:"checked"`
+	Paths   []longPath `json:"paths"`
+}
+
+// pathLength - the length of path in UTF-16 code units, as counted by Windows
+func pathLength(path string) int {
+	return len(utf16.Encode([]rune(path)))
+}
+
+/*
+FindLongPaths returns the entries whose absolute path is longer than limit
+
+Args:
+    allEntries: the entries to check
+
+    limit: the longest allowed path (-maxpath cmd line option)
+
+    onlyTypes: only check these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the report, with the longest paths first, and then by name
+*/
+func FindLongPaths(allEntries []FileStat, limit int, onlyTypes string) longPathReport {
+	report := longPathReport{Limit: limit, Paths: []longPath{}}
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		report.Checked++
+		abs, err := filepath.Abs(e.FullName)
+		if err != nil {
+			abs = e.FullName
+		}
+		if length := pathLength(abs); length > limit {
+			report.Paths = append(report.Paths, longPath{FullName: e.FullName, Absolute: abs, Length: length, Over: length - limit})
+		}
+	}
+	sort.Slice(report.Paths, func(i, j int) bool {
+		if report.Paths[i].Length != report.Paths[j].Length {
+			return report.Paths[i].Length > report.Paths[j].Length
+		}
+		return report.Paths[i].FullName < report.Paths[j].FullName
+	})
+	return report
+}
+
+/*
+RenderLongPaths outputs the entries whose absolute path is too long
+
+Args:
+    report: created by FindLongPaths()
+
+    outputCSV, outputTSV, outputJSON: alternate output formats (-oc, -ot, -oj cmd line options)
+
+    csvDelimiter: the field delimiter used with outputCSV (-csvdelim cmd line option)
+
+Returns:
+    the number of entries output
+*/
+func RenderLongPaths(report longPathReport, outputCSV bool, csvDelimiter rune, outputTSV bool, outputJSON bool) int {
+	if outputJSON {
+		j, _ := json.MarshalIndent(report, "", "    ")
+		fmt.Println(string(j))
+		return len(report.Paths)
+	}
+
+	var allRows [][]string
+	for _, p := range report.Paths {
+		allRows = append(allRows, []string{strconv.Itoa(p.Length), strconv.Itoa(p.Over), p.Absolute})
+	}
+	header := []string{"Length", "Over", "Name"}
+
+	if outputCSV {
+		renderCSV(header, allRows, csvDelimiter, nil)
+		return len(report.Paths)
+	}
+
+	if outputTSV {
+		renderTSV(header, allRows, nil)
+		return len(report.Paths)
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("paths longer than %d: %d of %d entries\n", report.Limit, len(report.Paths), report.Checked)
+	return len(report.Paths)
+}
@@ -0,0 +1,507 @@
+/*
+
+media.go
+
+Find the duration of audio and video files from their container headers, used by the -duration cmd line option
+
+Example: fstat -duration -t -r /srv/music
+Example: fstat -duration -group dir -where 'duration > 2h' -r /srv/video
+
+Supported containers:
+    .mp4 .m4a .m4b .m4v .mov .3gp    the mvhd box within the moov box
+    .mp3                             a Xing, Info or VBRI header, or else the size and bit rate of a constant bit rate file
+    .wav                             the size of the data chunk and the byte rate of the fmt chunk
+    .flac                            the sample rate and number of samples in the STREAMINFO block
+    .ogg .oga .opus                  the sample rate of the Vorbis or Opus header and the granule position of the last page
+    .mkv .mka .webm                  the Duration and TimecodeScale of the Info element
+
+Only the headers, and for Ogg the last 64 KiB, are read, so that the audio and video data is never decoded.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mediaReader - reads the duration in seconds from the headers of a container of the given size
+type mediaReader func(r io.ReaderAt, size int64) (float64, error)
+
+// mediaFormats - the container of each file extension, ignoring case
+var mediaFormats = map[string]mediaReader{
+	".mp4": mp4Duration, ".m4a": mp4Duration, ".m4b": mp4Duration, ".m4v": mp4Duration, ".mov": mp4Duration, ".3gp": mp4Duration,
+	".mp3":  mp3Duration,
+	".wav":  wavDuration,
+	".flac": flacDuration,
+	".ogg":  oggDuration, ".oga": oggDuration, ".opus": oggDuration,
+	".mkv": mkvDuration, ".mka": mkvDuration, ".webm": mkvDuration,
+}
+
+// errMediaHeader - the headers of a media file do not hold its duration
+var errMediaHeader = errors.New("duration not found in media headers")
+
+// isMedia - true when fname has one of the extensions of mediaFormats
+func isMedia(fname string) bool {
+	_, ok := mediaFormats[strings.ToLower(filepath.Ext(fname))]
+	return ok
+}
+
+// formatDuration - render seconds as hours, minutes and seconds, such as "1:02:03", or "2:03" when less than an hour
+func formatDuration(seconds float64) string {
+	total := int64(math.Round(seconds))
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+/*
+mediaDuration returns the duration of an audio or video file
+
+Args:
+    fname: the file name, whose extension is one of mediaFormats
+
+Returns:
+    the duration in seconds; or an error when the file can not be read or its headers do not hold the duration
+*/
+func mediaDuration(fname string) (float64, error) {
+	read, ok := mediaFormats[strings.ToLower(filepath.Ext(fname))]
+	if !ok {
+		return 0, errMediaHeader
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	f, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	seconds, err := read(file, f.Size())
+	if io.ErrUnexpectedEOF == err || io.EOF == err {
+		return 0, errMediaHeader
+	}
+	return seconds, err
+}
+
+// readBytes - exactly n bytes from r at off
+func readBytes(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	if off < 0 || n < 0 {
+		return nil, errMediaHeader
+	}
+	b := make([]byte, n)
+	if _, err := r.ReadAt(b, off); err != nil {
+		if io.EOF == err {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+/*
+findBox returns the first ISO base media box of a type
+
+Args:
+    r: the file
+
+    start, end: the part of the file holding a sequence of boxes
+
+    boxType: the four character type, such as "moov"
+
+Returns:
+    the start and end of the contents of the box; or an error when there is no such box
+*/
+func findBox(r io.ReaderAt, start int64, end int64, boxType string) (int64, int64, error) {
+	for off := start; off+8 <= end; {
+		h, err := readBytes(r, off, 8)
+		if err != nil {
+			return 0, 0, err
+		}
+		boxSize, header := int64(binary.BigEndian.Uint32(h)), int64(8)
+		switch boxSize {
+		case 0: // the box extends to the end
+			boxSize = end - off
+		case 1: // a 64 bit size follows the type
+			large, err := readBytes(r, off+8, 8)
+			if err != nil {
+				return 0, 0, err
+			}
+			boxSize, header = int64(binary.BigEndian.Uint64(large)), 16
+		}
+		if boxSize < header {
+			return 0, 0, errMediaHeader
+		}
+		if boxType == string(h[4:8]) {
+			return off + header, min(off+boxSize, end), nil
+		}
+		off += boxSize
+	}
+	return 0, 0, errMediaHeader
+}
+
+// mp4Duration - the duration in the movie header of an MPEG-4 or QuickTime file
+func mp4Duration(r io.ReaderAt, size int64) (float64, error) {
+	moov, moovEnd, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhd, _, err := findBox(r, moov, moovEnd, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+	b, err := readBytes(r, mvhd, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	// version 1 has 64 bit times and duration
+	var timescale, duration uint64
+	if 1 == b[0] {
+		timescale, duration = uint64(binary.BigEndian.Uint32(b[20:24])), binary.BigEndian.Uint64(b[24:32])
+	} else {
+		timescale, duration = uint64(binary.BigEndian.Uint32(b[12:16])), uint64(binary.BigEndian.Uint32(b[16:20]))
+	}
+	if 0 == timescale {
+		return 0, errMediaHeader
+	}
+	return float64(duration) / float64(timescale), nil
+}
+
+// mp3Bitrates - in kbit/s, by MPEG 1 or MPEG 2 and 2.5, then by layer 1, 2 or 3, then by the bit rate index
+var mp3Bitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// mp3SampleRates - of MPEG 1 by the sample rate index; halved for MPEG 2 and quartered for MPEG 2.5
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+// mp3SearchSize - the number of bytes after any ID3v2 tag which are searched for the first frame
+const mp3SearchSize = 64 * 1024
+
+// mp3Duration - the duration of an MP3 file, from its first frame
+func mp3Duration(r io.ReaderAt, size int64) (float64, error) {
+	h, err := readBytes(r, 0, 10)
+	if err != nil {
+		return 0, err
+	}
+	var start int64
+	if "ID3" == string(h[:3]) {
+		start = 10 + (int64(h[6]&0x7f)<<21 | int64(h[7]&0x7f)<<14 | int64(h[8]&0x7f)<<7 | int64(h[9]&0x7f))
+		if h[5]&0x10 != 0 { // a footer follows the tag
+			start += 10
+		}
+	}
+	if start >= size {
+		return 0, errMediaHeader
+	}
+	buf, err := readBytes(r, start, int(min(size-start, mp3SearchSize)))
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := buf[i+1] >> 3 & 3 // 0: MPEG 2.5, 2: MPEG 2, 3: MPEG 1
+		layer := 3 - int(buf[i+1]>>1&3)
+		bitrateIndex := buf[i+2] >> 4
+		rateIndex := buf[i+2] >> 2 & 3
+		if 1 == version || 3 == layer || 0 == bitrateIndex || 15 == bitrateIndex || 3 == rateIndex {
+			continue
+		}
+		mpeg1 := 3 == version
+		table := 1
+		if mpeg1 {
+			table = 0
+		}
+		bitrate := mp3Bitrates[table][layer][bitrateIndex] * 1000
+		sampleRate := mp3SampleRates[rateIndex]
+		switch version {
+		case 2:
+			sampleRate /= 2
+		case 0:
+			sampleRate /= 4
+		}
+		samples := 1152
+		if 0 == layer {
+			samples = 384
+		} else if 2 == layer && !mpeg1 {
+			samples = 576
+		}
+
+		// VBR encoders write the number of frames in a Xing or Info header after the side information, or a VBRI header
+		frame := buf[i:]
+		mono := 3 == frame[3]>>6
+		side := 32
+		if mpeg1 == mono {
+			side = 17
+		} else if !mpeg1 && mono {
+			side = 9
+		}
+		if x := 4 + side; len(frame) >= x+12 && ("Xing" == string(frame[x:x+4]) || "Info" == string(frame[x:x+4])) && frame[x+7]&1 != 0 {
+			return float64(binary.BigEndian.Uint32(frame[x+8:x+12])) * float64(samples) / float64(sampleRate), nil
+		}
+		if len(frame) >= 54 && "VBRI" == string(frame[36:40]) {
+			return float64(binary.BigEndian.Uint32(frame[50:54])) * float64(samples) / float64(sampleRate), nil
+		}
+
+		// otherwise the bit rate is constant; an ID3v1 tag is not audio
+		audio := size - start - int64(i)
+		if size >= 128 {
+			if tag, err := readBytes(r, size-128, 3); nil == err && "TAG" == string(tag) {
+				audio -= 128
+			}
+		}
+		return float64(audio) * 8 / float64(bitrate), nil
+	}
+	return 0, errMediaHeader
+}
+
+// wavDuration - the size of the data chunk divided by the byte rate of a WAV file
+func wavDuration(r io.ReaderAt, size int64) (float64, error) {
+	h, err := readBytes(r, 0, 12)
+	if err != nil {
+		return 0, err
+	}
+	if "RIFF" != string(h[0:4]) || "WAVE" != string(h[8:12]) {
+		return 0, errMediaHeader
+	}
+
+	var byteRate uint32
+	for off := int64(12); off+8 <= size; {
+		c, err := readBytes(r, off, 8)
+		if err != nil {
+			return 0, err
+		}
+		chunkSize := int64(binary.LittleEndian.Uint32(c[4:8]))
+		switch string(c[0:4]) {
+		case "fmt ":
+			f, err := readBytes(r, off+8, 12)
+			if err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(f[8:12])
+		case "data":
+			if 0 == byteRate {
+				return 0, errMediaHeader
+			}
+			// the size is not known when the file was written as a stream
+			chunkSize = min(chunkSize, size-off-8)
+			return float64(chunkSize) / float64(byteRate), nil
+		}
+		off += 8 + chunkSize + chunkSize%2
+	}
+	return 0, errMediaHeader
+}
+
+// flacDuration - the number of samples divided by the sample rate in the STREAMINFO block of a FLAC file
+func flacDuration(r io.ReaderAt, size int64) (float64, error) {
+	b, err := readBytes(r, 0, 42)
+	if err != nil {
+		return 0, err
+	}
+	// STREAMINFO is always the first metadata block
+	if "fLaC" != string(b[0:4]) || b[4]&0x7f != 0 {
+		return 0, errMediaHeader
+	}
+	v := binary.BigEndian.Uint64(b[18:26])
+	sampleRate, samples := v>>44, v&(1<<36-1)
+	if 0 == sampleRate || 0 == samples {
+		return 0, errMediaHeader
+	}
+	return float64(samples) / float64(sampleRate), nil
+}
+
+// oggTailSize - the number of bytes at the end of an Ogg file which are searched for the last page
+const oggTailSize = 64 * 1024
+
+// oggDuration - the granule position of the last page divided by the sample rate of an Ogg Vorbis or Opus file
+func oggDuration(r io.ReaderAt, size int64) (float64, error) {
+	first, err := readBytes(r, 0, int(min(size, 512)))
+	if err != nil {
+		return 0, err
+	}
+	if len(first) < 27 || !bytes.HasPrefix(first, []byte("OggS")) {
+		return 0, errMediaHeader
+	}
+
+	// the identification header is the first packet, after the segment table
+	packet := first[min(27+int(first[26]), len(first)):]
+	var sampleRate, preSkip uint64
+	switch {
+	case len(packet) >= 16 && bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		sampleRate = uint64(binary.LittleEndian.Uint32(packet[12:16]))
+	case len(packet) >= 12 && bytes.HasPrefix(packet, []byte("OpusHead")):
+		// Opus granule positions are always at 48 kHz, and include samples which are skipped when playing
+		sampleRate, preSkip = 48000, uint64(binary.LittleEndian.Uint16(packet[10:12]))
+	default:
+		return 0, errMediaHeader
+	}
+
+	tailSize := min(size, oggTailSize)
+	tail, err := readBytes(r, size-tailSize, int(tailSize))
+	if err != nil {
+		return 0, err
+	}
+	i := bytes.LastIndex(tail, []byte("OggS"))
+	if i < 0 || i+14 > len(tail) {
+		return 0, errMediaHeader
+	}
+	granule := binary.LittleEndian.Uint64(tail[i+6 : i+14])
+	if 0 == sampleRate || granule < preSkip || math.MaxUint64 == granule {
+		return 0, errMediaHeader
+	}
+	return float64(granule-preSkip) / float64(sampleRate), nil
+}
+
+// the Matroska element IDs which are needed to find the duration
+const (
+	ebmlHeaderID       = 0x1A45DFA3
+	mkvSegmentID       = 0x18538067
+	mkvInfoID          = 0x1549A966
+	mkvTimecodeScaleID = 0x2AD7B1
+	mkvDurationID      = 0x4489
+	mkvClusterID       = 0x1F43B675
+)
+
+// ebmlVint - the EBML variable length integer at off, and its length; element IDs keep their length marker
+func ebmlVint(r io.ReaderAt, off int64, keepMarker bool) (uint64, int, error) {
+	b, err := readBytes(r, off, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	length := bits.LeadingZeros8(b[0]) + 1
+	if length > 8 {
+		return 0, 0, errMediaHeader
+	}
+	rest, err := readBytes(r, off+1, length-1)
+	if err != nil {
+		return 0, 0, err
+	}
+	value := uint64(b[0])
+	if !keepMarker {
+		value &= 0xff >> length
+	}
+	for _, c := range rest {
+		value = value<<8 | uint64(c)
+	}
+	return value, length, nil
+}
+
+/*
+ebmlElement reads the header of an EBML element
+
+Args:
+    r: the file
+
+    off: the start of the element
+
+    end: the end of the parent element, used when the size of the element is unknown
+
+Returns:
+    the element ID, the start of its data, and the end of the element; or an error when it can not be read
+*/
+func ebmlElement(r io.ReaderAt, off int64, end int64) (uint64, int64, int64, error) {
+	id, idLength, err := ebmlVint(r, off, true)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size, sizeLength, err := ebmlVint(r, off+int64(idLength), false)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	data := off + int64(idLength) + int64(sizeLength)
+	if size == 1<<(7*sizeLength)-1 || size > uint64(end-data) { // all ones is an unknown size
+		return id, data, end, nil
+	}
+	return id, data, data + int64(size), nil
+}
+
+// mkvDuration - the duration in the Info element of a Matroska or WebM file
+func mkvDuration(r io.ReaderAt, size int64) (float64, error) {
+	id, _, next, err := ebmlElement(r, 0, size)
+	if err != nil {
+		return 0, err
+	}
+	if ebmlHeaderID != id {
+		return 0, errMediaHeader
+	}
+	id, segment, segmentEnd, err := ebmlElement(r, next, size)
+	if err != nil {
+		return 0, err
+	}
+	if mkvSegmentID != id {
+		return 0, errMediaHeader
+	}
+
+	// Info comes before the first Cluster, which holds the audio and video
+	for off := segment; off < segmentEnd; off = next {
+		var data int64
+		id, data, next, err = ebmlElement(r, off, segmentEnd)
+		if err != nil {
+			return 0, err
+		}
+		if mkvClusterID == id {
+			break
+		}
+		if mkvInfoID != id {
+			continue
+		}
+
+		scale, duration, found := uint64(1000000), 0.0, false
+		for child := data; child < next; {
+			id, value, end, err := ebmlElement(r, child, next)
+			if err != nil {
+				return 0, err
+			}
+			if end-value > 8 {
+				child = end
+				continue
+			}
+			b, err := readBytes(r, value, int(end-value))
+			if err != nil {
+				return 0, err
+			}
+			switch {
+			case mkvTimecodeScaleID == id:
+				scale = 0
+				for _, c := range b {
+					scale = scale<<8 | uint64(c)
+				}
+			case mkvDurationID == id && 4 == len(b):
+				duration, found = float64(math.Float32frombits(binary.BigEndian.Uint32(b))), true
+			case mkvDurationID == id && 8 == len(b):
+				duration, found = math.Float64frombits(binary.BigEndian.Uint64(b)), true
+			}
+			child = end
+		}
+		if !found {
+			return 0, errMediaHeader
+		}
+		return duration * float64(scale) / 1e9, nil
+	}
+	return 0, errMediaHeader
+}
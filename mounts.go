@@ -0,0 +1,121 @@
+/*
+
+mounts.go
+
+Capacity of each file system containing the collected entries, used by the -tm cmd line option
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// MountStat - capacity of a single file system
+type MountStat struct {
+	MountPoint string `json:"mountpoint"`
+	Total      uint64 `json:"total"`
+	Used       uint64 `json:"used"`
+	Free       uint64 `json:"free"`
+	Entries    int64  `json:"entries"`
+}
+
+/*
+findMountPoint returns the top-most directory of fname that is still on the same device
+This is done by walking up the parent directories until the device changes
+
+Args:
+    fname: the file name
+
+    device: the device that contains fname, from getDevice()
+
+Returns:
+    the mount point, as an absolute path
+*/
+func findMountPoint(fname string, device uint64) string {
+	current, err := filepath.Abs(fname)
+	if err != nil {
+		return fname
+	}
+	for {
+		parent := filepath.Dir(current)
+		if parent == current {
+			return current
+		}
+		info, err := os.Lstat(parent)
+		if err != nil || getDevice(parent, info) != device {
+			return current
+		}
+		current = parent
+	}
+}
+
+/*
+GetMountStats returns the capacity of each file system that contains at least one of the given entries
+
+Args:
+    allEntries: a slice of all files
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+Returns:
+    a slice of MountStat sorted by mount point
+*/
+func GetMountStats(allEntries []FileStat, quiet bool) []MountStat {
+	allMounts := make(map[uint64]*MountStat)
+	for _, e := range allEntries {
+		if m, ok := allMounts[e.Device]; ok {
+			m.Entries++
+			continue
+		}
+		m := &MountStat{MountPoint: findMountPoint(e.FullName, e.Device), Entries: 1}
+		var err error
+		m.Total, m.Used, m.Free, err = getDiskSpace(m.MountPoint)
+		if err != nil && !quiet {
+			logError("%s: %s", m.MountPoint, err)
+		}
+		allMounts[e.Device] = m
+	}
+
+	var mounts []MountStat
+	for _, m := range allMounts {
+		mounts = append(mounts, *m)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].MountPoint < mounts[j].MountPoint })
+	return mounts
+}
+
+/*
+RenderMountStats outputs a table with the total, used and free space of each file system
+
+Args:
+    mounts: a slice of MountStat created by GetMountStats
+
+    addCommas: when set, add a comma as a thousands separator (-c cmd line option)
+
+    convertToMiB: when set, output sizes in Mebibytes (-m cmd line option)
+
+    useSI: when set, output sizes in decimal KB, MB, GB units (-dec cmd line option)
+*/
+func RenderMountStats(mounts []MountStat, addCommas bool, convertToMiB bool, useSI bool) {
+	var allRows [][]string
+	for _, m := range mounts {
+		var percentUsed float64
+		if m.Total > 0 {
+			percentUsed = float64(m.Used) * 100 / float64(m.Total)
+		}
+		allRows = append(allRows, []string{m.MountPoint, formatSize(m.Entries, addCommas, false, false), formatSize(int64(m.Total), addCommas, convertToMiB, useSI), formatSize(int64(m.Used), addCommas, convertToMiB, useSI), formatSize(int64(m.Free), addCommas, convertToMiB, useSI), fmt.Sprintf("%.1f%%", percentUsed)})
+	}
+
+	if len(allRows) > 0 {
+		fmt.Println()
+		header := []string{"Mount Point", "Entries", "Total", "Used", "Free", "Use%"}
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT})
+	}
+}
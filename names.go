@@ -0,0 +1,101 @@
+/*
+
+names.go
+
+Output only the file names, used by the -names, -print0, and -xargs cmd line options
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// xargsMaxDefault - the default length of each -xargs batch, which is the default of GNU xargs
+// and well below the command line limit of Linux, macOS and the BSDs
+const xargsMaxDefault = 128 * 1024
+
+/*
+RenderNames outputs the name of each entry followed by a separator, so that fstat can be used in a pipeline
+Example: fstat -print0 -sS list.txt | xargs -0 ls -l
+
+Args:
+    allEntries: a slice of all files, already sorted
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    separator: either a newline (-names cmd line option) or a NUL character (-print0 cmd line option)
+*/
+func RenderNames(allEntries []FileStat, onlyTypes string, separator byte) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		w.WriteString(e.FullName)
+		w.WriteByte(separator)
+	}
+}
+
+// shellSafe - characters which never need to be quoted by a POSIX shell or by xargs
+const shellSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-+=.,/:@%"
+
+// shellQuote - quote a name for a POSIX shell or xargs, using single quotes unless every character is safe
+func shellQuote(name string) string {
+	if len(name) > 0 && "" == strings.Trim(name, shellSafe) {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", `'\''`) + "'"
+}
+
+/*
+RenderXargs outputs the names as shell quoted batches, one batch per line, so that each batch can be given to a single command
+Example: fstat -xargs -r dirs.txt | xargs -L1 ls -l
+
+Args:
+    allEntries: a slice of all files, already sorted
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    maxLength: the most bytes in each batch, not counting the newline (-xargsmax cmd line option)
+
+    quiet: when set, names that can not be output are not reported to STDERR (cmd line option: -q)
+
+Names containing a newline, or longer than maxLength once quoted, can not be part of a batch and are skipped.
+*/
+func RenderXargs(allEntries []FileStat, onlyTypes string, maxLength int, quiet bool) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	length := 0
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		quoted := shellQuote(e.FullName)
+		if strings.ContainsAny(e.FullName, "\n\r") || len(quoted) > maxLength {
+			if !quiet {
+				logError("-xargs skipped a name with a newline or longer than -xargsmax: %q", e.FullName)
+			}
+			continue
+		}
+		if length > 0 && length+1+len(quoted) > maxLength {
+			w.WriteByte('\n')
+			length = 0
+		}
+		if length > 0 {
+			w.WriteByte(' ')
+			length++
+		}
+		w.WriteString(quoted)
+		length += len(quoted)
+	}
+	if length > 0 {
+		w.WriteByte('\n')
+	}
+}
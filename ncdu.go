@@ -0,0 +1,97 @@
+/*
+
+ncdu.go
+
+Export in the ncdu JSON format, used by the -oncdu cmd line option
+The result can be browsed with: ncdu -f file.json
+
+Format reference: https://dev.yorhel.nl/ncdu/jsonfmt
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// ncduInfo - the information block for each file or directory
+type ncduInfo struct {
+	Name   string `json:"name"`
+	Asize  int64  `json:"asize,omitempty"`
+	Dsize  int64  `json:"dsize,omitempty"`
+	Mtime  int64  `json:"mtime,omitempty"`
+	NotReg bool   `json:"notreg,omitempty"`
+}
+
+// newNcduInfo - create the information block for a tree node
+// directories which were not part of the input do not have a size or mtime
+func newNcduInfo(node *treeNode) ncduInfo {
+	info := ncduInfo{Name: node.Name}
+	if node.entry == nil {
+		return info
+	}
+	info.Mtime = node.entry.ModTime.Unix()
+	if "D" != node.entry.FileType {
+		info.Asize = node.entry.Size
+		info.Dsize = node.entry.Allocated
+		info.NotReg = "F" != node.entry.FileType
+	}
+	return info
+}
+
+// writeNcduNode - recursively write a node; directories are written as an array
+// of their information block followed by all of their children
+func writeNcduNode(w *bufio.Writer, node *treeNode) {
+	info, _ := json.Marshal(newNcduInfo(node))
+	isDir := len(node.Children) > 0 || (node.entry != nil && "D" == node.entry.FileType)
+	if !isDir {
+		w.Write(info)
+		return
+	}
+
+	w.WriteString("[")
+	w.Write(info)
+	for _, c := range node.Children {
+		w.WriteString(",\n")
+		writeNcduNode(w, c)
+	}
+	w.WriteString("]")
+}
+
+/*
+RenderNcdu outputs all entries in the ncdu JSON export format
+The common parent directory of all entries is used as the root of the export, along with its information when it
+is one of the entries, such as . with -r
+
+Args:
+    allEntries: a slice of all files, directories and symbolic links
+*/
+func RenderNcdu(allEntries []FileStat) {
+	root := &treeNode{Name: "."}
+	for i := range allEntries {
+		node := root
+		if nodes := root.insert(allEntries[i].FullName); len(nodes) > 0 {
+			node = nodes[len(nodes)-1]
+		}
+		node.entry = &allEntries[i]
+	}
+	root = collapseRoot(root)
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"progname":  "fstat",
+		"progver":   version,
+		"timestamp": time.Now().Unix(),
+	})
+
+	w := bufio.NewWriter(os.Stdout)
+	w.WriteString("[1,0,")
+	w.Write(meta)
+	w.WriteString(",\n")
+	writeNcduNode(w, root)
+	w.WriteString("]\n")
+	w.Flush()
+}
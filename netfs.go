@@ -0,0 +1,196 @@
+/*
+
+netfs.go
+
+Limit the time spent examining files on network file systems, used by the -nettimeout cmd line option
+
+Example: fstat -nettimeout 3s -r /home
+Example: fstat -nettimeout 0s -r /mnt/archive
+
+The network file systems, such as NFS, SMB and automounted shares, are found without accessing them, see
+listNetworkMounts(). Each os.Lstat() of a file on one of these is abandoned when it takes longer than the
+timeout, and that file is reported as an error. A file system which has not responded is then skipped for the
+rest of the run, so that one hung share does not freeze the whole scan. A walk with -r does not descend into a
+file system once it has not responded. 0s waits as long as the file system takes.
+
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// networkFileSystems - the file system types which are served over the network, as named by the OS
+var networkFileSystems = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true, "ncpfs": true, "afs": true, "9p": true,
+	"ceph": true, "lustre": true, "gpfs": true, "davfs": true, "afpfs": true, "webdav": true, "autofs": true,
+	"fuse.sshfs": true, "fuse.glusterfs": true, "fuse.s3fs": true, "fuse.rclone": true,
+}
+
+// errNetTimeout - returned when a network file system does not respond in time
+var errNetTimeout = errors.New("network file system did not respond")
+
+// netMount - a network file system, by its mount point
+type netMount struct {
+	point  string
+	fsType string
+	hung   bool
+}
+
+// netFileSystems - the network file systems, and the timeout for each file on them; a nil netFileSystems waits forever
+type netFileSystems struct {
+	timeout time.Duration
+	mu      sync.Mutex
+	// sorted by mount point, longest first, so that the first match is the innermost file system
+	mounts []*netMount
+}
+
+/*
+newNetFileSystems finds the network file systems
+
+Args:
+    timeout: the longest time to wait for each file; when 0, nil is returned
+
+Returns:
+    the network file systems
+*/
+func newNetFileSystems(timeout time.Duration) *netFileSystems {
+	if 0 == timeout {
+		return nil
+	}
+	n := &netFileSystems{timeout: timeout}
+	for point, fsType := range listNetworkMounts() {
+		logVerbose("network file system: %s (%s)", point, fsType)
+		n.mounts = append(n.mounts, &netMount{point: point, fsType: fsType})
+	}
+	sort.Slice(n.mounts, func(i, j int) bool { return len(n.mounts[i].point) > len(n.mounts[j].point) })
+	return n
+}
+
+// isUnder - true when fname is dir, or is within dir; on Windows, ignoring case
+func isUnder(fname string, dir string) bool {
+	if "windows" == runtime.GOOS {
+		fname, dir = strings.ToLower(fname), strings.ToLower(dir)
+	}
+	if fname == dir {
+		return true
+	}
+	return strings.HasPrefix(fname, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+// mountOf - the network file system which contains fname, or nil when it is on a local file system
+func (n *netFileSystems) mountOf(fname string) *netMount {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, m := range n.mounts {
+		if isUnder(abs, m.point) {
+			return m
+		}
+	}
+
+	// each share of a UNC path, such as \\server\share, is its own network file system
+	if volume := filepath.VolumeName(abs); strings.HasPrefix(volume, `\\`) {
+		m := &netMount{point: volume, fsType: "smb"}
+		n.mounts = append(n.mounts, m)
+		sort.Slice(n.mounts, func(i, j int) bool { return len(n.mounts[i].point) > len(n.mounts[j].point) })
+		return m
+	}
+	return nil
+}
+
+/*
+wait runs f, and gives up when it takes longer than the timeout, marking m as hung
+f keeps running in the background, as a call blocked in the kernel can not be interrupted
+
+Args:
+    m: the network file system being accessed
+
+    f: the call to make
+
+Returns:
+    an error wrapping errNetTimeout when m has not responded, now or before
+*/
+func (n *netFileSystems) wait(m *netMount, f func()) error {
+	n.mu.Lock()
+	hung := m.hung
+	n.mu.Unlock()
+	if hung {
+		return fmt.Errorf("%w: %s on %s, skipped", errNetTimeout, m.fsType, m.point)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	timer := time.NewTimer(n.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		n.mu.Lock()
+		m.hung = true
+		n.mu.Unlock()
+		if verbosity > verbosityQuiet {
+			logWarn("%s on %s did not respond within %s, and is skipped for the rest of the run", m.fsType, m.point, n.timeout)
+		}
+		return fmt.Errorf("%w: %s on %s, within %s", errNetTimeout, m.fsType, m.point, n.timeout)
+	}
+}
+
+// lstat - the same as os.Lstat(), but limited to the timeout on network file systems; n can be nil
+func (n *netFileSystems) lstat(fname string) (os.FileInfo, error) {
+	if nil == n {
+		return os.Lstat(fname)
+	}
+	m := n.mountOf(fname)
+	if nil == m {
+		return os.Lstat(fname)
+	}
+	var info os.FileInfo
+	var err error
+	if waitErr := n.wait(m, func() { info, err = os.Lstat(fname) }); waitErr != nil {
+		return nil, &os.PathError{Op: "lstat", Path: fname, Err: waitErr}
+	}
+	return info, err
+}
+
+/*
+readable is used before walking a directory, and checks that its network file system responds to reading a
+directory within the timeout; the entries are read again by the walk. n can be nil
+
+Args:
+    dir: the directory name
+
+Returns:
+    an error wrapping errNetTimeout when the network file system of dir has not responded
+*/
+func (n *netFileSystems) readable(dir string) error {
+	if nil == n {
+		return nil
+	}
+	m := n.mountOf(dir)
+	if nil == m {
+		return nil
+	}
+	return n.wait(m, func() {
+		if f, err := os.Open(dir); err == nil {
+			f.Readdirnames(1)
+			f.Close()
+		}
+	})
+}
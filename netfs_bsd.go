@@ -0,0 +1,34 @@
+//go:build darwin || freebsd
+
+/*
+
+netfs_bsd.go
+
+Find the network file systems with getfsstat(2), using MNT_NOWAIT so that none of them are accessed
+
+*/
+
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// listNetworkMounts - the mount point and type of each network file system in networkFileSystems
+func listNetworkMounts() map[string]string {
+	mounts := make(map[string]string)
+	count, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil || 0 == count {
+		return mounts
+	}
+	all := make([]unix.Statfs_t, count)
+	if count, err = unix.Getfsstat(all, unix.MNT_NOWAIT); err != nil {
+		return mounts
+	}
+	for _, fs := range all[:count] {
+		if fsType := unix.ByteSliceToString(fs.Fstypename[:]); networkFileSystems[fsType] {
+			mounts[unix.ByteSliceToString(fs.Mntonname[:])] = fsType
+		}
+	}
+	return mounts
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+/*
+
+netfs_linux.go
+
+Find the network file systems from /proc/self/mountinfo, which is read without accessing any of them
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unescapeMountInfo - decode the octal escapes of a mount point, such as \040 for a space
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if '\\' == s[i] && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// listNetworkMounts - the mount point and type of each network file system in networkFileSystems
+func listNetworkMounts() map[string]string {
+	mounts := make(map[string]string)
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return mounts
+	}
+	defer file.Close()
+
+	// such as: 36 35 98:0 / /mnt/share rw,noatime master:1 - nfs4 server:/export rw
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i := 6; i+1 < len(fields); i++ {
+			if "-" == fields[i] {
+				if fsType := fields[i+1]; networkFileSystems[fsType] {
+					mounts[unescapeMountInfo(fields[4])] = fsType
+				}
+				break
+			}
+		}
+	}
+	return mounts
+}
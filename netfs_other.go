@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+/*
+
+netfs_other.go
+
+The network file systems can not be found on this OS, so only UNC paths are limited by -nettimeout
+
+*/
+
+package main
+
+// listNetworkMounts - none are found on this OS
+func listNetworkMounts() map[string]string {
+	return map[string]string{}
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+/*
+
+netfs_windows.go
+
+Find the network drives, which are mapped to a share such as \\server\share; UNC paths are found by mountOf()
+
+*/
+
+package main
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// listNetworkMounts - the root directory of each network drive, such as Z:\
+func listNetworkMounts() map[string]string {
+	mounts := make(map[string]string)
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		return mounts
+	}
+	for i := 0; i < 26; i++ {
+		if drives&(1<<uint(i)) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		if p, err := windows.UTF16PtrFromString(root); err == nil && windows.DRIVE_REMOTE == windows.GetDriveType(p) {
+			mounts[root] = "smb"
+		}
+	}
+	return mounts
+}
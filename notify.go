@@ -0,0 +1,128 @@
+/*
+
+notify.go
+
+POST the results to a webhook, used by the -notify cmd line option
+
+Example: fstat -notify https://hooks.slack.com/services/... -r -dn 1d dirs.txt
+
+A notification is only sent when something was found: the entries of a regular scan, the changes found by
+-diff, -baseline, -cmp or -deltas, or each -watch event. The payload includes a "text" field so that it is
+accepted as is by Slack and Teams incoming webhooks; the other fields are for everything else.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// notifyTimeout - how long to wait for the webhook to respond
+const notifyTimeout = 10 * time.Second
+
+// notifyMaxItems - the most entries or changes in one payload; Truncated is set when there were more
+const notifyMaxItems = 1000
+
+// notifyPayload - the JSON object sent to the webhook; only the fields for the kind of notification are set
+type notifyPayload struct {
+	Text      string       `json:"text"`
+	Args      []string     `json:"args"`
+	Summary   *ScanSummary `json:"summary,omitempty"`
+	Entries   []FileStat   `json:"entries,omitempty"`
+	Changes   []FileChange `json:"changes,omitempty"`
+	Event     *watchEvent  `json:"event,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+/*
+PostNotification sends payload to the webhook as JSON
+Failures are reported to STDERR, but do not stop the program
+
+Args:
+    url: the webhook (-notify cmd line option)
+
+    payload: Args is filled in with the cmd line options
+
+    quiet: when set, failures are not reported to STDERR (cmd line option: -q)
+*/
+func PostNotification(url string, payload notifyPayload, quiet bool) {
+	payload.Args = os.Args[1:]
+	body, err := json.Marshal(payload)
+	if err == nil {
+		var resp *http.Response
+		client := http.Client{Timeout: notifyTimeout}
+		resp, err = client.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = fmt.Errorf("%s", resp.Status)
+			}
+		}
+	}
+	if err != nil && !quiet {
+		logError("unable to notify: %s", err)
+	}
+}
+
+/*
+NotifyEntries sends the entries of a scan and their summary, when any entries are shown
+
+Args:
+    url: the webhook (-notify cmd line option)
+
+    allEntries: the entries of the scan
+
+    onlyTypes: only include these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    quiet: when set, failures are not reported to STDERR (cmd line option: -q)
+*/
+func NotifyEntries(url string, allEntries []FileStat, onlyTypes string, quiet bool) {
+	var shown []FileStat
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		shown = append(shown, e)
+	}
+	if 0 == len(shown) {
+		return
+	}
+
+	summary := ComputeScanSummary(shown, "", 0, "")
+	payload := notifyPayload{Text: fmt.Sprintf("fstat found %d entries, including %d files totaling %s", len(shown), summary.Files, humanSize(summary.TotalSize)), Summary: &summary, Entries: shown}
+	if len(shown) > notifyMaxItems {
+		payload.Entries, payload.Truncated = shown[:notifyMaxItems], true
+	}
+	PostNotification(url, payload, quiet)
+}
+
+/*
+NotifyChanges sends the changes found by -diff, -baseline or -cmp, when there are any
+
+Args:
+    url: the webhook (-notify cmd line option)
+
+    changes: created by ComputeChanges or CompareDirs
+
+    onlyTypes: only include these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    quiet: when set, failures are not reported to STDERR (cmd line option: -q)
+*/
+func NotifyChanges(url string, changes []FileChange, onlyTypes string, quiet bool) {
+	shown := shownChanges(changes, onlyTypes)
+	if 0 == len(shown) {
+		return
+	}
+
+	payload := notifyPayload{Text: fmt.Sprintf("fstat found %d changes", len(shown)), Changes: shown}
+	if len(shown) > notifyMaxItems {
+		payload.Changes, payload.Truncated = shown[:notifyMaxItems], true
+	}
+	PostNotification(url, payload, quiet)
+}
@@ -0,0 +1,109 @@
+/*
+
+oldnew.go
+
+Report the oldest and newest entries, used by the -oldnew cmd line option and the oldnew subcommand
+
+Example: fstat -oldnew 10 -r dirs.txt
+Example: fstat oldnew 10 -type f -r dirs.txt
+
+The oldest entries are listed first, oldest at the top, followed by the newest entries, newest at the top, so
+that stale data and the most recent writes can both be seen in one run. When there are fewer than twice as many
+entries, an entry can be in both lists.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// oldNewReport - the oldest and newest entries
+type oldNewReport struct {
+	Oldest []FileStat `json:"oldest"`
+	Newest []FileStat `json:"newest"`
+}
+
+/*
+FindOldNew returns the oldest and newest entries by modified time
+
+Args:
+    allEntries: the entries to search
+
+    count: the number of entries in each list
+
+    onlyTypes: only include these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the report; ties are listed by name
+*/
+func FindOldNew(allEntries []FileStat, count int, onlyTypes string) oldNewReport {
+	var entries []FileStat
+	for _, e := range allEntries {
+		if typeIncluded(onlyTypes, e.FileType) {
+			entries = append(entries, e)
+		}
+	}
+
+	var report oldNewReport
+	sortModTime(entries, true)
+	report.Oldest = append([]FileStat{}, entries[:min(count, len(entries))]...)
+	sortModTime(entries, false)
+	report.Newest = append([]FileStat{}, entries[:min(count, len(entries))]...)
+	return report
+}
+
+/*
+RenderOldNew outputs the oldest and newest entries
+
+Args:
+    report: created by FindOldNew()
+
+    options: how the entries are output (-c, -m, -dec, -oc, -ot, -oj and -csvdelim cmd line options)
+
+Returns:
+    the number of entries output
+*/
+func RenderOldNew(report oldNewReport, options renderOptions) int {
+	count := len(report.Oldest) + len(report.Newest)
+	if options.outputJSON {
+		j, _ := json.MarshalIndent(report, "", "    ")
+		fmt.Println(string(j))
+		return count
+	}
+
+	now := time.Now()
+	var allRows [][]string
+	addRows := func(list string, entries []FileStat) {
+		for i, e := range entries {
+			allRows = append(allRows, []string{list, strconv.Itoa(i + 1), e.ModTime.Format(options.timeLayout), formatAge(e.ModTime, now), formatSize(e.Size, options.addCommas, options.convertToMiB, options.useSI), e.FileType, e.FullName})
+		}
+	}
+	addRows("oldest", report.Oldest)
+	addRows("newest", report.Newest)
+	header := []string{"List", "Rank", "Mod Time", "Age", "Size", "Type", "Name"}
+
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, nil)
+		return count
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, nil)
+		return count
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+		oldest, newest := report.Oldest[0].ModTime, report.Newest[0].ModTime
+		fmt.Printf("oldest: %s  newest: %s  span: %s\n", oldest.Format(options.timeLayout), newest.Format(options.timeLayout), strings.TrimSuffix(formatAge(oldest, newest), " ago"))
+	}
+	return count
+}
@@ -0,0 +1,101 @@
+/*
+
+parquet.go
+
+Write all entries to a Parquet file, used by the -opq cmd line option
+
+Example: duckdb -c "SELECT type, COUNT(*), SUM(size) FROM 'files.parquet' GROUP BY type"
+
+*/
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow - the Parquet schema; time stamps are stored as UTC with nanosecond precision
+// directory and basename are null unless -splitname is used
+type parquetRow struct {
+	Path       string    `parquet:"path"`
+	Size       int64     `parquet:"size"`
+	ModTime    time.Time `parquet:"modtime"`
+	Type       string    `parquet:"type,dict"`
+	Mode       string    `parquet:"mode,dict"`
+	Owner      string    `parquet:"owner,dict"`
+	Group      string    `parquet:"grp,dict"`
+	Allocated  int64     `parquet:"allocated"`
+	Sparse     bool      `parquet:"sparse"`
+	Device     int64     `parquet:"device"`
+	AccessTime time.Time `parquet:"accesstime"`
+	Directory  *string   `parquet:"directory,optional"`
+	Basename   *string   `parquet:"basename,optional"`
+}
+
+/*
+writeParquet writes the entries to a new Parquet file using snappy compression
+
+Args:
+    fname: the Parquet file, which is overwritten when it already exists
+
+    allEntries: a slice of all files
+
+    onlyTypes: only write these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the number of entries written, or an error
+*/
+func writeParquet(fname string, allEntries []FileStat, onlyTypes string) (int64, error) {
+	file, err := os.Create(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w := parquet.NewGenericWriter[parquetRow](file, parquet.Compression(&parquet.Snappy))
+	var allRows []parquetRow
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		row := parquetRow{Path: e.FullName, Size: e.Size, ModTime: e.ModTime.UTC(), Type: e.FileType, Mode: e.Mode, Owner: e.Owner, Group: e.Group, Allocated: e.Allocated, Sparse: e.Sparse, Device: int64(e.Device), AccessTime: e.Access.UTC()}
+		if len(e.Basename) > 0 {
+			dir, base := e.Directory, e.Basename
+			row.Directory, row.Basename = &dir, &base
+		}
+		allRows = append(allRows, row)
+	}
+	if _, err = w.Write(allRows); err != nil {
+		return 0, err
+	}
+	if err = w.Close(); err != nil {
+		return 0, err
+	}
+	return int64(len(allRows)), file.Close()
+}
+
+/*
+RenderParquet writes all entries to a Parquet file and reports the number of entries to STDERR
+
+Args:
+    fname: the Parquet file (-opq cmd line option)
+
+    allEntries: a slice of all files
+
+    quiet: when set, the number of entries is not reported (cmd line option: -q)
+
+    onlyTypes: only write these types of entries, such as "FL"; empty for all types (-type cmd line option)
+*/
+func RenderParquet(fname string, allEntries []FileStat, quiet bool, onlyTypes string) {
+	count, err := writeParquet(fname, allEntries, onlyTypes)
+	if err != nil {
+		logError("%s: %s", fname, err)
+		os.Exit(1)
+	}
+	if !quiet {
+		logInfo("Wrote %d entries to %s", count, fname)
+	}
+}
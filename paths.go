@@ -0,0 +1,202 @@
+/*
+
+paths.go
+
+Normalize listed file names, used by the -clean and -cleanabs cmd line options
+
+Change how file names are matched and output, used by the -abs, -relto and -unc cmd line options
+
+Example: fstat -relto /srv/www -r /srv/www/site1
+This outputs site1/index.html instead of /srv/www/site1/index.html, however the file list was created.
+
+Example: fstat -abs -unc -r Z:\reports
+On Windows, this outputs \\server\share\reports\... when Z: is a mapped network drive.
+
+Windows volume names, such as C: or the \\server\share of a UNC path, are never treated as part of a
+directory or file name, such as by the -ed dot check or when shortening names with -shorten dirs.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+/*
+CleanAllFilenames normalizes each file name and then removes duplicates, keeping the first occurrence
+Separators are cleaned and . and .. are resolved lexically; symbolic links are not resolved
+
+Args:
+    allFilenames: the listed file names
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    makeAbsolute: when set, also convert each file name to an absolute path (-cleanabs cmd line option)
+
+Returns:
+    the normalized file names without duplicates
+*/
+func CleanAllFilenames(allFilenames []string, quiet bool, makeAbsolute bool) []string {
+	var allCleaned []string
+	nextName := cleanNames(sliceNames(allFilenames), quiet, makeAbsolute)
+	for name, ok := nextName(); ok; name, ok = nextName() {
+		allCleaned = append(allCleaned, name)
+	}
+	return allCleaned
+}
+
+/*
+cleanNames is the same as CleanAllFilenames(), for names which are returned one at a time, such as by walkNames()
+
+Args:
+    nextName: returns each file name in turn, and false when there are no more
+
+    quiet, makeAbsolute: see CleanAllFilenames()
+
+Returns:
+    a function which returns each normalized name in turn, skipping duplicates; it returns false after the last name
+*/
+func cleanNames(nextName func() (string, bool), quiet bool, makeAbsolute bool) func() (string, bool) {
+	seen := make(map[string]bool)
+	return func() (string, bool) {
+		for fname, ok := nextName(); ok; fname, ok = nextName() {
+			name := filepath.Clean(fname)
+			if isRemote(fname) {
+				name = fname
+			} else if makeAbsolute {
+				abs, err := filepath.Abs(name)
+				if err != nil {
+					if !quiet {
+						logError("%s", err)
+					}
+				} else {
+					name = abs
+				}
+			}
+
+			// Windows file names are not case sensitive
+			key := name
+			if "windows" == runtime.GOOS {
+				key = strings.ToLower(key)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			return name, true
+		}
+		return "", false
+	}
+}
+
+// isDotPath - true when the base name of fname or any of its directories starts with a dot, ignoring the volume name
+func isDotPath(fname string) bool {
+	rest := fname[len(filepath.VolumeName(fname)):]
+	if 0 == len(rest) {
+		return false
+	}
+	if "." == filepath.Base(rest)[:1] {
+		return true
+	}
+	return strings.Contains(rest, string(os.PathSeparator)+".") || (os.PathSeparator != '/' && strings.Contains(rest, "/."))
+}
+
+// uncPrefixes - the prefixes of Windows long and device paths, which are part of the volume name
+var uncPrefixes = []string{`\\?\UNC\`, `\\?\`, `\\.\`}
+
+// volumeHasWildcard - true when the volume name of pattern has a wildcard, such as \\server*\share, which can not be expanded
+func volumeHasWildcard(pattern string) bool {
+	vol := filepath.VolumeName(pattern)
+	for _, prefix := range uncPrefixes {
+		if strings.HasPrefix(vol, prefix) {
+			vol = vol[len(prefix):]
+			break
+		}
+	}
+	return strings.ContainsAny(vol, "*?[")
+}
+
+// uncResolver - returns a function which replaces a mapped drive at the start of a file name with the UNC path of its share
+func uncResolver() func(fname string) string {
+	targets := make(map[string]string)
+	return func(fname string) string {
+		vol := filepath.VolumeName(fname)
+		if 2 != len(vol) || ':' != vol[1] {
+			return fname
+		}
+		drive := strings.ToUpper(vol)
+		target, ok := targets[drive]
+		if !ok {
+			target, _ = mappedDriveTarget(drive)
+			targets[drive] = target
+		}
+		if 0 == len(target) {
+			return fname
+		}
+		return target + fname[len(vol):]
+	}
+}
+
+/*
+pathRenamer returns a function which changes each file name before it is matched and output
+
+Args:
+    absolute: when set, each file name is converted to an absolute path (-abs cmd line option)
+
+    relativeTo: when set, each file name is converted to a path relative to this directory (-relto cmd line option);
+                names on another volume, which can not be made relative, are output as absolute paths
+
+    resolveUNC: when set, a mapped network drive is replaced by the UNC path of its share, such as \\server\share
+                instead of Z:; relative file names are not changed unless absolute is also set (-unc cmd line option)
+
+Returns:
+    the function to pass to GetFileInfo(); nil when none of the options is used
+
+    an error when relativeTo is not a directory
+*/
+func pathRenamer(absolute bool, relativeTo string, resolveUNC bool) (func(fname string) string, error) {
+	if !absolute && 0 == len(relativeTo) && !resolveUNC {
+		return nil, nil
+	}
+	toUNC := func(fname string) string {
+		return fname
+	}
+	if resolveUNC {
+		toUNC = uncResolver()
+	}
+	if !absolute && 0 == len(relativeTo) {
+		return toUNC, nil
+	}
+	toAbsolute := func(fname string) string {
+		if abs, err := filepath.Abs(fname); err == nil {
+			return toUNC(abs)
+		}
+		return fname
+	}
+	if 0 == len(relativeTo) {
+		return toAbsolute, nil
+	}
+
+	base, err := filepath.Abs(relativeTo)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(base); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", relativeTo)
+	}
+	base = toUNC(base)
+	return func(fname string) string {
+		abs := toAbsolute(fname)
+		if rel, err := filepath.Rel(base, abs); err == nil {
+			return rel
+		}
+		return abs
+	}, nil
+}
@@ -0,0 +1,580 @@
+/*
+
+pdf.go
+
+Find the number of pages of PDF files, used by the -pages cmd line option
+
+Example: fstat -pages -t -r /srv/contracts
+Example: fstat -where 'pages > 100' -r /srv/contracts
+
+The page count is the /Count of the page tree, which is found the same way a PDF reader finds it: the startxref
+offset at the end of the file leads to the cross-reference table or stream, whose trailer names the document
+catalog, which names the page tree. Both classic cross-reference tables and the compressed cross-reference and
+object streams of PDF 1.5 are read, along with any earlier sections from incremental updates. Only these few
+objects are read, so that large documents are not read in full. Encrypted object streams can not be read.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pdfWhitespace - the white-space characters of PDF syntax
+const pdfWhitespace = "\x00\t\n\f\r "
+
+// pdfDelimiters - the characters which end a PDF name or number, other than white-space
+const pdfDelimiters = "()<>[]{}/%"
+
+// pdfTailSize - the number of bytes at the end of a PDF file which are searched for startxref
+const pdfTailSize = 2048
+
+// the most bytes read for a single object, and the largest decompressed stream
+const (
+	pdfMaxObject = 64 * 1024
+	pdfMaxStream = 64 * 1024 * 1024
+)
+
+// errPDFPages - the page count can not be found
+var errPDFPages = errors.New("page count not found in PDF")
+
+// isPDF - true when fname ends with .pdf, ignoring case
+func isPDF(fname string) bool {
+	return strings.HasSuffix(strings.ToLower(fname), ".pdf")
+}
+
+// pdfFile - the location of each object, found from the cross-reference sections
+type pdfFile struct {
+	r    io.ReaderAt
+	size int64
+	// the file offset of each uncompressed object
+	offsets map[int64]int64
+	// the object stream and index within it of each compressed object
+	compressed map[int64][2]int64
+	root       int64
+}
+
+// pdfValue - the text following key in a dictionary, such as "3 0 R>>" for /Pages; empty when there is no such key
+func pdfValue(dict string, key string) string {
+	for i := 0; i < len(dict); {
+		j := strings.Index(dict[i:], key)
+		if j < 0 {
+			return ""
+		}
+		end := i + j + len(key)
+		if end < len(dict) && !strings.ContainsRune(pdfWhitespace+pdfDelimiters, rune(dict[end])) {
+			i = end
+			continue
+		}
+		return strings.TrimLeft(dict[end:], pdfWhitespace)
+	}
+	return ""
+}
+
+// pdfInts - up to count unsigned integers separated by white-space at the start of value, and the text after them
+func pdfInts(value string, count int) ([]int64, string) {
+	var ints []int64
+	for len(ints) < count {
+		value = strings.TrimLeft(value, pdfWhitespace)
+		digits := 0
+		for digits < len(value) && value[digits] >= '0' && value[digits] <= '9' {
+			digits++
+		}
+		if 0 == digits {
+			break
+		}
+		n, err := strconv.ParseInt(value[:digits], 10, 64)
+		if err != nil {
+			break
+		}
+		ints = append(ints, n)
+		value = value[digits:]
+	}
+	return ints, strings.TrimLeft(value, pdfWhitespace)
+}
+
+// pdfRef - the object number of an indirect reference such as "3 0 R" for key
+func pdfRef(dict string, key string) (int64, bool) {
+	ints, rest := pdfInts(pdfValue(dict, key), 2)
+	if 2 == len(ints) && strings.HasPrefix(rest, "R") {
+		return ints[0], true
+	}
+	return 0, false
+}
+
+// pdfInt - the integer value of key, which is not an indirect reference
+func pdfInt(dict string, key string) (int64, bool) {
+	if _, ok := pdfRef(dict, key); ok {
+		return 0, false
+	}
+	ints, _ := pdfInts(pdfValue(dict, key), 1)
+	if 1 == len(ints) {
+		return ints[0], true
+	}
+	return 0, false
+}
+
+// pdfArray - the integers of an array value of key, such as "[1 2 1]"
+func pdfArray(dict string, key string) []int64 {
+	value := pdfValue(dict, key)
+	if !strings.HasPrefix(value, "[") {
+		return nil
+	}
+	end := strings.IndexByte(value, ']')
+	if end < 0 {
+		return nil
+	}
+	ints, _ := pdfInts(value[1:end], end)
+	return ints
+}
+
+/*
+pdfDict finds the dictionary at the start of text, skipping over strings which may contain << or >>
+
+Args:
+    text: starts with <<, after any white-space
+
+Returns:
+    the dictionary, including << and >>, and the text after it; or false when the dictionary does not end within text
+*/
+func pdfDict(text string) (string, string, bool) {
+	text = strings.TrimLeft(text, pdfWhitespace)
+	if !strings.HasPrefix(text, "<<") {
+		return "", text, false
+	}
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "<<"):
+			depth++
+			i++
+		case strings.HasPrefix(text[i:], ">>"):
+			depth--
+			i++
+			if 0 == depth {
+				return text[:i+1], text[i+1:], true
+			}
+		case '(' == text[i]: // a literal string, which can hold balanced or escaped parentheses
+			for nested := 0; i < len(text); i++ {
+				if '\\' == text[i] {
+					i++
+				} else if '(' == text[i] {
+					nested++
+				} else if ')' == text[i] {
+					if nested--; 0 == nested {
+						break
+					}
+				}
+			}
+		case '<' == text[i]: // a hexadecimal string
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				i += end
+			}
+		}
+	}
+	return "", text, false
+}
+
+/*
+readObject reads an uncompressed object
+
+Args:
+    off: the file offset of the object, starting with "num gen obj"
+
+Returns:
+    the dictionary of the object, or its text up to endobj when it is not a dictionary;
+    and the file offset of its stream data, or -1 when it has no stream
+*/
+func (p *pdfFile) readObject(off int64) (string, int64, error) {
+	if off < 0 || off >= p.size {
+		return "", -1, errPDFPages
+	}
+	buf := make([]byte, min(p.size-off, pdfMaxObject))
+	n, err := p.r.ReadAt(buf, off)
+	if err != nil && io.EOF != err {
+		return "", -1, err
+	}
+	text := string(buf[:n])
+	start := strings.Index(text, "obj")
+	if start < 0 || start > 32 {
+		return "", -1, errPDFPages
+	}
+	body := strings.TrimLeft(text[start+3:], pdfWhitespace)
+	if !strings.HasPrefix(body, "<<") {
+		if end := strings.Index(body, "endobj"); end >= 0 {
+			body = body[:end]
+		}
+		return body, -1, nil
+	}
+	dict, rest, ok := pdfDict(body)
+	if !ok {
+		return "", -1, errPDFPages
+	}
+	rest = strings.TrimLeft(rest, pdfWhitespace)
+	if !strings.HasPrefix(rest, "stream") {
+		return dict, -1, nil
+	}
+	rest = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(rest, "stream"), "\r"), "\n")
+	return dict, off + int64(len(text)-len(rest)), nil
+}
+
+// pngUnpredict - reverse the PNG predictors of a decompressed stream, with one predictor byte before each row
+func pngUnpredict(data []byte, columns int) ([]byte, error) {
+	if columns <= 0 {
+		return nil, errPDFPages
+	}
+	var out []byte
+	prior := make([]byte, columns)
+	for len(data) > 0 {
+		if len(data) < columns+1 {
+			return nil, errPDFPages
+		}
+		filter, row := data[0], append([]byte{}, data[1:columns+1]...)
+		data = data[columns+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i > 0 {
+				left, upLeft = row[i-1], prior[i-1]
+			}
+			up := prior[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				p := int(left) + int(up) - int(upLeft)
+				pa, pb, pc := abs(p-int(left)), abs(p-int(up)), abs(p-int(upLeft))
+				switch {
+				case pa <= pb && pa <= pc:
+					row[i] += left
+				case pb <= pc:
+					row[i] += up
+				default:
+					row[i] += upLeft
+				}
+			}
+		}
+		out = append(out, row...)
+		prior = row
+	}
+	return out, nil
+}
+
+// abs - the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// stream - the decoded data of the stream of an object; only FlateDecode is supported
+func (p *pdfFile) stream(dict string, start int64) ([]byte, error) {
+	if start < 0 {
+		return nil, errPDFPages
+	}
+	length, ok := pdfInt(dict, "/Length")
+	if num, isRef := pdfRef(dict, "/Length"); isRef {
+		text, err := p.object(num)
+		if err != nil {
+			return nil, err
+		}
+		ints, _ := pdfInts(text, 1)
+		length, ok = 0, 1 == len(ints)
+		if ok {
+			length = ints[0]
+		}
+	}
+	if !ok || length > p.size-start {
+		return nil, errPDFPages
+	}
+	data := make([]byte, length)
+	if _, err := p.r.ReadAt(data, start); err != nil {
+		return nil, err
+	}
+
+	filter := pdfValue(dict, "/Filter")
+	if 0 == len(filter) {
+		return data, nil
+	}
+	if !strings.HasPrefix(strings.TrimLeft(filter, "["+pdfWhitespace), "/FlateDecode") {
+		return nil, errPDFPages
+	}
+	z, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	data, err = io.ReadAll(io.LimitReader(z, pdfMaxStream))
+	if err != nil && io.ErrUnexpectedEOF != err {
+		return nil, err
+	}
+
+	if predictor, _ := pdfInt(dict, "/Predictor"); predictor >= 10 {
+		columns, ok := pdfInt(dict, "/Columns")
+		if !ok {
+			columns = 1
+		}
+		return pngUnpredict(data, int(columns))
+	}
+	return data, nil
+}
+
+// object - the dictionary or other text of an object, which can be within an object stream
+func (p *pdfFile) object(num int64) (string, error) {
+	if off, ok := p.offsets[num]; ok {
+		text, _, err := p.readObject(off)
+		return text, err
+	}
+	location, ok := p.compressed[num]
+	if !ok {
+		return "", errPDFPages
+	}
+	off, ok := p.offsets[location[0]]
+	if !ok {
+		return "", errPDFPages
+	}
+	dict, start, err := p.readObject(off)
+	if err != nil {
+		return "", err
+	}
+	data, err := p.stream(dict, start)
+	if err != nil {
+		return "", err
+	}
+
+	// the stream starts with pairs of object numbers and offsets, which are relative to /First
+	count, _ := pdfInt(dict, "/N")
+	first, ok := pdfInt(dict, "/First")
+	if !ok || first > int64(len(data)) || location[1] >= count {
+		return "", errPDFPages
+	}
+	pairs, _ := pdfInts(string(data[:first]), int(2*count))
+	i := 2 * location[1]
+	if i+1 >= int64(len(pairs)) || pairs[i] != num || first+pairs[i+1] > int64(len(data)) {
+		return "", errPDFPages
+	}
+	text := string(data[first+pairs[i+1]:])
+	if dict, _, ok := pdfDict(text); ok {
+		return dict, nil
+	}
+	return text, nil
+}
+
+// addObject - record the location of an object, unless a newer cross-reference section already has
+func (p *pdfFile) addObject(num int64, off int64, objectStream int64, index int64, inStream bool) {
+	if _, ok := p.offsets[num]; ok {
+		return
+	}
+	if _, ok := p.compressed[num]; ok {
+		return
+	}
+	if inStream {
+		p.compressed[num] = [2]int64{objectStream, index}
+	} else {
+		p.offsets[num] = off
+	}
+}
+
+// readXrefTable - read a classic cross-reference table at off, and return its trailer dictionary
+func (p *pdfFile) readXrefTable(off int64) (string, error) {
+	scanner := bufio.NewScanner(io.NewSectionReader(p.r, off, p.size-off))
+	scanner.Split(bufio.ScanWords)
+	words := func(count int) []string {
+		var w []string
+		for len(w) < count && scanner.Scan() {
+			w = append(w, scanner.Text())
+		}
+		return w
+	}
+	if w := words(1); 1 != len(w) || "xref" != w[0] {
+		return "", errPDFPages
+	}
+
+	for {
+		w := words(1)
+		if 0 == len(w) {
+			return "", errPDFPages
+		}
+		if strings.HasPrefix(w[0], "trailer") {
+			// the dictionary is rebuilt from its words, which keeps its keys and values apart
+			trailer := strings.TrimPrefix(w[0], "trailer")
+			for len(trailer) < pdfMaxObject && scanner.Scan() {
+				trailer += " " + scanner.Text()
+				if dict, _, ok := pdfDict(trailer); ok {
+					return dict, nil
+				}
+			}
+			return "", errPDFPages
+		}
+		start, err := strconv.ParseInt(w[0], 10, 64)
+		if err != nil {
+			return "", errPDFPages
+		}
+		w = words(1)
+		if 0 == len(w) {
+			return "", errPDFPages
+		}
+		count, err := strconv.ParseInt(w[0], 10, 64)
+		if err != nil {
+			return "", errPDFPages
+		}
+		for i := int64(0); i < count; i++ {
+			entry := words(3)
+			if 3 != len(entry) {
+				return "", errPDFPages
+			}
+			if "n" == entry[2] {
+				if objOff, err := strconv.ParseInt(entry[0], 10, 64); nil == err {
+					p.addObject(start+i, objOff, 0, 0, false)
+				}
+			}
+		}
+	}
+}
+
+// readXrefStream - read a cross-reference stream at off, and return its dictionary, which is also the trailer
+func (p *pdfFile) readXrefStream(off int64) (string, error) {
+	dict, start, err := p.readObject(off)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(pdfValue(dict, "/Type"), "/XRef") {
+		return "", errPDFPages
+	}
+	data, err := p.stream(dict, start)
+	if err != nil {
+		return "", err
+	}
+
+	widths := pdfArray(dict, "/W")
+	if 3 != len(widths) {
+		return "", errPDFPages
+	}
+	index := pdfArray(dict, "/Index")
+	if 0 == len(index) {
+		size, _ := pdfInt(dict, "/Size")
+		index = []int64{0, size}
+	}
+	field := func(b []byte, width int64, missing int64) int64 {
+		if 0 == width {
+			return missing
+		}
+		var n int64
+		for _, c := range b[:width] {
+			n = n<<8 | int64(c)
+		}
+		return n
+	}
+	entrySize := widths[0] + widths[1] + widths[2]
+	if entrySize <= 0 || widths[0] < 0 || widths[1] < 0 || widths[2] < 0 {
+		return "", errPDFPages
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		for num := index[i]; num < index[i]+index[i+1] && int64(len(data)) >= entrySize; num++ {
+			entry := data[:entrySize]
+			data = data[entrySize:]
+			second, third := field(entry[widths[0]:], widths[1], 0), field(entry[widths[0]+widths[1]:], widths[2], 0)
+			switch field(entry, widths[0], 1) {
+			case 1:
+				p.addObject(num, second, 0, 0, false)
+			case 2:
+				p.addObject(num, 0, second, third, true)
+			}
+		}
+	}
+	return dict, nil
+}
+
+/*
+pdfPages returns the number of pages of a PDF file
+
+Args:
+    fname: the file name
+
+Returns:
+    the number of pages; or an error when the file can not be read or the page tree can not be found
+*/
+func pdfPages(fname string) (int64, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	f, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	p := &pdfFile{r: file, size: f.Size(), offsets: make(map[int64]int64), compressed: make(map[int64][2]int64)}
+
+	tailSize := min(p.size, pdfTailSize)
+	tail := make([]byte, tailSize)
+	if _, err := file.ReadAt(tail, p.size-tailSize); err != nil {
+		return 0, err
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return 0, errPDFPages
+	}
+	ints, _ := pdfInts(string(tail[i+len("startxref"):]), 1)
+	if 0 == len(ints) {
+		return 0, errPDFPages
+	}
+
+	// the newest section is read first; /Prev leads to the sections of earlier versions of the file
+	seen := make(map[int64]bool)
+	for next := []int64{ints[0]}; len(next) > 0; {
+		off := next[0]
+		next = next[1:]
+		if seen[off] {
+			continue
+		}
+		seen[off] = true
+
+		var trailer string
+		if trailer, err = p.readXrefTable(off); err != nil {
+			if trailer, err = p.readXrefStream(off); err != nil {
+				return 0, err
+			}
+		}
+		if root, ok := pdfRef(trailer, "/Root"); ok && 0 == p.root {
+			p.root = root
+		}
+		// a hybrid file also has a cross-reference stream for PDF 1.5 readers, which comes before /Prev
+		if stream, ok := pdfInt(trailer, "/XRefStm"); ok {
+			next = append(next, stream)
+		}
+		if prev, ok := pdfInt(trailer, "/Prev"); ok {
+			next = append(next, prev)
+		}
+	}
+
+	catalog, err := p.object(p.root)
+	if err != nil {
+		return 0, err
+	}
+	pagesRef, ok := pdfRef(catalog, "/Pages")
+	if !ok {
+		return 0, errPDFPages
+	}
+	pages, err := p.object(pagesRef)
+	if err != nil {
+		return 0, err
+	}
+	count, ok := pdfInt(pages, "/Count")
+	if !ok {
+		return 0, errPDFPages
+	}
+	return count, nil
+}
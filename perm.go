@@ -0,0 +1,173 @@
+/*
+
+perm.go
+
+Permission bit filtering modeled on find(1), used by the -perm cmd line option
+
+  -perm 644     mode is exactly 644
+  -perm -220    all of the 220 bits are set
+  -perm /022    any of the 022 bits are set
+  -perm !/111   none of the 111 bits are set; '!' negates any of the above
+
+The mode can also be given symbolically, such as: u+x,o+w or /o=w
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// how permission bits are compared; used by -perm
+const (
+	permExact = iota
+	permAll
+	permAny
+)
+
+// permFilter - a parsed -perm value
+type permFilter struct {
+	bits   uint32
+	match  int
+	negate bool
+}
+
+// unixMode - convert an os.FileMode into traditional Unix permission bits, including setuid, setgid and sticky
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+/*
+parseSymbolicMode converts a symbolic mode such as "u+x,go=r" into permission bits
+Since -perm only tests for bits, '+' and '=' are treated the same
+
+Args:
+    value: comma separated clauses of who (ugoa), an operator (+ or =), and permissions (rwxst)
+
+Returns:
+    the permission bits, or an error when value is not a valid symbolic mode
+*/
+func parseSymbolicMode(value string) (uint32, error) {
+	var bits uint32
+	for _, clause := range strings.Split(value, ",") {
+		i := strings.IndexAny(clause, "+=")
+		if i < 0 {
+			return 0, fmt.Errorf("missing '+' or '=' in: %s", clause)
+		}
+		who := clause[:i]
+		if len(who) == 0 {
+			who = "a"
+		}
+
+		var whoMask uint32
+		for _, w := range who {
+			switch w {
+			case 'u':
+				whoMask |= 04700
+			case 'g':
+				whoMask |= 02070
+			case 'o':
+				whoMask |= 01007
+			case 'a':
+				whoMask |= 07777
+			default:
+				return 0, fmt.Errorf("invalid who '%c' in: %s", w, clause)
+			}
+		}
+
+		var permMask uint32
+		for _, p := range clause[i+1:] {
+			switch p {
+			case 'r':
+				permMask |= 0444
+			case 'w':
+				permMask |= 0222
+			case 'x':
+				permMask |= 0111
+			case 's':
+				permMask |= 06000
+			case 't':
+				permMask |= 01000
+			default:
+				return 0, fmt.Errorf("invalid permission '%c' in: %s", p, clause)
+			}
+		}
+		bits |= whoMask & permMask
+	}
+	return bits, nil
+}
+
+/*
+parsePermFilter converts a -perm value into a permFilter
+
+Args:
+    value: an octal or symbolic mode, optionally prefixed with '!' and then '-' or '/'
+
+Returns:
+    the filter, or an error when value is not valid
+*/
+func parsePermFilter(value string) (permFilter, error) {
+	var filter permFilter
+	if strings.HasPrefix(value, "!") {
+		filter.negate = true
+		value = value[1:]
+	}
+	switch {
+	case strings.HasPrefix(value, "-"):
+		filter.match = permAll
+		value = value[1:]
+	case strings.HasPrefix(value, "/"):
+		filter.match = permAny
+		value = value[1:]
+	default:
+		filter.match = permExact
+	}
+	if len(value) == 0 {
+		return filter, fmt.Errorf("missing mode")
+	}
+
+	if value[0] >= '0' && value[0] <= '9' {
+		n, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid octal mode: %s", value)
+		}
+		if n > 07777 {
+			return filter, fmt.Errorf("mode is larger than 7777: %s", value)
+		}
+		filter.bits = uint32(n)
+		return filter, nil
+	}
+	bits, err := parseSymbolicMode(value)
+	filter.bits = bits
+	return filter, err
+}
+
+// matches - true when the given file mode satisfies the filter
+func (p permFilter) matches(mode os.FileMode) bool {
+	bits := unixMode(mode)
+	var result bool
+	switch p.match {
+	case permAll:
+		result = bits&p.bits == p.bits
+	case permAny:
+		// as with find(1), a mode of 0 matches every file
+		result = p.bits == 0 || bits&p.bits != 0
+	default:
+		result = bits == p.bits
+	}
+	return result != p.negate
+}
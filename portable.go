@@ -0,0 +1,121 @@
+/*
+
+portable.go
+
+Find file names which can not be used on Windows, used by the -portable cmd line option and the portable subcommand
+
+Example: fstat -portable -r release/
+Example: fstat portable -oc -r /srv/share > renames.csv
+
+The base name of each entry is checked for:
+    the characters < > : " | ? * and control characters, which Windows does not allow
+    a reserved device name, such as CON, NUL, COM1 or LPT1, with or without an extension, such as nul.txt
+    a trailing space or dot, which Windows removes
+
+The output is the same as -badnames, see badnames.go. In the suggested name, each character which is not allowed
+is replaced with _, _ is added after a reserved device name, and trailing spaces and dots are removed.
+
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// windowsIllegal - the printable characters that Windows does not allow in a file name
+const windowsIllegal = `<>:"|?*`
+
+// windowsReserved - device names that can not be used as a file name on Windows, even with an extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+/*
+checkPortable finds the reasons a single base name can not be used on Windows
+
+Args:
+    base: the base name of a file
+
+Returns:
+    a description of each problem, empty when there are none
+
+    the suggested name, which is base when there are no problems
+*/
+func checkPortable(base string) ([]string, string) {
+	if "." == base || ".." == base || "/" == base {
+		return nil, base
+	}
+
+	var problems []string
+	var illegal, control bool
+	suggested := strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(windowsIllegal, r):
+			illegal = true
+			return '_'
+		case unicode.IsControl(r):
+			control = true
+			return '_'
+		}
+		return r
+	}, base)
+	if illegal {
+		problems = append(problems, "illegal character")
+	}
+	if control {
+		problems = append(problems, "control character")
+	}
+
+	if strings.HasSuffix(base, " ") {
+		problems = append(problems, "trailing space")
+	}
+	if strings.HasSuffix(base, ".") {
+		problems = append(problems, "trailing dot")
+	}
+	suggested = strings.TrimRight(suggested, " .")
+
+	// Windows ignores the extension and any spaces before it, so that "nul .txt" is also the NUL device
+	stem, ext, _ := strings.Cut(suggested, ".")
+	if windowsReserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		problems = append(problems, "reserved name")
+		suggested = strings.TrimRight(stem, " ") + "_"
+		if len(ext) > 0 {
+			suggested += "." + ext
+		}
+	}
+
+	if 0 == len(suggested) {
+		suggested = "_"
+	}
+	return problems, suggested
+}
+
+/*
+FindNonPortable returns the entries whose base names can not be used on Windows
+
+Args:
+    allEntries: the entries to check
+
+    onlyTypes: only check these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    each entry with at least one problem, in the same order as allEntries
+*/
+func FindNonPortable(allEntries []FileStat, onlyTypes string) []badName {
+	var bad []badName
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		problems, suggested := checkPortable(filepath.Base(e.FullName))
+		if len(problems) > 0 {
+			bad = append(bad, badName{FullName: e.FullName, Escaped: escapeName(e.FullName), Problems: problems, Suggested: suggested})
+		}
+	}
+	return bad
+}
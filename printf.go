@@ -0,0 +1,182 @@
+/*
+
+printf.go
+
+Custom per-entry output modeled on find -printf, used by the -printf cmd line option
+
+Example: fstat -printf '%s %TY-%Tm-%Td %p\n' list.txt
+
+Directives, which can include a width and flags such as %-40p or %10s:
+    %p  full file name         %f  base name              %h  directory
+    %s  size in bytes          %k  1 KiB blocks allocated %b  512 byte blocks allocated
+    %m  octal permissions      %M  mode, such as -rw-r--r--
+    %u  owner                  %g  group                  %y  type: f, d, l or ?
+    %t  modification time      %Tk modification time with strftime directive k, such as %TY; %T@ is seconds since the epoch
+    %a  last access time       %Ak last access time with strftime directive k
+    %%  a literal %
+Backslash escapes such as \t and \n are expanded; unlike -format, no newline is added after each entry
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// printfTimeLayout - the layout of %t and %a, which matches ctime(3)
+const printfTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// printfPart - either literal text or a single directive of a -printf format
+type printfPart struct {
+	literal  string
+	width    string
+	verb     byte
+	timeVerb byte
+}
+
+/*
+compilePrintf splits a -printf format into literal text and directives
+
+Args:
+    format: the format, see the top of this file for the allowed directives
+
+Returns:
+    a slice of printfPart, or an error when format contains an unknown directive
+*/
+func compilePrintf(format string) ([]printfPart, error) {
+	var allParts []printfPart
+	format = formatEscapes.Replace(format)
+
+	var literal strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			literal.WriteByte(format[i])
+			continue
+		}
+		start := i + 1
+		for i++; i < len(format) && strings.IndexByte("-+ #0123456789.", format[i]) >= 0; i++ {
+		}
+		if i == len(format) {
+			return nil, fmt.Errorf("incomplete directive at the end of: %s", format)
+		}
+		part := printfPart{width: format[start:i], verb: format[i]}
+		switch part.verb {
+		case '%':
+			literal.WriteByte('%')
+			continue
+		case 'p', 'f', 'h', 's', 'k', 'b', 'm', 'M', 'u', 'g', 'y', 't', 'a':
+		case 'T', 'A':
+			i++
+			if i == len(format) {
+				return nil, fmt.Errorf("missing time directive after: %%%c", part.verb)
+			}
+			part.timeVerb = format[i]
+			if _, ok := strftimeDirectives[part.timeVerb]; !ok && '@' != part.timeVerb && '+' != part.timeVerb {
+				return nil, fmt.Errorf("unknown time directive: %%%c%c", part.verb, part.timeVerb)
+			}
+		default:
+			return nil, fmt.Errorf("unknown directive: %%%c", part.verb)
+		}
+		if literal.Len() > 0 {
+			allParts = append(allParts, printfPart{literal: literal.String()})
+			literal.Reset()
+		}
+		allParts = append(allParts, part)
+	}
+	if literal.Len() > 0 {
+		allParts = append(allParts, printfPart{literal: literal.String()})
+	}
+	return allParts, nil
+}
+
+// printfUses - true when one of the directives is the given verb, such as 'u'
+func printfUses(allParts []printfPart, verb byte) bool {
+	for _, part := range allParts {
+		if part.verb == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// printfTime - render t for %t, %T, %a and %A
+func printfTime(t time.Time, timeVerb byte) string {
+	switch timeVerb {
+	case 0:
+		return t.Format(printfTimeLayout)
+	case '@':
+		return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+	case '+':
+		return t.Format("2006-01-02+15:04:05.000000000")
+	}
+	return t.Format(strftimeDirectives[timeVerb])
+}
+
+// render - expand all directives for a single entry
+func (part printfPart) render(e FileStat) string {
+	if 0 == part.verb {
+		return part.literal
+	}
+
+	var value interface{}
+	verb := "s"
+	switch part.verb {
+	case 'p':
+		value = e.FullName
+	case 'f':
+		value = filepath.Base(e.FullName)
+	case 'h':
+		value = filepath.Dir(e.FullName)
+	case 's':
+		value, verb = e.Size, "d"
+	case 'k':
+		value, verb = (e.Allocated+1023)/1024, "d"
+	case 'b':
+		value, verb = (e.Allocated+511)/512, "d"
+	case 'm':
+		value, verb = e.Perm, "o"
+	case 'M':
+		value = e.Mode
+	case 'u':
+		value = e.Owner
+	case 'g':
+		value = e.Group
+	case 'y':
+		value = strings.ToLower(e.FileType)
+	case 't', 'T':
+		value = printfTime(e.ModTime, part.timeVerb)
+	case 'a', 'A':
+		value = printfTime(e.Access, part.timeVerb)
+	}
+	return fmt.Sprintf("%"+part.width+verb, value)
+}
+
+/*
+RenderPrintf outputs each entry using a format compiled by compilePrintf
+
+Args:
+    allEntries: a slice of all files, already sorted
+
+    allParts: the compiled format (-printf cmd line option)
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+*/
+func RenderPrintf(allEntries []FileStat, allParts []printfPart, onlyTypes string) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		for _, part := range allParts {
+			w.WriteString(part.render(e))
+		}
+	}
+}
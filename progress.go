@@ -0,0 +1,262 @@
+/*
+
+progress.go
+
+Count the files examined by GetFileInfo(), used by the -progress, -stats and -errors cmd line options
+
+-progress periodically reports the counts to STDERR. When STDERR is a terminal, a single line
+is redrawn every second; otherwise a new line is written every 10 seconds.
+
+-stats reports the counts, along with the slowest calls to os.Lstat(), once all output is done.
+
+-errors keeps the name and error of each file that could not be examined, so that they are part of the output.
+
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressBarWidth - the number of characters in the progress bar, which is only shown when the number of files is known
+const progressBarWidth = 30
+
+// slowestStatCount - the number of the slowest os.Lstat() calls reported by -stats
+const slowestStatCount = 10
+
+// statTiming - the time taken by os.Lstat() for a single file
+type statTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// scanError - a file that could not be examined, kept by the -errors cmd line option
+type scanError struct {
+	FullName string `json:"name"`
+	Error    string `json:"error"`
+}
+
+// scanCounters - updated by GetFileInfo(), which may be read by another goroutine
+type scanCounters struct {
+	examined   atomic.Int64
+	failed     atomic.Int64
+	timedOut   atomic.Int64
+	bytes      atomic.Int64
+	start      time.Time
+	timeStats  bool
+	keepErrors bool
+	mu         sync.Mutex
+	slowest    []statTiming
+	errors     []scanError
+}
+
+// scanProgress - reports scanCounters from a background goroutine
+type scanProgress struct {
+	counters *scanCounters
+	total    int64
+	terminal bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+/*
+newScanCounters returns counters which start timing now
+
+Args:
+    timeStats: when set, time each call to os.Lstat() so that the slowest can be reported (-stats cmd line option)
+
+    keepErrors: when set, keep each file that could not be examined (-errors cmd line option)
+*/
+func newScanCounters(timeStats bool, keepErrors bool) *scanCounters {
+	return &scanCounters{start: time.Now(), timeStats: timeStats, keepErrors: keepErrors}
+}
+
+// addExamined - count one more file name; c can be nil
+func (c *scanCounters) addExamined() {
+	if c != nil {
+		c.examined.Add(1)
+	}
+}
+
+// addFailed - count one more file that could not be examined; c can be nil
+func (c *scanCounters) addFailed(fname string, err error) {
+	if c != nil {
+		c.failed.Add(1)
+		if errors.Is(err, errNetTimeout) {
+			c.timedOut.Add(1)
+		}
+		if c.keepErrors {
+			c.mu.Lock()
+			c.errors = append(c.errors, scanError{FullName: fname, Error: err.Error()})
+			c.mu.Unlock()
+		}
+	}
+}
+
+// failedCount - the number of files that could not be examined; c can be nil
+func (c *scanCounters) failedCount() int64 {
+	if nil == c {
+		return 0
+	}
+	return c.failed.Load()
+}
+
+// allErrors - the files that could not be examined, which is never nil so that the -errors output is always included
+func (c *scanCounters) allErrors() []scanError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]scanError{}, c.errors...)
+}
+
+// addBytes - add the size of an included file; c can be nil
+func (c *scanCounters) addBytes(size int64) {
+	if c != nil {
+		c.bytes.Add(size)
+	}
+}
+
+// timed - true when calls to os.Lstat() should be timed; c can be nil
+func (c *scanCounters) timed() bool {
+	return c != nil && c.timeStats
+}
+
+// addStatTime - keep the slowest calls to os.Lstat(), slowest first
+func (c *scanCounters) addStatTime(fname string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.slowest) == slowestStatCount && duration <= c.slowest[len(c.slowest)-1].duration {
+		return
+	}
+	i := sort.Search(len(c.slowest), func(i int) bool { return c.slowest[i].duration < duration })
+	c.slowest = append(c.slowest, statTiming{})
+	copy(c.slowest[i+1:], c.slowest[i:])
+	c.slowest[i] = statTiming{name: fname, duration: duration}
+	if len(c.slowest) > slowestStatCount {
+		c.slowest = c.slowest[:slowestStatCount]
+	}
+}
+
+/*
+RenderRunStats outputs the counters to STDERR, so that they do not mix with the regular output (-stats cmd line option)
+
+Args:
+    c: the counters passed to GetFileInfo()
+*/
+func RenderRunStats(c *scanCounters) {
+	elapsed := time.Since(c.start)
+	examined := c.examined.Load()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(examined) / elapsed.Seconds()
+	}
+
+	fmt.Fprintf(os.Stderr, "\n")
+	precision := time.Millisecond
+	if elapsed < time.Second {
+		precision = time.Microsecond
+	}
+	fmt.Fprintf(os.Stderr, "wall time     : %s\n", elapsed.Round(precision))
+	fmt.Fprintf(os.Stderr, "files examined: %s\n", RenderInteger("#,###.", examined))
+	fmt.Fprintf(os.Stderr, "files/second  : %s\n", RenderFloat("#,###.", rate))
+	fmt.Fprintf(os.Stderr, "bytes summed  : %s\n", RenderInteger("#,###.", c.bytes.Load()))
+	fmt.Fprintf(os.Stderr, "errors        : %s\n", RenderInteger("#,###.", c.failed.Load()))
+	if timedOut := c.timedOut.Load(); timedOut > 0 {
+		fmt.Fprintf(os.Stderr, "timed out     : %s, on network file systems which did not respond, see -nettimeout\n", RenderInteger("#,###.", timedOut))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.slowest) > 0 {
+		fmt.Fprintf(os.Stderr, "slowest stat calls:\n")
+		for _, t := range c.slowest {
+			fmt.Fprintf(os.Stderr, "    %12s  %s\n", t.duration.Round(time.Microsecond), t.name)
+		}
+	}
+}
+
+/*
+startProgress starts reporting progress to STDERR until stop() is called
+
+Args:
+    counters: updated by GetFileInfo()
+
+    total: the number of files to examine, or 0 when unknown such as with -stream or -r
+
+Returns:
+    the progress
+*/
+func startProgress(counters *scanCounters, total int64) *scanProgress {
+	p := &scanProgress{counters: counters, total: total, done: make(chan struct{})}
+	interval := 10 * time.Second
+	if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		p.terminal = true
+		interval = time.Second
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.report(false)
+			case <-p.done:
+				p.report(true)
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// stop - output the final counts and stop reporting; p can be nil
+func (p *scanProgress) stop() {
+	if p != nil {
+		close(p.done)
+		p.wg.Wait()
+	}
+}
+
+/*
+report outputs one progress line, such as:
+[#########---------------------]  30%  examined: 12,345 of 41,150  errors: 2  rate: 4,115/s  elapsed: 3s
+
+Args:
+    final: when set, the line is always ended with a newline
+*/
+func (p *scanProgress) report(final bool) {
+	examined := p.counters.examined.Load()
+	elapsed := time.Since(p.counters.start)
+	var rate int64
+	if elapsed >= time.Second/10 {
+		rate = int64(float64(examined) / elapsed.Seconds())
+	}
+
+	var line string
+	if p.total > 0 {
+		filled := int(examined * progressBarWidth / p.total)
+		line = fmt.Sprintf("[%s%s] %3d%%  examined: %s of %s", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), examined*100/p.total, RenderInteger("#,###.", examined), RenderInteger("#,###.", p.total))
+	} else {
+		line = fmt.Sprintf("examined: %s", RenderInteger("#,###.", examined))
+	}
+	line += fmt.Sprintf("  errors: %s  rate: %s/s  elapsed: %s", RenderInteger("#,###.", p.counters.failed.Load()), RenderInteger("#,###.", rate), elapsed.Round(time.Second))
+
+	switch {
+	case p.terminal && final:
+		fmt.Fprintf(os.Stderr, "\r%s\033[K\n", line)
+	case p.terminal:
+		fmt.Fprintf(os.Stderr, "\r%s\033[K", line)
+	default:
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
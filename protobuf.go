@@ -0,0 +1,159 @@
+/*
+
+protobuf.go
+
+Protocol Buffers output, used by the -opb cmd line option
+
+The messages are described by proto/fstat.proto and are encoded here with protowire,
+so that no generated code needs to be kept in sync with FileStat
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// appendTimestamp - encode t as a google.protobuf.Timestamp field
+func appendTimestamp(b []byte, num protowire.Number, t time.Time) []byte {
+	var ts []byte
+	if seconds := t.Unix(); seconds != 0 {
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(seconds))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nanos))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+// appendString - encode a string field, omitting empty values as proto3 does
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// appendVarint - encode an integer or bool field, omitting zero values as proto3 does
+func appendVarint(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+// appendDouble - encode a double field, omitting zero values as proto3 does
+func appendDouble(b []byte, num protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+// appendMessage - encode an embedded message field
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// encodeFileStat - encode e as a fstat.FileStat message
+func encodeFileStat(e FileStat) []byte {
+	var b []byte
+	b = appendString(b, 1, e.FullName)
+	b = appendVarint(b, 2, uint64(e.Size))
+	b = appendTimestamp(b, 3, e.ModTime)
+	b = appendString(b, 4, e.FileType)
+	b = appendString(b, 5, e.Owner)
+	b = appendString(b, 6, e.Group)
+	b = appendString(b, 7, e.Mode)
+	b = appendVarint(b, 8, uint64(e.Allocated))
+	b = appendVarint(b, 9, protowire.EncodeBool(e.Sparse))
+	b = appendVarint(b, 10, e.Device)
+	b = appendTimestamp(b, 11, e.Access)
+	b = appendString(b, 12, e.Directory)
+	b = appendString(b, 13, e.Basename)
+	return b
+}
+
+// encodeSummary - encode s as a fstat.Summary message
+func encodeSummary(s Summary) []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(s.TotalSize))
+	b = appendVarint(b, 2, uint64(s.FileCount))
+	b = appendVarint(b, 3, uint64(s.DirCount))
+	b = appendVarint(b, 4, uint64(s.LinkCount))
+	b = appendDouble(b, 5, s.AverageSize)
+	b = appendDouble(b, 6, s.AverageFilesPerDir)
+	if s.Extended != nil {
+		var x []byte
+		x = appendVarint(x, 1, uint64(s.Extended.Median))
+		x = appendVarint(x, 2, uint64(s.Extended.P90))
+		x = appendVarint(x, 3, uint64(s.Extended.P99))
+		x = appendDouble(x, 4, s.Extended.StdDev)
+		x = appendMessage(x, 5, encodeFileStat(s.Extended.Smallest))
+		x = appendMessage(x, 6, encodeFileStat(s.Extended.Largest))
+		b = appendMessage(b, 7, x)
+	}
+	b = appendVarint(b, 8, uint64(s.PipeCount))
+	b = appendVarint(b, 9, uint64(s.SocketCount))
+	b = appendVarint(b, 10, uint64(s.CharDeviceCount))
+	b = appendVarint(b, 11, uint64(s.BlockDeviceCount))
+	b = appendVarint(b, 12, uint64(s.OtherCount))
+	b = appendVarint(b, 13, uint64(s.FailedCount))
+	b = appendVarint(b, 14, uint64(s.MediaCount))
+	b = appendDouble(b, 15, s.TotalDuration)
+	b = appendDouble(b, 16, s.AverageDuration)
+	b = appendVarint(b, 17, uint64(s.DocumentCount))
+	b = appendVarint(b, 18, uint64(s.TotalPages))
+	return b
+}
+
+// writeRecord - write a fstat.Record holding message in field num, preceded by its length
+func writeRecord(w *bufio.Writer, num protowire.Number, message []byte) {
+	record := appendMessage(nil, num, message)
+	w.Write(protowire.AppendVarint(nil, uint64(len(record))))
+	w.Write(record)
+}
+
+/*
+RenderProtobuf outputs a length delimited fstat.Record for each entry, see proto/fstat.proto
+
+Args:
+    allEntries: a slice of all files, already sorted
+
+    includeTotals: when set, append a Record holding the Summary (-t cmd line option)
+
+    extendedTotals: when set, include the size distribution in the Summary (-tx cmd line option)
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    failed: the number of files that could not be examined, included in the Summary
+*/
+func RenderProtobuf(allEntries []FileStat, includeTotals bool, extendedTotals bool, onlyTypes string, failed int64) {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	var allIncluded []FileStat
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		allIncluded = append(allIncluded, e)
+		writeRecord(w, 1, encodeFileStat(e))
+	}
+	if includeTotals {
+		writeRecord(w, 2, encodeSummary(ComputeSummary(allIncluded, extendedTotals, failed)))
+	}
+}
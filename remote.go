@@ -0,0 +1,217 @@
+/*
+
+remote.go
+
+Examine files on remote servers, for names such as sftp://user@host/path and webdavs://user@host/path
+
+Example: echo sftp://deploy@web1/var/www | fstat -r -dn 1d
+Example: fstat -f 'webdavs://alice@dav.example.com/Shared/Reports' -r -ext .xlsx -do 20200101
+
+The part of a name before :// selects the protocol, see sftp.go and webdav.go, and each server is connected to
+once. All remote names are examined, and walked with -r, -mindepth and -maxdepth, before any output, so that all
+filters, sorting and output options apply to them; wildcards are not expanded. The content of remote files is
+not read, so -entropy, -class, -imgsize and similar options leave them empty. With -du, the size of a remote
+directory is only the total of its files when it was walked with -r and without -maxdepth.
+
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// remoteLister - a connection to a remote server, where each path is the slash separated path of a remote name
+type remoteLister interface {
+	// lstat - the stat results of a path, without following a final symbolic link
+	lstat(p string) (statRecord, error)
+	// readDir - the names and stat results of the entries of a directory, sorted by name, without . and ..
+	readDir(p string) ([]string, map[string]statRecord, error)
+	// close - end the connection
+	close()
+}
+
+// remoteDialers - connect to the server of a remote name, for each protocol which can be used before ://; once ctx
+// is done, the connection ends any request which is waiting for the server
+var remoteDialers = map[string]func(ctx context.Context, u *url.URL) (remoteLister, error){
+	"sftp":    dialSFTP,
+	"webdav":  dialWebDAV,
+	"webdavs": dialWebDAV,
+}
+
+// remoteScheme - the lower-cased protocol of a remote name, such as "sftp"; empty for a local name
+func remoteScheme(fname string) string {
+	i := strings.Index(fname, "://")
+	if i <= 0 {
+		return ""
+	}
+	scheme := strings.ToLower(fname[:i])
+	if _, ok := remoteDialers[scheme]; !ok {
+		return ""
+	}
+	return scheme
+}
+
+// isRemote - true when fname starts with the protocol of one of remoteDialers, such as sftp://
+func isRemote(fname string) bool {
+	return len(remoteScheme(fname)) > 0
+}
+
+// hasRemote - true when any of allFilenames is a remote name
+func hasRemote(allFilenames []string) bool {
+	for _, fname := range allFilenames {
+		if isRemote(fname) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutPassword - fname without any password given as user:password@host, so that it is never output
+func withoutPassword(fname string) string {
+	start := strings.Index(fname, "://") + len("://")
+	end := len(fname)
+	if slash := strings.Index(fname[start:], "/"); slash >= 0 {
+		end = start + slash
+	}
+	at := strings.LastIndex(fname[start:end], "@")
+	if at < 0 {
+		return fname
+	}
+	at += start
+	if colon := strings.Index(fname[start:at], ":"); colon >= 0 {
+		return fname[:start+colon] + fname[at:]
+	}
+	return fname
+}
+
+/*
+ExpandRemoteFilenames examines each remote name in allFilenames, and with -r, everything beneath it
+Each server is only connected to once, and errors are reported to STDERR unless quiet is set
+
+Args:
+    ctx: once done, no more remote names are examined, see cancel.go (-timeout cmd line option)
+
+    allFilenames: the listed file names, where local names are kept as they are
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    walk: when set, replace each remote directory with the directory and everything beneath it (-r cmd line option)
+
+    minDepth: only include entries at this depth or deeper (-mindepth cmd line option)
+
+    maxDepth: when not negative, do not descend below this depth (-maxdepth cmd line option)
+
+    ignorePatterns: directories matching any of these patterns are not descended into, see ignore.go
+
+Returns:
+    the expanded slice of file names, with the remote names which can not be examined removed, and any password
+    removed from the rest; and the stat results of each remote name, which GetFileInfo() uses instead of os.Lstat()
+*/
+func ExpandRemoteFilenames(ctx context.Context, allFilenames []string, quiet bool, walk bool, minDepth int, maxDepth int, ignorePatterns []string) ([]string, map[string]statRecord) {
+	var allExpanded []string
+	records := make(map[string]statRecord)
+	clients := make(map[string]remoteLister)
+	defer func() {
+		for _, c := range clients {
+			if c != nil {
+				c.close()
+			}
+		}
+	}()
+	report := func(fname string, err error) {
+		if !quiet && nil == ctx.Err() {
+			logError("%s: %s", fname, err)
+		}
+	}
+	if !walk {
+		minDepth, maxDepth = 0, 0
+	}
+
+	// visit adds a remote entry, then the entries beneath it, and returns the total size of its regular files
+	// and whether all of them were found, the same as diskUsage()
+	var visit func(c remoteLister, fname string, p string, rec statRecord, depth int) (int64, bool)
+	visit = func(c remoteLister, fname string, p string, rec statRecord, depth int) (int64, bool) {
+		if depth >= minDepth {
+			allExpanded = append(allExpanded, fname)
+		}
+		records[fname] = rec
+		if rec.Mode.IsRegular() {
+			return rec.Size, true
+		}
+		if !rec.Mode.IsDir() {
+			return 0, true
+		}
+		if (maxDepth >= 0 && depth >= maxDepth) || ctx.Err() != nil {
+			return 0, false
+		}
+		names, children, err := c.readDir(p)
+		if err != nil {
+			report(fname, err)
+			return 0, false
+		}
+		var usage int64
+		complete := true
+		for _, name := range names {
+			child, childName, childPath := children[name], strings.TrimSuffix(fname, "/")+"/"+name, path.Join(p, name)
+			if child.Mode.IsDir() && len(ignorePatterns) > 0 && isIgnored(filepath.FromSlash(childPath), ignorePatterns) {
+				logVerbose("skipped %s: %s: matches a pattern", childName, ignoreFileName)
+				continue
+			}
+			size, all := visit(c, childName, childPath, child, depth+1)
+			usage += size
+			complete = complete && all
+		}
+		if complete {
+			rec.DirUsage, rec.HaveDirUsage = usage, true
+			records[fname] = rec
+		}
+		return usage, complete
+	}
+
+	for _, fname := range allFilenames {
+		if ctx.Err() != nil {
+			break
+		}
+		if !isRemote(fname) {
+			allExpanded = append(allExpanded, fname)
+			continue
+		}
+		u, err := url.Parse(fname)
+		if err != nil || 0 == len(u.Host) {
+			report(withoutPassword(fname), fmt.Errorf("invalid remote name, such as: %s://user@host/path", remoteScheme(fname)))
+			continue
+		}
+		fname = withoutPassword(fname)
+
+		// a failed connection is only reported once for each server
+		server := strings.ToLower(u.Scheme) + "://" + u.Host
+		if u.User != nil {
+			server = strings.ToLower(u.Scheme) + "://" + u.User.Username() + "@" + u.Host
+		}
+		c, connected := clients[server]
+		if !connected {
+			logVerbose("connecting to %s", server)
+			if c, err = remoteDialers[strings.ToLower(u.Scheme)](ctx, u); err != nil {
+				report(server, err)
+			}
+			clients[server] = c
+		}
+		if nil == c {
+			continue
+		}
+
+		rec, err := c.lstat(u.Path)
+		if err != nil {
+			report(fname, err)
+			continue
+		}
+		visit(c, fname, u.Path, rec, 0)
+	}
+	return allExpanded, records
+}
@@ -0,0 +1,130 @@
+/*
+
+samename.go
+
+Find files with the same base name in different directories, used by the -samename cmd line option and the
+samename subcommand
+
+Example: fstat -samename -r dirs.txt
+Example: fstat samename -oj -r /etc /usr/local/etc
+
+Unlike -dups, the contents are not read, so this also finds copies of the same configuration or binary file which
+have since been changed. Each group is shown with its number of copies, the smallest and largest size, and the
+most recently modified copy. The groups with the most copies are output first.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// nameGroup - files with the same base name
+type nameGroup struct {
+	Basename string     `json:"basename"`
+	Smallest int64      `json:"smallest"`
+	Largest  int64      `json:"largest"`
+	Newest   FileStat   `json:"newest"`
+	Files    []FileStat `json:"files"`
+}
+
+/*
+FindSameNames returns the groups of files which have the same base name
+
+Args:
+    allEntries: the entries to compare; only files are compared
+
+Returns:
+    each group of two or more files, sorted by the number of files descending, and then by base name;
+    the files of each group are sorted by name
+*/
+func FindSameNames(allEntries []FileStat) []nameGroup {
+	byName := make(map[string][]FileStat)
+	for _, e := range allEntries {
+		if "F" == e.FileType {
+			base := filepath.Base(e.FullName)
+			byName[base] = append(byName[base], e)
+		}
+	}
+
+	var groups []nameGroup
+	for base, files := range byName {
+		if len(files) < 2 {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].FullName < files[j].FullName })
+		g := nameGroup{Basename: base, Smallest: files[0].Size, Largest: files[0].Size, Newest: files[0], Files: files}
+		for _, f := range files[1:] {
+			g.Smallest = min(g.Smallest, f.Size)
+			g.Largest = max(g.Largest, f.Size)
+			if f.ModTime.After(g.Newest.ModTime) {
+				g.Newest = f
+			}
+		}
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Files) != len(groups[j].Files) {
+			return len(groups[i].Files) > len(groups[j].Files)
+		}
+		return groups[i].Basename < groups[j].Basename
+	})
+	return groups
+}
+
+/*
+RenderSameNames outputs each group of files with the same base name
+
+Args:
+    groups: created by FindSameNames()
+
+    options: how the groups are output (-c, -m, -dec, -oc, -ot, -oj and -csvdelim cmd line options)
+
+Returns:
+    the number of files in all groups
+*/
+func RenderSameNames(groups []nameGroup, options renderOptions) int {
+	var copies int
+	for _, g := range groups {
+		copies += len(g.Files)
+	}
+
+	if options.outputJSON {
+		if nil == groups {
+			groups = []nameGroup{}
+		}
+		j, _ := json.MarshalIndent(groups, "", "    ")
+		fmt.Println(string(j))
+		return copies
+	}
+
+	var allRows [][]string
+	for _, g := range groups {
+		allRows = append(allRows, []string{g.Basename, strconv.Itoa(len(g.Files)), formatSize(g.Smallest, options.addCommas, options.convertToMiB, options.useSI), formatSize(g.Largest, options.addCommas, options.convertToMiB, options.useSI), g.Newest.ModTime.Format(options.timeLayout), g.Newest.FullName})
+	}
+	header := []string{"Basename", "Copies", "Smallest", "Largest", "Newest", "Newest Copy"}
+
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, nil)
+		return copies
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, nil)
+		return copies
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("groups: %d  files: %d\n", len(groups), copies)
+	return copies
+}
@@ -0,0 +1,505 @@
+/*
+
+sftp.go
+
+Examine files on remote servers over SFTP, for names such as sftp://user@host/path, see remote.go
+
+Example: echo sftp://deploy@web1/var/www | fstat -r -dn 1d
+Example: fstat -f 'sftp://backup.example.com:2222/~/archive' -r -ss
+
+Each server is connected to once, with an SSH agent from SSH_AUTH_SOCK or a key without a passphrase from
+~/.ssh/id_ed25519, id_ecdsa or id_rsa. The server must already be listed in ~/.ssh/known_hosts. The user
+defaults to the current user and the port to 22. A path starting with /~ is relative to the remote home
+directory. The owner and group are the numeric uid and gid, which -user and -grp only match when there is no
+local account with that id.
+
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpDialTimeout - the longest time to wait for each server to connect
+const sftpDialTimeout = 30 * time.Second
+
+// sftpKeyFiles - the private keys which are tried, in order, from ~/.ssh
+var sftpKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// SFTP version 3 packet types, see draft-ietf-secsh-filexfer-02
+const (
+	sshFxpInit    = 1
+	sshFxpVersion = 2
+	sshFxpClose   = 4
+	sshFxpLstat   = 7
+	sshFxpOpendir = 11
+	sshFxpReaddir = 12
+	sshFxpStatus  = 101
+	sshFxpHandle  = 102
+	sshFxpName    = 104
+	sshFxpAttrs   = 105
+)
+
+// SFTP version 3 attribute flags and status codes
+const (
+	sshFileXferAttrSize        = 0x00000001
+	sshFileXferAttrUIDGID      = 0x00000002
+	sshFileXferAttrPermissions = 0x00000004
+	sshFileXferAttrACModTime   = 0x00000008
+	sshFileXferAttrExtended    = 0x80000000
+	sshFxEOF                   = 1
+)
+
+// errSFTPPacket - returned when a reply from the server can not be parsed
+var errSFTPPacket = errors.New("invalid SFTP reply")
+
+// sftpReply - the fields of a reply from the server, which are read in order; err is set once any field is missing
+type sftpReply struct {
+	data []byte
+	err  error
+}
+
+func (r *sftpReply) uint32() uint32 {
+	if len(r.data) < 4 {
+		r.err = errSFTPPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *sftpReply) uint64() uint64 {
+	if len(r.data) < 8 {
+		r.err = errSFTPPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *sftpReply) string() string {
+	n := r.uint32()
+	if uint32(len(r.data)) < n {
+		r.err = errSFTPPacket
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+// attrs - read a file attributes structure, and convert it to stat results
+func (r *sftpReply) attrs() statRecord {
+	var rec statRecord
+	flags := r.uint32()
+	if flags&sshFileXferAttrSize != 0 {
+		rec.Size = int64(r.uint64())
+		rec.Allocated = rec.Size
+	}
+	if flags&sshFileXferAttrUIDGID != 0 {
+		rec.Owner = strconv.FormatUint(uint64(r.uint32()), 10)
+		rec.Group = strconv.FormatUint(uint64(r.uint32()), 10)
+		rec.HaveOwner, rec.HaveGroup = true, true
+	}
+	if flags&sshFileXferAttrPermissions != 0 {
+		rec.Mode = remoteMode(r.uint32())
+	}
+	if flags&sshFileXferAttrACModTime != 0 {
+		rec.Access = time.Unix(int64(r.uint32()), 0)
+		rec.ModTime = time.Unix(int64(r.uint32()), 0)
+	}
+	if flags&sshFileXferAttrExtended != 0 {
+		for n := r.uint32(); n > 0 && nil == r.err; n-- {
+			r.string()
+			r.string()
+		}
+	}
+	return rec
+}
+
+// remoteMode - convert the st_mode bits of a remote file to an os.FileMode
+func remoteMode(bits uint32) os.FileMode {
+	mode := os.FileMode(bits & 0777)
+	switch bits & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	case 0010000:
+		mode |= os.ModeNamedPipe
+	case 0140000:
+		mode |= os.ModeSocket
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 0060000:
+		mode |= os.ModeDevice
+	}
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// sftpClient - a single SFTP session, with one request outstanding at a time
+type sftpClient struct {
+	conn   *ssh.Client
+	stdin  io.WriteCloser
+	stdout io.Reader
+	id     uint32
+	// no longer close the connection when the context of dialSFTP() is done
+	stop func() bool
+}
+
+/*
+sshAuthMethods returns the ways of authenticating to a server
+
+Returns:
+    the signers of the SSH agent, when SSH_AUTH_SOCK is set, then the private keys without a passphrase in ~/.ssh
+*/
+func sshAuthMethods() []ssh.AuthMethod {
+	var methods []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); len(sock) > 0 {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		} else {
+			logVerbose("unable to use the SSH agent: %s", err)
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return methods
+	}
+	var signers []ssh.Signer
+	for _, name := range sftpKeyFiles {
+		key, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			logVerbose("unable to use %s: %s", name, err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	return methods
+}
+
+/*
+dialSFTP connects to a server and starts its SFTP subsystem
+
+Args:
+    ctx: once done, the connection is closed, which ends any request waiting for a reply
+
+    u: the remote name, where only the user, host and port are used
+
+Returns:
+    the client; or an error when the server can not be connected to or authenticated with
+*/
+func dialSFTP(ctx context.Context, u *url.URL) (remoteLister, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify host keys: %w", err)
+	}
+
+	username := u.User.Username()
+	if 0 == len(username) {
+		current, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		// Windows user names include the domain, such as DOMAIN\user
+		username = current.Username[strings.LastIndex(current.Username, "\\")+1:]
+	}
+	port := u.Port()
+	if 0 == len(port) {
+		port = "22"
+	}
+
+	// the same as ssh.Dial(), but the connection can also be ended by ctx
+	address := net.JoinHostPort(u.Hostname(), port)
+	dialer := net.Dialer{Timeout: sftpDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	// only ask for the types of host key in known_hosts, since otherwise the server may send another type of key,
+	// which would fail to verify
+	config := &ssh.ClientConfig{User: username, Auth: sshAuthMethods(), HostKeyCallback: hostKeys, HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeys, address, netConn.RemoteAddr()), Timeout: sftpDialTimeout}
+	sshConn, channels, requests, err := ssh.NewClientConn(netConn, address, config)
+	if err != nil {
+		stop()
+		netConn.Close()
+		return nil, err
+	}
+	conn := ssh.NewClient(sshConn, channels, requests)
+	session, err := conn.NewSession()
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	c := &sftpClient{conn: conn, stop: stop}
+	if c.stdin, err = session.StdinPipe(); err == nil {
+		c.stdout, err = session.StdoutPipe()
+	}
+	if err == nil {
+		err = session.RequestSubsystem("sftp")
+	}
+	if err == nil {
+		err = c.init()
+	}
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+/*
+knownHostKeyAlgorithms returns the host key algorithms for the keys of a server in known_hosts
+
+Args:
+    hostKeys: the callback which checks the keys in known_hosts
+
+    address: the host and port of the server
+
+    remote: the address the server was connected to
+
+Returns:
+    the algorithms, sorted; or nil when known_hosts has no key for the server, so that the default algorithms are
+    used and hostKeys reports the unknown server
+*/
+func knownHostKeyAlgorithms(hostKeys ssh.HostKeyCallback, address string, remote net.Addr) []string {
+	// a key which is never in known_hosts makes hostKeys list the keys which are
+	var keyErr *knownhosts.KeyError
+	if err := hostKeys(address, remote, unknownHostKey{}); !errors.As(err, &keyErr) || 0 == len(keyErr.Want) {
+		return nil
+	}
+	var algorithms []string
+	for _, known := range keyErr.Want {
+		keyType := known.Key.Type()
+		if ssh.KeyAlgoRSA == keyType {
+			// an RSA key can also be used with the SHA-2 signature algorithms
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algorithms = append(algorithms, keyType)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
+// unknownHostKey - a host key which does not match any key in known_hosts, see knownHostKeyAlgorithms()
+type unknownHostKey struct{}
+
+func (unknownHostKey) Type() string {
+	return "unknown"
+}
+
+func (unknownHostKey) Marshal() []byte {
+	return []byte("unknown")
+}
+
+func (unknownHostKey) Verify(data []byte, sig *ssh.Signature) error {
+	return errors.New("unknown host key")
+}
+
+// send - write a single packet of kind, followed by each field, which is a uint32 or a string
+func (c *sftpClient) send(kind byte, fields ...interface{}) error {
+	packet := []byte{0, 0, 0, 0, kind}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			packet = binary.BigEndian.AppendUint32(packet, v)
+		case string:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		}
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	_, err := c.stdin.Write(packet)
+	return err
+}
+
+// receive - read a single packet, returning its kind and the fields after it
+func (c *sftpClient) receive() (byte, *sftpReply, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.stdout, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > 1<<24 {
+		return 0, nil, errSFTPPacket
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(c.stdout, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], &sftpReply{data: data}, nil
+}
+
+// init - agree on version 3 of the protocol
+func (c *sftpClient) init() error {
+	if err := c.send(sshFxpInit, uint32(3)); err != nil {
+		return err
+	}
+	kind, reply, err := c.receive()
+	if err != nil {
+		return err
+	}
+	if version := reply.uint32(); kind != sshFxpVersion || version < 3 || reply.err != nil {
+		return errors.New("unsupported SFTP server")
+	}
+	return nil
+}
+
+/*
+request sends a request, and waits for its reply
+
+Args:
+    kind: the packet type of the request
+
+    fields: the fields of the request after its id, each a uint32 or a string
+
+Returns:
+    the packet type and fields of the reply, after its id; or an error when the connection fails
+*/
+func (c *sftpClient) request(kind byte, fields ...interface{}) (byte, *sftpReply, error) {
+	c.id++
+	if err := c.send(kind, append([]interface{}{c.id}, fields...)...); err != nil {
+		return 0, nil, err
+	}
+	replyKind, reply, err := c.receive()
+	if err != nil {
+		return 0, nil, err
+	}
+	if id := reply.uint32(); id != c.id || reply.err != nil {
+		return 0, nil, errSFTPPacket
+	}
+	return replyKind, reply, nil
+}
+
+// statusError - the error of a status reply, or errSFTPPacket when the reply is of another kind
+func statusError(kind byte, reply *sftpReply) error {
+	if kind != sshFxpStatus {
+		return errSFTPPacket
+	}
+	reply.uint32()
+	if msg := reply.string(); len(msg) > 0 {
+		return errors.New(msg)
+	}
+	return errSFTPPacket
+}
+
+// sftpPath - the path of a remote name as sent to the server, where /~ is the home directory
+func sftpPath(p string) string {
+	switch {
+	case 0 == len(p) || "/~" == p || "/~/" == p:
+		return "."
+	case strings.HasPrefix(p, "/~/"):
+		return p[3:]
+	}
+	return p
+}
+
+// lstat - the stat results of a remote path, without following a final symbolic link
+func (c *sftpClient) lstat(p string) (statRecord, error) {
+	kind, reply, err := c.request(sshFxpLstat, sftpPath(p))
+	if err != nil {
+		return statRecord{}, err
+	}
+	if kind != sshFxpAttrs {
+		return statRecord{}, statusError(kind, reply)
+	}
+	rec := reply.attrs()
+	return rec, reply.err
+}
+
+// readDir - the names and stat results of the entries of a remote directory, sorted by name, without . and ..
+func (c *sftpClient) readDir(p string) ([]string, map[string]statRecord, error) {
+	kind, reply, err := c.request(sshFxpOpendir, sftpPath(p))
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != sshFxpHandle {
+		return nil, nil, statusError(kind, reply)
+	}
+	handle := reply.string()
+	if reply.err != nil {
+		return nil, nil, reply.err
+	}
+	defer c.request(sshFxpClose, handle)
+
+	var names []string
+	records := make(map[string]statRecord)
+	for {
+		kind, reply, err = c.request(sshFxpReaddir, handle)
+		if err != nil {
+			return nil, nil, err
+		}
+		if kind != sshFxpName {
+			// the status code is read from a copy, so that statusError() reads it again
+			if status := *reply; kind == sshFxpStatus && sshFxEOF == status.uint32() {
+				break
+			}
+			return nil, nil, statusError(kind, reply)
+		}
+		for n := reply.uint32(); n > 0 && nil == reply.err; n-- {
+			name := reply.string()
+			reply.string() // the long name, as shown by ls -l
+			rec := reply.attrs()
+			if "." != name && ".." != name {
+				names = append(names, name)
+				records[name] = rec
+			}
+		}
+		if reply.err != nil {
+			return nil, nil, reply.err
+		}
+	}
+	sort.Strings(names)
+	return names, records, nil
+}
+
+// close - end the session and the connection
+func (c *sftpClient) close() {
+	c.stop()
+	c.stdin.Close()
+	c.conn.Close()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpFields - encode each field as in an SFTP packet: a uint32, a uint64, or a string with its length
+func sftpFields(fields ...interface{}) []byte {
+	var data []byte
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			data = binary.BigEndian.AppendUint32(data, v)
+		case uint64:
+			data = binary.BigEndian.AppendUint64(data, v)
+		case string:
+			data = binary.BigEndian.AppendUint32(data, uint32(len(v)))
+			data = append(data, v...)
+		}
+	}
+	return data
+}
+
+func TestSFTPReplyAttrs(t *testing.T) {
+	modified := time.Unix(1700000000, 0)
+	accessed := time.Unix(1700000100, 0)
+	tests := []struct {
+		name   string
+		fields []interface{}
+		want   statRecord
+	}{
+		{"none", []interface{}{uint32(0)}, statRecord{}},
+		{"size", []interface{}{uint32(sshFileXferAttrSize), uint64(1 << 40)}, statRecord{Size: 1 << 40, Allocated: 1 << 40}},
+		{"owner", []interface{}{uint32(sshFileXferAttrUIDGID), uint32(1000), uint32(100)}, statRecord{Owner: "1000", Group: "100", HaveOwner: true, HaveGroup: true}},
+		{"mode", []interface{}{uint32(sshFileXferAttrPermissions), uint32(0100644)}, statRecord{Mode: 0644}},
+		{"times", []interface{}{uint32(sshFileXferAttrACModTime), uint32(accessed.Unix()), uint32(modified.Unix())}, statRecord{Access: accessed, ModTime: modified}},
+		{"all", []interface{}{uint32(sshFileXferAttrSize | sshFileXferAttrUIDGID | sshFileXferAttrPermissions | sshFileXferAttrACModTime | sshFileXferAttrExtended), uint64(42), uint32(0), uint32(0), uint32(040755), uint32(accessed.Unix()), uint32(modified.Unix()), uint32(2), "a", "1", "b", "2"},
+			statRecord{Size: 42, Allocated: 42, Owner: "0", Group: "0", HaveOwner: true, HaveGroup: true, Mode: os.ModeDir | 0755, Access: accessed, ModTime: modified}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a trailing field shows that exactly the attributes were read
+			reply := &sftpReply{data: sftpFields(append(tt.fields, "next")...)}
+			got := reply.attrs()
+			if reply.err != nil {
+				t.Fatalf("attrs() error = %v", reply.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attrs() = %+v, want %+v", got, tt.want)
+			}
+			if next := reply.string(); "next" != next || reply.err != nil {
+				t.Errorf("after attrs(), string() = %q, %v, want \"next\"", next, reply.err)
+			}
+		})
+	}
+}
+
+func TestSFTPReplyAttrsTruncated(t *testing.T) {
+	full := sftpFields(uint32(sshFileXferAttrSize|sshFileXferAttrACModTime), uint64(42), uint32(1), uint32(2))
+	for n := 0; n < len(full); n++ {
+		reply := &sftpReply{data: full[:n]}
+		reply.attrs()
+		if reply.err != errSFTPPacket {
+			t.Errorf("attrs() of %d of %d bytes: error = %v, want %v", n, len(full), reply.err, errSFTPPacket)
+		}
+	}
+}
+
+func TestRemoteMode(t *testing.T) {
+	tests := []struct {
+		bits uint32
+		want os.FileMode
+	}{
+		{0100644, 0644},
+		{0040755, os.ModeDir | 0755},
+		{0120777, os.ModeSymlink | 0777},
+		{0010600, os.ModeNamedPipe | 0600},
+		{0140755, os.ModeSocket | 0755},
+		{0020620, os.ModeDevice | os.ModeCharDevice | 0620},
+		{0060660, os.ModeDevice | 0660},
+		{0104755, os.ModeSetuid | 0755},
+		{0102755, os.ModeSetgid | 0755},
+		{0041777, os.ModeDir | os.ModeSticky | 0777},
+	}
+	for _, tt := range tests {
+		if got := remoteMode(tt.bits); got != tt.want {
+			t.Errorf("remoteMode(%#o) = %v, want %v", tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestSFTPPath(t *testing.T) {
+	tests := []struct {
+		p    string
+		want string
+	}{
+		{"", "."},
+		{"/~", "."},
+		{"/~/", "."},
+		{"/~/archive", "archive"},
+		{"/~/a/b", "a/b"},
+		{"/var/www", "/var/www"},
+		{"/", "/"},
+		{"/~user", "/~user"},
+	}
+	for _, tt := range tests {
+		if got := sftpPath(tt.p); got != tt.want {
+			t.Errorf("sftpPath(%q) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+// nopWriteCloser - an io.WriteCloser which does nothing on Close()
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+/*
+fakeSFTPServer returns a client whose requests are answered by reply
+
+Args:
+    t: the test; the server stops when it ends
+
+    reply: returns the packet type and fields of the reply to a request of kind, after its id
+
+Returns:
+    the client
+*/
+func fakeSFTPServer(t *testing.T, reply func(kind byte) (byte, []interface{})) *sftpClient {
+	requests, requestWriter := io.Pipe()
+	replyReader, replies := io.Pipe()
+	t.Cleanup(func() {
+		requestWriter.Close()
+		replies.Close()
+	})
+	go func() {
+		for {
+			var header [9]byte
+			if _, err := io.ReadFull(requests, header[:]); err != nil {
+				return
+			}
+			rest := make([]byte, binary.BigEndian.Uint32(header[:4])-5)
+			if _, err := io.ReadFull(requests, rest); err != nil {
+				return
+			}
+			kind, fields := reply(header[4])
+			data := append([]byte{kind}, sftpFields(append([]interface{}{binary.BigEndian.Uint32(header[5:])}, fields...)...)...)
+			packet := append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+			if _, err := replies.Write(packet); err != nil {
+				return
+			}
+		}
+	}()
+	return &sftpClient{stdin: nopWriteCloser{requestWriter}, stdout: replyReader}
+}
+
+func TestSFTPReadDir(t *testing.T) {
+	readdirs := 0
+	c := fakeSFTPServer(t, func(kind byte) (byte, []interface{}) {
+		switch kind {
+		case sshFxpOpendir:
+			return sshFxpHandle, []interface{}{"handle"}
+		case sshFxpReaddir:
+			readdirs++
+			switch readdirs {
+			case 1:
+				return sshFxpName, []interface{}{uint32(3), ".", "", uint32(0), "b", "", uint32(sshFileXferAttrSize), uint64(2), "..", "", uint32(0)}
+			case 2:
+				return sshFxpName, []interface{}{uint32(1), "a", "", uint32(sshFileXferAttrSize), uint64(1)}
+			}
+			return sshFxpStatus, []interface{}{uint32(sshFxEOF), "End of file", ""}
+		}
+		return sshFxpStatus, []interface{}{uint32(0), "", ""}
+	})
+	names, records, err := c.readDir("/~/dir")
+	if err != nil {
+		t.Fatalf("readDir() error = %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("readDir() names = %q, want %q", names, want)
+	}
+	if records["a"].Size != 1 || records["b"].Size != 2 || len(records) != 2 {
+		t.Errorf("readDir() records = %+v", records)
+	}
+}
+
+func TestSFTPReadDirStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    byte
+		status  []interface{}
+		wantErr string
+	}{
+		{"opendir denied", sshFxpOpendir, []interface{}{uint32(3), "Permission denied", ""}, "Permission denied"},
+		{"readdir failed", sshFxpReaddir, []interface{}{uint32(4), "Failure", ""}, "Failure"},
+		{"readdir without message", sshFxpReaddir, []interface{}{uint32(4), "", ""}, errSFTPPacket.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeSFTPServer(t, func(kind byte) (byte, []interface{}) {
+				switch {
+				case kind == tt.kind:
+					return sshFxpStatus, tt.status
+				case sshFxpOpendir == kind:
+					return sshFxpHandle, []interface{}{"handle"}
+				}
+				return sshFxpStatus, []interface{}{uint32(0), "", ""}
+			})
+			_, _, err := c.readDir("/dir")
+			if nil == err || err.Error() != tt.wantErr {
+				t.Errorf("readDir() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKnownHostKeyAlgorithms(t *testing.T) {
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var keys []ssh.PublicKey
+	for _, k := range []interface{}{edKey, &ecKey.PublicKey, &rsaKey.PublicKey} {
+		key, err := ssh.NewPublicKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	lines := []string{
+		knownhosts.Line([]string{knownhosts.Normalize("ed.example.com:22")}, keys[0]),
+		knownhosts.Line([]string{knownhosts.Normalize("both.example.com:22")}, keys[1]),
+		knownhosts.Line([]string{knownhosts.Normalize("both.example.com:22")}, keys[0]),
+		knownhosts.Line([]string{knownhosts.Normalize("rsa.example.com:2222")}, keys[2]),
+	}
+	fname := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(fname, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hostKeys, err := knownhosts.New(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	tests := []struct {
+		address string
+		want    []string
+	}{
+		{"ed.example.com:22", []string{ssh.KeyAlgoED25519}},
+		{"both.example.com:22", []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoED25519}},
+		{"rsa.example.com:2222", []string{ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSA}},
+		{"unknown.example.com:22", nil},
+	}
+	for _, tt := range tests {
+		got := knownHostKeyAlgorithms(hostKeys, tt.address, remote)
+		want := append([]string(nil), tt.want...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("knownHostKeyAlgorithms(%q) = %q, want %q", tt.address, got, want)
+		}
+	}
+}
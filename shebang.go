@@ -0,0 +1,61 @@
+/*
+
+shebang.go
+
+Find the interpreter of executable scripts, used by the -shebang cmd line option
+
+Example: fstat -shebang -type f -r /usr/local/bin
+Example: fstat -shebang -where 'interpreter =~ "python2"' -r /opt/scripts
+
+The first line of each executable file is read, and when it starts with #!, the rest of the line is shown, such
+as: /usr/bin/env python3. Like the kernel, only the first 256 bytes are read. Windows does not have execute
+permission bits, so every file is read there.
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// shebangMaxLength - the longest first line which is read, the same as the Linux kernel
+const shebangMaxLength = 256
+
+// isExecutable - true when any of the execute permission bits are set, or always on Windows
+func isExecutable(mode os.FileMode) bool {
+	return mode&0111 != 0 || "windows" == runtime.GOOS
+}
+
+/*
+readShebang returns the interpreter named by the first line of a script
+
+Args:
+    fname: the file name
+
+Returns:
+    the interpreter and its arguments, such as "/usr/bin/env python3"; empty when the file does not start with #!
+    or the line is not text; or an error when the file can not be read
+*/
+func readShebang(fname string) (string, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReaderSize(io.LimitReader(file, shebangMaxLength), shebangMaxLength).ReadSlice('\n')
+	if err != nil && io.EOF != err && bufio.ErrBufferFull != err {
+		return "", err
+	}
+	if !bytes.HasPrefix(line, []byte("#!")) || !utf8.Valid(line) || bytes.IndexByte(line, 0) >= 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(string(line[2:])), nil
+}
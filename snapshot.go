@@ -0,0 +1,126 @@
+/*
+
+snapshot.go
+
+Save a scan to a file and load it again later, used by the -snapshot-save and -snapshot-load cmd line options
+
+A snapshot is a gzip compressed gob stream: one snapshotHeader followed by one snapshotEntry per file.
+Each entry holds the same statRecord used by the -cache cmd line option, so a loaded snapshot is
+filtered, sorted and rendered by the regular code without examining the file system again.
+
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// snapshotVersion - increment when snapshotHeader or snapshotEntry change
+const snapshotVersion = 1
+
+// errNotInSnapshot - reported for a file name that is not in the snapshot loaded by -snapshot-load
+var errNotInSnapshot = errors.New("not in snapshot")
+
+// snapshotHeader - the first value in a snapshot file
+type snapshotHeader struct {
+	Version int
+	Created time.Time
+	Args    []string
+	Count   int
+}
+
+// snapshotEntry - a single file within a snapshot
+type snapshotEntry struct {
+	FullName string
+	Record   statRecord
+}
+
+/*
+SaveSnapshot writes all entries to a new snapshot file
+
+Args:
+    fname: the snapshot file (-snapshot-save cmd line option)
+
+    allEntries: the entries to save
+
+    cache: holds the statRecord of every entry, which was filled in by GetFileInfo()
+
+Returns:
+    an error when fname can not be written
+*/
+func SaveSnapshot(fname string, allEntries []FileStat, cache *statCache) error {
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := gzip.NewWriter(file)
+	enc := gob.NewEncoder(zw)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Created: time.Now(), Args: os.Args[1:], Count: len(allEntries)}); err != nil {
+		return err
+	}
+	for _, e := range allEntries {
+		// the name before -abs, -relto or -unc, which are applied again when the snapshot is loaded
+		rec, ok := cache.records[cache.key(e.statName)]
+		if !ok {
+			return fmt.Errorf("no stat results for: %s", e.FullName)
+		}
+		if err := enc.Encode(snapshotEntry{FullName: e.statName, Record: rec}); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+/*
+LoadSnapshot reads a snapshot file created by SaveSnapshot()
+
+Args:
+    fname: the snapshot file (-snapshot-load cmd line option)
+
+Returns:
+    the file names in the order they were saved; a cache which holds the stat results of every file
+    and never expires; or an error when fname is not a valid snapshot
+*/
+func LoadSnapshot(fname string) ([]string, *statCache, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: not a snapshot file: %s", fname, err)
+	}
+	dec := gob.NewDecoder(zr)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, nil, fmt.Errorf("%s: not a snapshot file: %s", fname, err)
+	}
+	if header.Version != snapshotVersion {
+		return nil, nil, fmt.Errorf("%s: unsupported snapshot version: %d", fname, header.Version)
+	}
+
+	cache := newSnapshotCache()
+	allFilenames := make([]string, 0, header.Count)
+	for i := 0; i < header.Count; i++ {
+		var entry snapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, nil, fmt.Errorf("%s: entry %d: %s", fname, i+1, err)
+		}
+		cache.records[cache.key(entry.FullName)] = entry.Record
+		allFilenames = append(allFilenames, entry.FullName)
+	}
+	return allFilenames, cache, nil
+}
@@ -0,0 +1,185 @@
+/*
+
+sqlite.go
+
+Write all entries into a SQLite database, used by the -osqlite cmd line option
+
+Each run is recorded in the scans table and its entries are added to the files table with the same scan_id,
+so that repeated runs can be appended to the same database and compared with SQL afterwards:
+
+    SELECT path, size FROM files WHERE scan_id = (SELECT MAX(scan_id) FROM scans) ORDER BY size DESC LIMIT 10;
+
+Time stamps are stored as UTC RFC 3339 text with nanoseconds, which sorts and compares correctly.
+
+*/
+
+package main
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"time"
+
+	// pure Go SQLite driver, so that cgo is not needed
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeLayout - fixed width so that time stamps stored as text sort correctly
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteSchema - tables and indices created when they do not already exist
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS scans (
+		scan_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started TEXT NOT NULL,
+		args TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS files (
+		scan_id INTEGER NOT NULL REFERENCES scans(scan_id),
+		path TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		modtime TEXT NOT NULL,
+		type TEXT NOT NULL,
+		mode TEXT,
+		owner TEXT,
+		grp TEXT,
+		allocated INTEGER,
+		sparse INTEGER,
+		device INTEGER,
+		accesstime TEXT,
+		directory TEXT,
+		basename TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS files_scan_id ON files(scan_id)`,
+	`CREATE INDEX IF NOT EXISTS files_size ON files(size)`,
+	`CREATE INDEX IF NOT EXISTS files_modtime ON files(modtime)`,
+	`CREATE INDEX IF NOT EXISTS files_path ON files(path)`,
+}
+
+// sqliteAddedColumns - columns of the files table which are added to databases created by older versions
+var sqliteAddedColumns = []string{"directory TEXT", "basename TEXT"}
+
+// addSQLiteColumns - add each of sqliteAddedColumns which is not already in the files table
+func addSQLiteColumns(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('files')")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for _, column := range sqliteAddedColumns {
+		name, _, _ := strings.Cut(column, " ")
+		if existing[name] {
+			continue
+		}
+		if _, err = db.Exec("ALTER TABLE files ADD COLUMN " + column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullString - nil when s is empty, so that it is stored as NULL
+func nullString(s string) interface{} {
+	if 0 == len(s) {
+		return nil
+	}
+	return s
+}
+
+/*
+writeSQLite adds a new scan and all of its entries to the database in a single transaction
+
+Args:
+    dbFile: the database file, which is created when it does not exist
+
+    allEntries: a slice of all files
+
+    onlyTypes: only write these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the scan_id of the new scan and the number of entries written, or an error
+*/
+func writeSQLite(dbFile string, allEntries []FileStat, onlyTypes string) (int64, int64, error) {
+	db, err := sql.Open("sqlite", dbFile)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	for _, stmt := range sqliteSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err = addSQLiteColumns(db); err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO scans (started, args) VALUES (?, ?)", time.Now().UTC().Format(sqliteTimeLayout), strings.Join(os.Args[1:], " "))
+	if err != nil {
+		return 0, 0, err
+	}
+	scanID, err := result.LastInsertId()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	insert, err := tx.Prepare("INSERT INTO files (scan_id, path, size, modtime, type, mode, owner, grp, allocated, sparse, device, accesstime, directory, basename) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insert.Close()
+
+	var count int64
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		_, err = insert.Exec(scanID, e.FullName, e.Size, e.ModTime.UTC().Format(sqliteTimeLayout), e.FileType, e.Mode, e.Owner, e.Group, e.Allocated, e.Sparse, int64(e.Device), e.Access.UTC().Format(sqliteTimeLayout), nullString(e.Directory), nullString(e.Basename))
+		if err != nil {
+			return 0, 0, err
+		}
+		count++
+	}
+	return scanID, count, tx.Commit()
+}
+
+/*
+RenderSQLite writes all entries to a SQLite database and reports the new scan_id to STDERR
+
+Args:
+    dbFile: the database file (-osqlite cmd line option)
+
+    allEntries: a slice of all files
+
+    quiet: when set, the scan_id is not reported (cmd line option: -q)
+
+    onlyTypes: only write these types of entries, such as "FL"; empty for all types (-type cmd line option)
+*/
+func RenderSQLite(dbFile string, allEntries []FileStat, quiet bool, onlyTypes string) {
+	scanID, count, err := writeSQLite(dbFile, allEntries, onlyTypes)
+	if err != nil {
+		logError("%s: %s", dbFile, err)
+		os.Exit(1)
+	}
+	if !quiet {
+		logInfo("Wrote %d entries to %s with scan_id: %d", count, dbFile, scanID)
+	}
+}
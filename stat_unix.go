@@ -0,0 +1,134 @@
+//go:build !windows
+
+/*
+
+stat_unix.go
+
+Platform specific file metadata for Unix-like systems
+
+*/
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// ownerCache - map a uid to its user name so that each uid is only looked up once
+var ownerCache = make(map[uint32]string)
+
+// getOwner returns the user name that owns the file
+// when the uid can not be resolved to a name, the numeric uid is returned instead
+func getOwner(fname string, f os.FileInfo) string {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "?"
+	}
+
+	uid := st.Uid
+	if name, ok := ownerCache[uid]; ok {
+		return name
+	}
+
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	ownerCache[uid] = name
+	return name
+}
+
+// groupCache - map a gid to its group name so that each gid is only looked up once
+var groupCache = make(map[uint32]string)
+
+// getGroup returns the name of the group that owns the file
+// when the gid can not be resolved to a name, the numeric gid is returned instead
+func getGroup(fname string, f os.FileInfo) string {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "?"
+	}
+
+	gid := st.Gid
+	if name, ok := groupCache[gid]; ok {
+		return name
+	}
+
+	name := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(name); err == nil {
+		name = g.Name
+	}
+	groupCache[gid] = name
+	return name
+}
+
+// resolveUser converts a numeric uid given on the cmd line into a user name, so that it can be compared to getOwner()
+func resolveUser(value string) string {
+	if u, err := user.LookupId(value); err == nil {
+		return u.Username
+	}
+	return value
+}
+
+// resolveGroup converts a numeric gid given on the cmd line into a group name, so that it can be compared to getGroup()
+func resolveGroup(value string) string {
+	if g, err := user.LookupGroupId(value); err == nil {
+		return g.Name
+	}
+	return value
+}
+
+// getAllocated returns the number of bytes allocated on disk for the file
+func getAllocated(f os.FileInfo) int64 {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return f.Size()
+	}
+	return int64(st.Blocks) * 512
+}
+
+// getDevice returns the id of the device that contains the file
+func getDevice(fname string, f os.FileInfo) uint64 {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(st.Dev)
+}
+
+// getDeviceNumber returns the major and minor numbers of a character or block device, such as "8:1"; otherwise ""
+func getDeviceNumber(f os.FileInfo) string {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok || 0 == f.Mode()&os.ModeDevice {
+		return ""
+	}
+	rdev := uint64(st.Rdev)
+	return strconv.FormatUint(uint64(unix.Major(rdev)), 10) + ":" + strconv.FormatUint(uint64(unix.Minor(rdev)), 10)
+}
+
+// getFileID returns the device and inode of the file, which are the same for every path to it
+func getFileID(fname string, f os.FileInfo) (fileID, bool) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{device: uint64(st.Dev), inode: uint64(st.Ino)}, true
+}
+
+// getDiskSpace returns the total, used and available space of the file system that contains path
+func getDiskSpace(path string) (total uint64, used uint64, free uint64, err error) {
+	var st syscall.Statfs_t
+	if err = syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, err
+	}
+	bsize := uint64(st.Bsize)
+	total = uint64(st.Blocks) * bsize
+	used = (uint64(st.Blocks) - uint64(st.Bfree)) * bsize
+	free = uint64(st.Bavail) * bsize
+	return total, used, free, nil
+}
@@ -0,0 +1,146 @@
+//go:build windows
+
+/*
+
+stat_windows.go
+
+Platform specific file metadata for Windows
+
+*/
+
+package main
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// ownerCache - map a SID string to its account name so that each SID is only looked up once
+var ownerCache = make(map[string]string)
+
+// getOwner returns the account name, in DOMAIN\user format, that owns the file
+// when the owner SID can not be resolved to a name, the SID string is returned instead
+func getOwner(fname string, f os.FileInfo) string {
+	sd, err := windows.GetNamedSecurityInfo(fname, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "?"
+	}
+	sid, _, err := sd.Owner()
+	if err != nil || sid == nil {
+		return "?"
+	}
+
+	sidString := sid.String()
+	if name, ok := ownerCache[sidString]; ok {
+		return name
+	}
+
+	name := sidString
+	if account, domain, _, err := sid.LookupAccount(""); err == nil {
+		name = account
+		if len(domain) > 0 {
+			name = domain + `\` + account
+		}
+	}
+	ownerCache[sidString] = name
+	return name
+}
+
+// groupCache - map a SID string to its account name so that each SID is only looked up once
+var groupCache = make(map[string]string)
+
+// getGroup returns the name, in DOMAIN\group format, of the primary group of the file
+// when the group SID can not be resolved to a name, the SID string is returned instead
+func getGroup(fname string, f os.FileInfo) string {
+	sd, err := windows.GetNamedSecurityInfo(fname, windows.SE_FILE_OBJECT, windows.GROUP_SECURITY_INFORMATION)
+	if err != nil {
+		return "?"
+	}
+	sid, _, err := sd.Group()
+	if err != nil || sid == nil {
+		return "?"
+	}
+
+	sidString := sid.String()
+	if name, ok := groupCache[sidString]; ok {
+		return name
+	}
+
+	name := sidString
+	if account, domain, _, err := sid.LookupAccount(""); err == nil {
+		name = account
+		if len(domain) > 0 {
+			name = domain + `\` + account
+		}
+	}
+	groupCache[sidString] = name
+	return name
+}
+
+// resolveUser returns the user name given on the cmd line as is, since Windows does not use numeric ids
+func resolveUser(value string) string {
+	return value
+}
+
+// resolveGroup returns the group name given on the cmd line as is, since Windows does not use numeric ids
+func resolveGroup(value string) string {
+	return value
+}
+
+// getAllocated returns the number of bytes allocated on disk for the file
+// the allocated size is not available from os.Lstat on Windows, so the file size is used instead
+func getAllocated(f os.FileInfo) int64 {
+	return f.Size()
+}
+
+// getDevice returns an id for the volume that contains the file, derived from its volume name
+func getDevice(fname string, f os.FileInfo) uint64 {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToUpper(filepath.VolumeName(abs))))
+	return h.Sum64()
+}
+
+// getDeviceNumber returns "", since device files are not listed on Windows
+func getDeviceNumber(f os.FileInfo) string {
+	return ""
+}
+
+// getFileID returns the volume serial number and file index of the file, which are the same for every path to it
+func getFileID(fname string, f os.FileInfo) (fileID, bool) {
+	p, err := windows.UTF16PtrFromString(fname)
+	if err != nil {
+		return fileID{}, false
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is needed to open a directory
+	h, err := windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileID{}, false
+	}
+	defer windows.CloseHandle(h)
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		return fileID{}, false
+	}
+	return fileID{device: uint64(info.VolumeSerialNumber), inode: uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)}, true
+}
+
+// getDiskSpace returns the total, used and available space of the volume that contains path
+func getDiskSpace(path string) (total uint64, used uint64, free uint64, err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var totalFree uint64
+	if err = windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, 0, 0, err
+	}
+	return total, total - totalFree, free, nil
+}
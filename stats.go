@@ -0,0 +1,340 @@
+/*
+
+stats.go
+
+Size statistics used by the -t and -tx cmd line options
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// SizeStats - distribution of file sizes across a set of regular files
+type SizeStats struct {
+	Count    int64    `json:"count"`
+	Total    int64    `json:"total"`
+	Average  float64  `json:"average"`
+	Median   int64    `json:"median"`
+	P90      int64    `json:"p90"`
+	P99      int64    `json:"p99"`
+	StdDev   float64  `json:"stddev"`
+	Smallest FileStat `json:"smallest"`
+	Largest  FileStat `json:"largest"`
+}
+
+/*
+percentile returns the value at the given percentile using the nearest-rank method
+
+Args:
+    sortedSizes: file sizes sorted from smallest to largest; must not be empty
+
+    p: the percentile, between 0 and 100
+
+Returns:
+    the size at the p-th percentile
+*/
+func percentile(sortedSizes []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sortedSizes))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sortedSizes[rank-1]
+}
+
+/*
+ComputeSizeStats calculates the size distribution of the given files
+
+Args:
+    files: a slice of regular files; directories and symbolic links should already be removed
+
+Returns:
+    the statistics for these files; all fields are zero when files is empty
+*/
+func ComputeSizeStats(files []FileStat) SizeStats {
+	var stats SizeStats
+	if len(files) == 0 {
+		return stats
+	}
+
+	sizes := make([]int64, len(files))
+	stats.Smallest = files[0]
+	stats.Largest = files[0]
+	for i, f := range files {
+		sizes[i] = f.Size
+		stats.Total += f.Size
+		if f.Size < stats.Smallest.Size {
+			stats.Smallest = f
+		}
+		if f.Size > stats.Largest.Size {
+			stats.Largest = f
+		}
+	}
+	stats.Count = int64(len(files))
+	stats.Average = float64(stats.Total) / float64(stats.Count)
+
+	var sumSquares float64
+	for _, size := range sizes {
+		diff := float64(size) - stats.Average
+		sumSquares += diff * diff
+	}
+	stats.StdDev = math.Sqrt(sumSquares / float64(stats.Count))
+
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	stats.Median = percentile(sizes, 50)
+	stats.P90 = percentile(sizes, 90)
+	stats.P99 = percentile(sizes, 99)
+	return stats
+}
+
+// Summary - totals for a set of entries, used by the -t cmd line option with -oc, -ot, -oh and -oj
+type Summary struct {
+	TotalSize          int64      `json:"totalsize"`
+	FileCount          int64      `json:"files"`
+	DirCount           int64      `json:"directories"`
+	LinkCount          int64      `json:"symlinks"`
+	PipeCount          int64      `json:"pipes"`
+	SocketCount        int64      `json:"sockets"`
+	CharDeviceCount    int64      `json:"chardevices"`
+	BlockDeviceCount   int64      `json:"blockdevices"`
+	OtherCount         int64      `json:"other"`
+	FailedCount        int64      `json:"failed"`
+	AverageSize        float64    `json:"averagesize"`
+	AverageFilesPerDir float64    `json:"averagefilesperdir"`
+	MediaCount         int64      `json:"mediafiles,omitempty"`
+	TotalDuration      float64    `json:"totalduration,omitempty"`
+	AverageDuration    float64    `json:"averageduration,omitempty"`
+	DocumentCount      int64      `json:"documents,omitempty"`
+	TotalPages         int64      `json:"totalpages,omitempty"`
+	Extended           *SizeStats `json:"extended,omitempty"`
+}
+
+/*
+ComputeSummary calculates the totals shown by the -t cmd line option
+
+Args:
+    allEntries: a slice of files, directories and symbolic links
+
+    extended: when set, also include the size distribution of all regular files (-tx cmd line option)
+
+    failed: the number of files that could not be examined, which are not in allEntries
+
+Returns:
+    the totals for these entries, with a count of each type
+*/
+func ComputeSummary(allEntries []FileStat, extended bool, failed int64) Summary {
+	summary := Summary{FailedCount: failed}
+	var files []FileStat
+	for _, e := range allEntries {
+		switch e.FileType {
+		case "F":
+			summary.TotalSize += e.Size
+			summary.FileCount++
+			files = append(files, e)
+			if e.Duration > 0 {
+				summary.MediaCount++
+				summary.TotalDuration += e.Duration
+			}
+			if e.Pages > 0 {
+				summary.DocumentCount++
+				summary.TotalPages += e.Pages
+			}
+		case "D":
+			summary.DirCount++
+		case "L":
+			summary.LinkCount++
+		case "P":
+			summary.PipeCount++
+		case "S":
+			summary.SocketCount++
+		case "C":
+			summary.CharDeviceCount++
+		case "B":
+			summary.BlockDeviceCount++
+		default:
+			summary.OtherCount++
+		}
+	}
+	if summary.MediaCount > 0 {
+		summary.AverageDuration = summary.TotalDuration / float64(summary.MediaCount)
+	}
+	if summary.FileCount > 0 {
+		summary.AverageSize = float64(summary.TotalSize) / float64(summary.FileCount)
+		if summary.DirCount > 0 {
+			summary.AverageFilesPerDir = float64(summary.FileCount) / float64(summary.DirCount)
+		}
+		if extended {
+			stats := ComputeSizeStats(files)
+			summary.Extended = &stats
+		}
+	}
+	return summary
+}
+
+// summaryField - one named value of a Summary, used for the -t footer of -oc, -ot and -oh
+type summaryField struct {
+	Name  string
+	Value string
+}
+
+/*
+summaryFields flattens a Summary into name, value pairs; names match the JSON field names
+
+Args:
+    summary: created by ComputeSummary
+
+    formatBytes: converts a size into text, such as formatSize()
+
+    formatCount: converts a count, such as the number of files, into text
+
+Returns:
+    the fields in display order; the extended fields are only included when summary.Extended is set
+*/
+func summaryFields(summary Summary, formatBytes func(int64) string, formatCount func(int64) string) []summaryField {
+	fields := []summaryField{
+		{"totalsize", formatBytes(summary.TotalSize)},
+		{"files", formatCount(summary.FileCount)},
+		{"directories", formatCount(summary.DirCount)},
+		{"symlinks", formatCount(summary.LinkCount)},
+		{"pipes", formatCount(summary.PipeCount)},
+		{"sockets", formatCount(summary.SocketCount)},
+		{"chardevices", formatCount(summary.CharDeviceCount)},
+		{"blockdevices", formatCount(summary.BlockDeviceCount)},
+		{"other", formatCount(summary.OtherCount)},
+		{"failed", formatCount(summary.FailedCount)},
+		{"averagesize", formatBytes(int64(math.Round(summary.AverageSize)))},
+		{"averagefilesperdir", fmt.Sprintf("%.2f", summary.AverageFilesPerDir)},
+	}
+	if summary.MediaCount > 0 {
+		fields = append(fields,
+			summaryField{"mediafiles", formatCount(summary.MediaCount)},
+			summaryField{"totalduration", fmt.Sprintf("%.3f", summary.TotalDuration)},
+			summaryField{"averageduration", fmt.Sprintf("%.3f", summary.AverageDuration)},
+		)
+	}
+	if summary.DocumentCount > 0 {
+		fields = append(fields,
+			summaryField{"documents", formatCount(summary.DocumentCount)},
+			summaryField{"totalpages", formatCount(summary.TotalPages)},
+		)
+	}
+	if stats := summary.Extended; stats != nil {
+		fields = append(fields,
+			summaryField{"median", formatBytes(stats.Median)},
+			summaryField{"p90", formatBytes(stats.P90)},
+			summaryField{"p99", formatBytes(stats.P99)},
+			summaryField{"stddev", formatBytes(int64(math.Round(stats.StdDev)))},
+			summaryField{"smallest", formatBytes(stats.Smallest.Size)},
+			summaryField{"smallestname", stats.Smallest.FullName},
+			summaryField{"largest", formatBytes(stats.Largest.Size)},
+			summaryField{"largestname", stats.Largest.FullName},
+		)
+	}
+	return fields
+}
+
+// ScanSummary - a JSON document with the totals of a scan, used by the -ts and -tsfile cmd line options
+type ScanSummary struct {
+	Generated    time.Time  `json:"generated"`
+	Files        int64      `json:"files"`
+	Directories  int64      `json:"directories"`
+	Symlinks     int64      `json:"symlinks"`
+	Pipes        int64      `json:"pipes"`
+	Sockets      int64      `json:"sockets"`
+	CharDevices  int64      `json:"chardevices"`
+	BlockDevices int64      `json:"blockdevices"`
+	Other        int64      `json:"other"`
+	Failed       int64      `json:"failed"`
+	TotalSize    int64      `json:"totalsize"`
+	AverageSize  float64    `json:"averagesize"`
+	MedianSize   int64      `json:"mediansize"`
+	Oldest       *time.Time `json:"oldest,omitempty"`
+	Newest       *time.Time `json:"newest,omitempty"`
+	Partial      string     `json:"partial,omitempty"`
+}
+
+/*
+ComputeScanSummary calculates the counts by type, file sizes and modified time range of all entries
+
+Args:
+    allEntries: a slice of files, directories and symbolic links
+
+    onlyTypes: when set, only include entries of these types, such as "FL"; empty for all types (-type cmd line option)
+
+    failed: the number of files that could not be examined, which are not in allEntries
+
+    partial: why the scan was stopped early, see partialReason(); empty when every file was examined
+
+Returns:
+    the summary; sizes only include regular files, Oldest and Newest are nil when no entries are included
+*/
+func ComputeScanSummary(allEntries []FileStat, onlyTypes string, failed int64, partial string) ScanSummary {
+	summary := ScanSummary{Generated: time.Now(), Failed: failed, Partial: partial}
+	var files []FileStat
+	for i, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		switch e.FileType {
+		case "F":
+			summary.Files++
+			files = append(files, e)
+		case "D":
+			summary.Directories++
+		case "L":
+			summary.Symlinks++
+		case "P":
+			summary.Pipes++
+		case "S":
+			summary.Sockets++
+		case "C":
+			summary.CharDevices++
+		case "B":
+			summary.BlockDevices++
+		default:
+			summary.Other++
+		}
+		if summary.Oldest == nil || e.ModTime.Before(*summary.Oldest) {
+			summary.Oldest = &allEntries[i].ModTime
+		}
+		if summary.Newest == nil || e.ModTime.After(*summary.Newest) {
+			summary.Newest = &allEntries[i].ModTime
+		}
+	}
+	stats := ComputeSizeStats(files)
+	summary.TotalSize = stats.Total
+	summary.AverageSize = stats.Average
+	summary.MedianSize = stats.Median
+	return summary
+}
+
+/*
+WriteScanSummary outputs summary as indented JSON
+
+Args:
+    summary: created by ComputeScanSummary
+
+    fname: the file to create, or STDOUT when empty
+
+Returns:
+    an error when fname can not be written
+*/
+func WriteScanSummary(summary ScanSummary, fname string) error {
+	j, err := json.MarshalIndent(summary, "", "    ")
+	if err != nil {
+		return err
+	}
+	j = append(j, '\n')
+	if 0 == len(fname) {
+		_, err = os.Stdout.Write(j)
+		return err
+	}
+	return os.WriteFile(fname, j, 0644)
+}
@@ -0,0 +1,166 @@
+/*
+
+stream.go
+
+Stream each entry as soon as it is examined, used by the -ojl and -ocbor cmd line options
+
+-ojl writes one JSON object per line (JSON Lines)
+-ocbor writes one CBOR map per entry as a CBOR sequence (RFC 8742), with time stamps as tagged RFC 3339 strings
+-stream reads the file list one name at a time and outputs entries in batches with -oc, -ot, -names, -print0, -xargs, -format, or -printf
+When a sort option is also given, the entries are sorted by extsort.go before being output
+
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// streamEncoder - implemented by both json.Encoder and cbor.Encoder
+type streamEncoder interface {
+	Encode(v interface{}) error
+}
+
+/*
+newStreamEncoder returns the encoder used for STDOUT
+
+Args:
+    useCBOR: when set, encode CBOR instead of JSON Lines (-ocbor cmd line option)
+
+Returns:
+    the encoder
+*/
+func newStreamEncoder(useCBOR bool) streamEncoder {
+	if !useCBOR {
+		return json.NewEncoder(os.Stdout)
+	}
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano, TimeTag: cbor.EncTagRequired}.EncMode()
+	if err != nil {
+		logError("%s", err)
+		os.Exit(1)
+	}
+	return mode.NewEncoder(os.Stdout)
+}
+
+/*
+newStreamWriter returns a function which encodes a single entry to STDOUT
+This is passed to GetFileInfo() so that entries are output immediately instead of being collected
+
+Args:
+    enc: the encoder created by newStreamEncoder()
+
+    loc: when not nil, convert modification times to this time zone (-utc and -tz cmd line options)
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the function to call for each entry
+*/
+func newStreamWriter(enc streamEncoder, loc *time.Location, onlyTypes string) func(e FileStat) {
+	return func(e FileStat) {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			return
+		}
+		if loc != nil {
+			e.ModTime = e.ModTime.In(loc)
+			commitTimeIn(&e, loc)
+		}
+		if err := enc.Encode(e); err != nil {
+			// such as when the reading end of a pipe has been closed
+			logError("%s", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// streamBatchSize - the number of entries output at a time by the -stream cmd line option
+const streamBatchSize = 1000
+
+/*
+sliceNames returns a function which returns each name in allFilenames, for use with GetFileInfo()
+
+Args:
+    allFilenames: the file names
+
+Returns:
+    the function; it returns false after the last name
+*/
+func sliceNames(allFilenames []string) func() (string, bool) {
+	i := 0
+	return func() (string, bool) {
+		if i >= len(allFilenames) {
+			return "", false
+		}
+		i++
+		return allFilenames[i-1], true
+	}
+}
+
+/*
+scanNames returns a function which reads one name at a time from input, for use with GetFileInfo()
+Unlike GetFileList(), the names are never all held in memory (-stream cmd line option)
+
+Args:
+    input: the file list, such as STDIN
+
+    usingFile: the name of the file list, used in the error message when it is empty
+
+Returns:
+    the function; it returns false after the last name
+*/
+func scanNames(input *bufio.Scanner, usingFile string) func() (string, bool) {
+	count := 0
+	return func() (string, bool) {
+		if input.Scan() {
+			count++
+			return input.Text(), true
+		}
+		if 0 == count {
+			usageError("No files were listed in '%s'", usingFile)
+			os.Exit(3)
+		}
+		return "", false
+	}
+}
+
+/*
+newBatchWriter returns a function which collects entries and passes them to render in batches,
+so that memory use does not grow with the number of entries (-stream cmd line option)
+
+Args:
+    batchSize: the number of entries in each batch
+
+    loc: when not nil, convert modification times to this time zone (-utc and -tz cmd line options)
+
+    render: outputs a batch; first is only set for the first batch, such as to output a header row
+
+Returns:
+    the function to pass to GetFileInfo(); and a function to output any remaining entries, which must be called last
+*/
+func newBatchWriter(batchSize int, loc *time.Location, render func(batch []FileStat, first bool)) (func(e FileStat), func()) {
+	batch := make([]FileStat, 0, batchSize)
+	first := true
+	flush := func() {
+		if len(batch) > 0 || first {
+			render(batch, first)
+		}
+		batch = batch[:0]
+		first = false
+	}
+	return func(e FileStat) {
+		if loc != nil {
+			e.ModTime = e.ModTime.In(loc)
+			commitTimeIn(&e, loc)
+		}
+		batch = append(batch, e)
+		if len(batch) == batchSize {
+			flush()
+		}
+	}, flush
+}
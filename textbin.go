@@ -0,0 +1,74 @@
+/*
+
+textbin.go
+
+Classify files as text or binary, used by the -class, -text and -binary cmd line options
+
+Example: fstat -text -dn 7d -r src/
+Example: fstat -class -where 'class == "binary" && ext == ".txt"' -r docs/
+
+Like git and grep, only the start of each file is read. A file is binary when this contains a NUL byte or is
+not valid UTF-8, which also includes text in a legacy encoding such as Latin-1 or UTF-16. An empty file is text.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// textSampleSize - the number of bytes read from the start of each file
+const textSampleSize = 8000
+
+// the classes of file contents
+const (
+	classText   = "text"
+	classBinary = "binary"
+)
+
+/*
+classifyContents reads the start of a file to tell text from binary
+
+Args:
+    fname: the file name
+
+Returns:
+    classText or classBinary; or an error when the file can not be read
+*/
+func classifyContents(fname string) (string, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, textSampleSize)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && io.EOF != err && io.ErrUnexpectedEOF != err {
+		return "", err
+	}
+	sample := buf[:n]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return classBinary, nil
+	}
+
+	// the sample can end in the middle of a character
+	if n == textSampleSize {
+		for i := len(sample) - 1; i >= 0 && i >= len(sample)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(sample[i]) {
+				if !utf8.FullRune(sample[i:]) {
+					sample = sample[:i]
+				}
+				break
+			}
+		}
+	}
+	if !utf8.Valid(sample) {
+		return classBinary, nil
+	}
+	return classText, nil
+}
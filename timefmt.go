@@ -0,0 +1,143 @@
+/*
+
+timefmt.go
+
+Time stamp layouts and time zones, used by the -M, -ns, -datefmt, -rfc3339, -utc and -tz cmd line options
+
+*/
+
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	// embed the time zone database so that -tz also works on systems without one, such as Windows
+	_ "time/tzdata"
+)
+
+// defaultTimeLayout - the layout used for the Mod Time column
+const defaultTimeLayout = "2006-01-02 15:04:05"
+
+// strftimeDirectives - map strftime directives to their Go time layout equivalent
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'j': "002",
+	'a': "Mon",
+	'A': "Monday",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'Z': "MST",
+	'z': "-0700",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+	'D': "01/02/06",
+	'R': "15:04",
+	'%': "%",
+}
+
+/*
+strftimeToLayout converts a strftime style format, such as "%Y-%m-%d %H:%M", into a Go time layout
+Unknown directives are kept as is
+
+Args:
+    format: the strftime format
+
+Returns:
+    the equivalent Go time layout
+*/
+func strftimeToLayout(format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		i++
+		if layout, ok := strftimeDirectives[format[i]]; ok {
+			sb.WriteString(layout)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+	return sb.String()
+}
+
+/*
+getTimeLayout returns the layout used to render modification times
+
+Args:
+    addMilliseconds: when set, include thousands of a second (-M cmd line option)
+
+    addNanoseconds: when set, include billionths of a second (-ns cmd line option)
+
+    dateFormat: when set, a Go time layout or a strftime format containing '%' (-datefmt cmd line option)
+
+    useRFC3339: when set, use RFC 3339 time stamps which include the time zone offset (-rfc3339 cmd line option)
+
+Returns:
+    a Go time layout
+*/
+func getTimeLayout(addMilliseconds bool, addNanoseconds bool, dateFormat string, useRFC3339 bool) string {
+	if useRFC3339 {
+		if addNanoseconds {
+			return "2006-01-02T15:04:05.000000000Z07:00"
+		}
+		if addMilliseconds {
+			return "2006-01-02T15:04:05.000Z07:00"
+		}
+		return time.RFC3339
+	}
+	if len(dateFormat) > 0 {
+		if strings.Contains(dateFormat, "%") {
+			return strftimeToLayout(dateFormat)
+		}
+		return dateFormat
+	}
+	if addNanoseconds {
+		return defaultTimeLayout + ".000000000"
+	}
+	if addMilliseconds {
+		return defaultTimeLayout + ".000"
+	}
+	return defaultTimeLayout
+}
+
+/*
+getLocation returns the time zone used to render modification times
+
+Args:
+    useUTC: when set, use UTC (-utc cmd line option)
+
+    timeZone: when set, an IANA time zone name such as "America/New_York" (-tz cmd line option)
+
+Returns:
+    the time zone; nil when neither option is given, meaning the Local time zone is used
+*/
+//goland:noinspection GoUnhandledErrorResult
+func getLocation(useUTC bool, timeZone string) *time.Location {
+	if useUTC {
+		return time.UTC
+	}
+	if len(timeZone) == 0 {
+		return nil
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		logError("unable to load time zone: %s", timeZone)
+		os.Exit(2)
+	}
+	return loc
+}
@@ -0,0 +1,127 @@
+/*
+
+top.go
+
+Report the largest files, used by the -top cmd line option and the top subcommand
+
+Example: fstat -top 25 -r dirs.txt
+Example: fstat top 25 -r dirs.txt
+
+Each file is shown with its percentage of the total size of all files, and the cumulative percentage of it and
+every larger file, so that it is easy to see how few files use most of the space.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// topFile - one of the largest files
+type topFile struct {
+	Rank       int       `json:"rank"`
+	FullName   string    `json:"fullname"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modtime"`
+	Percent    float64   `json:"percent"`
+	Cumulative float64   `json:"cumulative"`
+}
+
+// topReport - the largest files, and the totals they are compared to
+type topReport struct {
+	TotalSize  int64     `json:"totalsize"`
+	TotalFiles int       `json:"totalfiles"`
+	Files      []topFile `json:"files"`
+}
+
+// percentOf - part as a percentage of total, or 0 when total is 0
+func percentOf(part int64, total int64) float64 {
+	if 0 == total {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
+
+/*
+FindLargest returns the largest regular files
+
+Args:
+    allEntries: the entries to search; only files are included
+
+    count: the number of files to return
+
+Returns:
+    the report, with files sorted from largest to smallest, and then by name
+*/
+func FindLargest(allEntries []FileStat, count int) topReport {
+	var files []FileStat
+	var report topReport
+	for _, e := range allEntries {
+		if "F" == e.FileType {
+			files = append(files, e)
+			report.TotalSize += e.Size
+		}
+	}
+	report.TotalFiles = len(files)
+	sortSize(files, false)
+	if len(files) > count {
+		files = files[:count]
+	}
+
+	report.Files = []topFile{}
+	var cumulative int64
+	for i, e := range files {
+		cumulative += e.Size
+		report.Files = append(report.Files, topFile{Rank: i + 1, FullName: e.FullName, Size: e.Size, ModTime: e.ModTime, Percent: percentOf(e.Size, report.TotalSize), Cumulative: percentOf(cumulative, report.TotalSize)})
+	}
+	return report
+}
+
+/*
+RenderLargest outputs the largest files
+
+Args:
+    report: created by FindLargest()
+
+    options: how the files are output (-c, -m, -dec, -oc, -ot, -oj and -csvdelim cmd line options)
+
+Returns:
+    the number of files output
+*/
+func RenderLargest(report topReport, options renderOptions) int {
+	if options.outputJSON {
+		j, _ := json.MarshalIndent(report, "", "    ")
+		fmt.Println(string(j))
+		return len(report.Files)
+	}
+
+	var allRows [][]string
+	var shownSize int64
+	for _, f := range report.Files {
+		shownSize += f.Size
+		allRows = append(allRows, []string{strconv.Itoa(f.Rank), formatSize(f.Size, options.addCommas, options.convertToMiB, options.useSI), fmt.Sprintf("%.1f%%", f.Percent), fmt.Sprintf("%.1f%%", f.Cumulative), f.ModTime.Format(options.timeLayout), f.FullName})
+	}
+	header := []string{"Rank", "Size", "Percent", "Cumulative", "Mod Time", "Name"}
+
+	if options.outputCSV {
+		renderCSV(header, allRows, options.csvDelimiter, nil)
+		return len(report.Files)
+	}
+
+	if options.outputTSV {
+		renderTSV(header, allRows, nil)
+		return len(report.Files)
+	}
+
+	if len(allRows) > 0 {
+		renderTable(header, allRows, []int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	}
+	fmt.Printf("largest %d of %d files: %s of %s (%.1f%%)\n", len(report.Files), report.TotalFiles, formatSize(shownSize, options.addCommas, options.convertToMiB, options.useSI), formatSize(report.TotalSize, options.addCommas, options.convertToMiB, options.useSI), percentOf(shownSize, report.TotalSize))
+	return len(report.Files)
+}
@@ -0,0 +1,221 @@
+/*
+
+treemap.go
+
+Self-contained HTML treemap of file sizes by directory, used by the -oh-treemap cmd line option
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeNode - a directory or file in the treemap; the size of a directory is the sum of its children
+type treeNode struct {
+	Name     string      `json:"n"`
+	Size     int64       `json:"s"`
+	Children []*treeNode `json:"c,omitempty"`
+	index    map[string]*treeNode
+	entry    *FileStat
+}
+
+// child returns the child node with the given name, creating it when needed
+func (t *treeNode) child(name string) *treeNode {
+	if t.index == nil {
+		t.index = make(map[string]*treeNode)
+	}
+	c, ok := t.index[name]
+	if !ok {
+		c = &treeNode{Name: name}
+		t.index[name] = c
+		t.Children = append(t.Children, c)
+	}
+	return c
+}
+
+// sortChildren orders all children from largest to smallest, which the treemap layout expects
+func (t *treeNode) sortChildren() {
+	sort.Slice(t.Children, func(i, j int) bool {
+		if t.Children[i].Size != t.Children[j].Size {
+			return t.Children[i].Size > t.Children[j].Size
+		}
+		return t.Children[i].Name < t.Children[j].Name
+	})
+	for _, c := range t.Children {
+		c.sortChildren()
+	}
+}
+
+/*
+insert adds all path components of fullName below t, creating nodes when needed
+
+Args:
+    fullName: the file name; the volume name and leading separator are kept with the first path component
+
+Returns:
+    the nodes for each path component, with the node for fullName itself last; none when fullName is ., which is the root itself
+*/
+func (t *treeNode) insert(fullName string) []*treeNode {
+	name := filepath.Clean(fullName)
+	if "." == name {
+		return nil
+	}
+	volume := filepath.VolumeName(name)
+	rest := name[len(volume):]
+	prefix := volume
+	if strings.HasPrefix(rest, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+		rest = strings.TrimLeft(rest, string(os.PathSeparator))
+	}
+	parts := strings.Split(rest, string(os.PathSeparator))
+	parts[0] = prefix + parts[0]
+
+	var nodes []*treeNode
+	node := t
+	for _, part := range parts {
+		node = node.child(part)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// collapseRoot skips over leading directories that only have a single child, joining their names;
+// a directory which is itself one of the entries is kept as the root, so that its information is not lost
+func collapseRoot(root *treeNode) *treeNode {
+	for top := true; len(root.Children) == 1 && len(root.Children[0].Children) > 0 && nil == root.entry; top = false {
+		only := root.Children[0]
+		if !top {
+			only.Name = filepath.Join(root.Name, only.Name)
+		}
+		root = only
+	}
+	return root
+}
+
+/*
+buildTree creates a tree of path components for all regular files
+
+Args:
+    allEntries: a slice of all files; only regular files are added
+
+Returns:
+    the root node, whose size is the total size of all regular files
+*/
+func buildTree(allEntries []FileStat) *treeNode {
+	root := &treeNode{Name: "(all)"}
+	for _, e := range allEntries {
+		if "F" != e.FileType || e.Size <= 0 {
+			continue
+		}
+		root.Size += e.Size
+		for _, node := range root.insert(e.FullName) {
+			node.Size += e.Size
+		}
+	}
+
+	root = collapseRoot(root)
+	root.sortChildren()
+	return root
+}
+
+/*
+RenderTreemap outputs a self-contained HTML page with an interactive treemap of file sizes by directory
+No external resources are used; clicking a directory zooms into it and clicking the header zooms out
+
+Args:
+    allEntries: a slice of all files; only regular files are included
+*/
+func RenderTreemap(allEntries []FileStat) {
+	j, _ := json.Marshal(buildTree(allEntries))
+	fmt.Printf(treemapTemplate, string(j))
+}
+
+// treemapTemplate - the HTML page; %s is replaced with the JSON encoded tree
+const treemapTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fstat treemap</title>
+<style>
+body { margin: 0; font-family: sans-serif; font-size: 12px; }
+#path { padding: 6px; background: #333; color: #fff; cursor: pointer; }
+#map { position: absolute; top: 30px; left: 0; right: 0; bottom: 0; }
+.node { position: absolute; box-sizing: border-box; border: 1px solid #fff; overflow: hidden; padding: 2px; cursor: pointer; }
+.node:hover { border-color: #000; }
+</style>
+</head>
+<body>
+<div id="path"></div>
+<div id="map"></div>
+<script>
+var root = %s;
+var stack = [root];
+function human(n) {
+  var u = ["B", "KiB", "MiB", "GiB", "TiB"], i = 0;
+  while (n >= 1024 && i < u.length - 1) { n /= 1024; i++; }
+  return n.toFixed(i ? 1 : 0) + " " + u[i];
+}
+function color(i) { return "hsl(" + ((i * 47) %% 360) + ",55%%,70%%)"; }
+function worst(row, w, total) {
+  var s = 0, max = 0, min = Infinity;
+  row.forEach(function (r) { s += r; max = Math.max(max, r); min = Math.min(min, r); });
+  s = s / total;
+  return Math.max(w * w * max / total / (s * s), (s * s) / (w * w * min / total));
+}
+function layout(nodes, x, y, w, h) {
+  var total = 0, rects = [], i = 0;
+  nodes.forEach(function (n) { total += n.s; });
+  var area = w * h;
+  while (i < nodes.length) {
+    var side = Math.min(w, h), row = [nodes[i]], areas = [nodes[i].s / total * area];
+    var j = i + 1;
+    while (j < nodes.length) {
+      var next = areas.concat([nodes[j].s / total * area]);
+      if (worst(next, side, 1) > worst(areas, side, 1)) { break; }
+      areas = next; row.push(nodes[j]); j++;
+    }
+    var rowArea = areas.reduce(function (a, b) { return a + b; }, 0);
+    var thick = rowArea / side, offset = 0;
+    row.forEach(function (n, k) {
+      var len = areas[k] / thick;
+      if (w >= h) { rects.push([n, x, y + offset, thick, len]); } else { rects.push([n, x + offset, y, len, thick]); }
+      offset += len;
+    });
+    if (w >= h) { x += thick; w -= thick; } else { y += thick; h -= thick; }
+    total -= row.reduce(function (a, n) { return a + n.s; }, 0);
+    area = w * h;
+    i = j;
+  }
+  return rects;
+}
+function draw() {
+  var node = stack[stack.length - 1], map = document.getElementById("map");
+  document.getElementById("path").textContent = stack.map(function (n) { return n.n; }).join(" / ") + "  (" + human(node.s) + ")" + (stack.length > 1 ? "  [click to go up]" : "");
+  map.innerHTML = "";
+  var kids = node.c || [];
+  layout(kids, 0, 0, map.clientWidth, map.clientHeight).forEach(function (r, i) {
+    var d = document.createElement("div");
+    d.className = "node";
+    d.style.left = r[1] + "px"; d.style.top = r[2] + "px";
+    d.style.width = r[3] + "px"; d.style.height = r[4] + "px";
+    d.style.background = color(i);
+    d.textContent = r[0].n + " (" + human(r[0].s) + ")";
+    d.title = d.textContent;
+    if (r[0].c) { d.onclick = function () { stack.push(r[0]); draw(); }; }
+    map.appendChild(d);
+  });
+}
+document.getElementById("path").onclick = function () { if (stack.length > 1) { stack.pop(); draw(); } };
+window.onresize = draw;
+draw();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,392 @@
+/*
+
+tui.go
+
+Browse the entries in an interactive terminal UI, used by the -tui cmd line option
+
+Example: fstat -tui -r dirs.txt > marked.txt
+
+The entries are examined once and can then be sorted, filtered and explored without running fstat again.
+The terminal is used directly, so that STDOUT can be redirected: when quitting with q, the names of the marked
+entries are written to STDOUT, one per line.
+
+Keys:
+    Up, Down, PgUp, PgDn, Home, End (or k, j, g, G): move
+    Enter or Right (or l): show the entries in the selected directory
+    Backspace or Left (or h): go back to the previous directory
+    Space: mark or unmark the selected entry; a: mark or unmark every shown entry
+    /: filter by name as you type, using fuzzy matching as described in fuzzy.go; Enter keeps the filter and Esc clears it
+    s, d, n, t: sort by size, modified date, name or type; press again to reverse the order
+    q: quit and output the marked names; Ctrl-C: quit without any output
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// tuiBrowser - the state of the terminal UI
+type tuiBrowser struct {
+	screen     tcell.Screen
+	allEntries []FileStat
+	shown      []FileStat
+	dirs       []string
+	cursors    []int
+	cursor     int
+	top        int
+	filter     string
+	filtering  bool
+	sortKey    rune
+	ascending  bool
+	marked     map[string]bool
+	formatSize func(size int64) string
+	timeLayout string
+}
+
+/*
+RunTUI lets the user browse the entries until they quit
+
+Args:
+    allEntries: the entries to browse
+
+    addCommas, convertToMiB, useSI: how sizes are shown (-c, -m, -dec cmd line options)
+
+    timeLayout: how modified times are shown
+
+    onlyTypes: only show these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+Returns:
+    the names of the marked entries, in the order they were listed; nil when the user quits with Ctrl-C
+
+    an error when the terminal can not be used
+*/
+func RunTUI(allEntries []FileStat, addCommas bool, convertToMiB bool, useSI bool, timeLayout string, onlyTypes string) ([]string, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	defer screen.Fini()
+
+	b := &tuiBrowser{screen: screen, dirs: []string{""}, marked: make(map[string]bool), timeLayout: timeLayout}
+	b.formatSize = func(size int64) string {
+		return formatSize(size, addCommas, convertToMiB, useSI)
+	}
+	for _, e := range allEntries {
+		if !typeIncluded(onlyTypes, e.FileType) {
+			continue
+		}
+		b.allEntries = append(b.allEntries, e)
+	}
+	b.refresh()
+
+	for {
+		b.draw()
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if tcell.KeyCtrlC == ev.Key() {
+				return nil, nil
+			}
+			if b.filtering {
+				b.editFilter(ev)
+			} else if quit := b.handleKey(ev); quit {
+				return b.markedNames(), nil
+			}
+		}
+	}
+}
+
+// refresh - update the shown entries after the directory, filter or sort order changes
+func (b *tuiBrowser) refresh() {
+	dir := b.dirs[len(b.dirs)-1]
+	query := newFuzzyQuery(b.filter)
+	b.shown = b.shown[:0]
+	for _, e := range b.allEntries {
+		if len(dir) > 0 && filepath.Dir(e.FullName) != dir {
+			continue
+		}
+		if _, ok := query.score(e.FullName); !ok {
+			continue
+		}
+		b.shown = append(b.shown, e)
+	}
+
+	switch b.sortKey {
+	case 0:
+		if query != nil {
+			sortFuzzy(b.shown, query)
+		}
+	case 's':
+		sortSize(b.shown, b.ascending)
+	case 'd':
+		sortModTime(b.shown, b.ascending)
+	case 'n':
+		sortNameCaseInsensitive(b.shown, b.ascending)
+	case 't':
+		sortEntries(b.shown, func(x *FileStat, y *FileStat) bool {
+			if x.FileType != y.FileType {
+				return (x.FileType < y.FileType) == b.ascending
+			}
+			return x.FullName < y.FullName
+		})
+	}
+	b.moveTo(b.cursor)
+}
+
+// moveTo - select the entry at index, keeping it on the screen
+func (b *tuiBrowser) moveTo(index int) {
+	if index >= len(b.shown) {
+		index = len(b.shown) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	b.cursor = index
+	rows := b.rows()
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if b.cursor >= b.top+rows {
+		b.top = b.cursor - rows + 1
+	}
+	if b.top < 0 {
+		b.top = 0
+	}
+}
+
+// rows - the number of entries that fit on the screen, below the header and above the status line
+func (b *tuiBrowser) rows() int {
+	_, height := b.screen.Size()
+	if height < 4 {
+		return 1
+	}
+	return height - 3
+}
+
+/*
+handleKey acts on a key pressed while not filtering
+
+Args:
+    ev: the key
+
+Returns:
+    true when the user quits
+*/
+func (b *tuiBrowser) handleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		b.moveTo(b.cursor - 1)
+	case tcell.KeyDown:
+		b.moveTo(b.cursor + 1)
+	case tcell.KeyPgUp:
+		b.moveTo(b.cursor - b.rows())
+	case tcell.KeyPgDn:
+		b.moveTo(b.cursor + b.rows())
+	case tcell.KeyHome:
+		b.moveTo(0)
+	case tcell.KeyEnd:
+		b.moveTo(len(b.shown) - 1)
+	case tcell.KeyEnter, tcell.KeyRight:
+		b.open()
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyLeft:
+		b.back()
+	case tcell.KeyEscape:
+		if len(b.filter) > 0 {
+			b.filter = ""
+			b.refresh()
+		}
+	case tcell.KeyRune:
+		switch r := ev.Rune(); r {
+		case 'q':
+			return true
+		case 'k':
+			b.moveTo(b.cursor - 1)
+		case 'j':
+			b.moveTo(b.cursor + 1)
+		case 'g':
+			b.moveTo(0)
+		case 'G':
+			b.moveTo(len(b.shown) - 1)
+		case 'l':
+			b.open()
+		case 'h':
+			b.back()
+		case ' ':
+			if b.cursor < len(b.shown) {
+				name := b.shown[b.cursor].FullName
+				b.marked[name] = !b.marked[name]
+				b.moveTo(b.cursor + 1)
+			}
+		case 'a':
+			allMarked := true
+			for _, e := range b.shown {
+				allMarked = allMarked && b.marked[e.FullName]
+			}
+			for _, e := range b.shown {
+				b.marked[e.FullName] = !allMarked
+			}
+		case '/':
+			b.filtering = true
+		case 's', 'd', 'n', 't':
+			if r == b.sortKey {
+				b.ascending = !b.ascending
+			} else {
+				// largest and newest first, otherwise alphabetical
+				b.sortKey, b.ascending = r, 'n' == r || 't' == r
+			}
+			b.refresh()
+		}
+	}
+	return false
+}
+
+// editFilter - act on a key pressed while typing a filter, updating the shown entries as it changes
+func (b *tuiBrowser) editFilter(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		b.filtering = false
+	case tcell.KeyEscape:
+		b.filtering = false
+		b.filter = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if r := []rune(b.filter); len(r) > 0 {
+			b.filter = string(r[:len(r)-1])
+		}
+	case tcell.KeyRune:
+		b.filter += string(ev.Rune())
+	default:
+		return
+	}
+	b.cursor, b.top = 0, 0
+	b.refresh()
+}
+
+// open - show the entries in the selected directory
+func (b *tuiBrowser) open() {
+	if b.cursor >= len(b.shown) || "D" != b.shown[b.cursor].FileType {
+		return
+	}
+	b.dirs = append(b.dirs, b.shown[b.cursor].FullName)
+	b.cursors = append(b.cursors, b.cursor)
+	b.cursor, b.top = 0, 0
+	b.refresh()
+}
+
+// back - return to the previous directory, selecting the directory that was opened
+func (b *tuiBrowser) back() {
+	if len(b.dirs) < 2 {
+		return
+	}
+	b.dirs = b.dirs[:len(b.dirs)-1]
+	b.cursor = b.cursors[len(b.cursors)-1]
+	b.cursors = b.cursors[:len(b.cursors)-1]
+	b.refresh()
+}
+
+// markedNames - the names of the marked entries, in the order they were listed
+func (b *tuiBrowser) markedNames() []string {
+	names := make([]string, 0, len(b.marked))
+	for _, e := range b.allEntries {
+		if b.marked[e.FullName] {
+			names = append(names, e.FullName)
+		}
+	}
+	return names
+}
+
+/*
+drawText writes text at a screen position, clipped to the screen width
+
+Args:
+    x, y: the position
+
+    style: the colors and attributes
+
+    text: the text, where wide characters use two cells
+
+Returns:
+    the x position following the text
+*/
+func (b *tuiBrowser) drawText(x int, y int, style tcell.Style, text string) int {
+	width, _ := b.screen.Size()
+	last := -1
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if 0 == w && last >= 0 {
+			// a combining character, such as an accent, is drawn in the same cell as the character before it
+			mainc, combc, _, _ := b.screen.GetContent(last, y)
+			b.screen.SetContent(last, y, mainc, append(combc, r), style)
+			continue
+		}
+		if x+w > width {
+			break
+		}
+		b.screen.SetContent(x, y, r, nil, style)
+		last = x
+		x += w
+	}
+	return x
+}
+
+// draw - output the header, the shown entries and the status line
+func (b *tuiBrowser) draw() {
+	b.screen.Clear()
+	width, height := b.screen.Size()
+	timeWidth := len(b.timeLayout)
+	line := func(mark string, modTime string, size string, fileType string, name string) string {
+		return fmt.Sprintf("%1s %-*s  %15s  %-4s  %s", mark, timeWidth, modTime, size, fileType, name)
+	}
+
+	header := tcell.StyleDefault.Bold(true)
+	b.drawText(0, 0, header, line("", "Mod Time", "Size", "Type", "Name"))
+	for i := 0; i < b.rows() && b.top+i < len(b.shown); i++ {
+		e := b.shown[b.top+i]
+		style := tcell.StyleDefault
+		mark := ""
+		if b.marked[e.FullName] {
+			mark = "*"
+			style = style.Foreground(tcell.ColorYellow)
+		}
+		if b.top+i == b.cursor {
+			style = style.Reverse(true)
+		}
+		x := b.drawText(0, i+1, style, line(mark, e.ModTime.Format(b.timeLayout), b.formatSize(e.Size), e.FileType, e.FullName))
+		if b.top+i == b.cursor {
+			for ; x < width; x++ {
+				b.screen.SetContent(x, i+1, ' ', nil, style)
+			}
+		}
+	}
+	if 0 == len(b.shown) {
+		b.drawText(0, 1, tcell.StyleDefault.Dim(true), "(no entries)")
+	}
+
+	var total int64
+	for _, e := range b.shown {
+		if "F" == e.FileType {
+			total += e.Size
+		}
+	}
+	status := fmt.Sprintf("%d entries  %s  marked: %d", len(b.shown), b.formatSize(total), len(b.markedNames()))
+	if dir := b.dirs[len(b.dirs)-1]; len(dir) > 0 {
+		status += "  dir: " + dir
+	}
+	if b.filtering {
+		status += "  filter: " + b.filter + "_"
+	} else if len(b.filter) > 0 {
+		status += "  filter: " + b.filter
+	}
+	b.drawText(0, height-2, header, status)
+	b.drawText(0, height-1, tcell.StyleDefault.Dim(true), "arrows move  Enter open  Bksp back  Space mark  a all  / filter  s d n t sort  q quit")
+	b.screen.Show()
+}
@@ -0,0 +1,110 @@
+/*
+
+types.go
+
+The type of each entry, shown in the Type column, and the -type cmd line option
+
+Example: fstat -type f,l -r dirs.txt
+
+-type accepts any combination of the lower-cased codes below, except for ?. The -if, -id, -il, -ip and -is
+cmd line options are shortcuts for a single type, and can be combined with each other and with -type.
+
+Types:
+    F  regular file         D  directory            L  symbolic link
+    P  named pipe (FIFO)    S  socket               C  character device
+    B  block device         ?  anything else
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// typeCodes - every type, in the order they are listed in totals and summaries
+var typeCodes = []string{"F", "D", "L", "P", "S", "C", "B", "?"}
+
+// typeNames - a description of each type, by its code
+var typeNames = map[string]string{
+	"F": "file",
+	"D": "directory",
+	"L": "symbolic link",
+	"P": "named pipe",
+	"S": "socket",
+	"C": "character device",
+	"B": "block device",
+	"?": "other",
+}
+
+// typePlurals - the plural of each description in typeNames, used in the -t totals
+var typePlurals = map[string]string{
+	"F": "files",
+	"D": "directories",
+	"L": "symbolic links",
+	"P": "named pipes",
+	"S": "sockets",
+	"C": "character devices",
+	"B": "block devices",
+	"?": "other entries",
+}
+
+// fileType - the type code of a file mode, such as "F" for a regular file
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "F"
+	case mode.IsDir():
+		return "D"
+	case mode&os.ModeSymlink != 0:
+		return "L"
+	case mode&os.ModeNamedPipe != 0:
+		return "P"
+	case mode&os.ModeSocket != 0:
+		return "S"
+	case mode&os.ModeCharDevice != 0:
+		return "C"
+	case mode&os.ModeDevice != 0:
+		return "B"
+	}
+	return "?"
+}
+
+/*
+parseTypes validates the -type cmd line option, and combines it with the single type options
+
+Args:
+    typeList: comma separated type codes, such as "f,l"; case is ignored
+
+    selected: the types set by -if, -id, -il, -ip and -is, by code
+
+Returns:
+    the codes of every selected type, in the order of typeCodes, such as "FL"; empty to include all types
+
+    an error when typeList has an unknown type
+*/
+func parseTypes(typeList string, selected map[string]bool) (string, error) {
+	if len(typeList) > 0 {
+		for _, t := range strings.Split(typeList, ",") {
+			code := strings.ToUpper(strings.TrimSpace(t))
+			if _, ok := typeNames[code]; !ok || "?" == code {
+				return "", fmt.Errorf("unknown -type: %s; use a comma separated list of: f, d, l, p, s, c, b", strings.TrimSpace(t))
+			}
+			selected[code] = true
+		}
+	}
+	var onlyTypes string
+	for _, code := range typeCodes {
+		if selected[code] {
+			onlyTypes += code
+		}
+	}
+	return onlyTypes, nil
+}
+
+// typeIncluded - true when onlyTypes is empty, or contains fileType
+func typeIncluded(onlyTypes string, fileType string) bool {
+	return 0 == len(onlyTypes) || strings.Contains(onlyTypes, fileType)
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+/*
+
+unc_other.go
+
+Mapped network drives only exist on Windows, so the -unc cmd line option is not supported
+
+*/
+
+package main
+
+// uncSupported - mapped drives can be resolved on this OS
+const uncSupported = false
+
+// mappedDriveTarget - always false, since there are no mapped drives
+func mappedDriveTarget(drive string) (string, bool) {
+	return "", false
+}
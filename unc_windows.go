@@ -0,0 +1,44 @@
+//go:build windows
+
+/*
+
+unc_windows.go
+
+Resolve mapped network drives to the UNC path of their share, used by the -unc cmd line option
+
+*/
+
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// uncSupported - mapped drives can be resolved on this OS
+const uncSupported = true
+
+// procWNetGetConnection - returns the remote name of a mapped network drive
+var procWNetGetConnection = windows.NewLazySystemDLL("mpr.dll").NewProc("WNetGetConnectionW")
+
+// mappedDriveTarget - the UNC path of the share mapped to drive, such as \\server\share for Z:; false when drive is not a mapped network drive
+func mappedDriveTarget(drive string) (string, bool) {
+	local, err := windows.UTF16PtrFromString(drive)
+	if err != nil {
+		return "", false
+	}
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		size := uint32(len(buf))
+		r, _, _ := procWNetGetConnection.Call(uintptr(unsafe.Pointer(local)), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+		if uintptr(windows.ERROR_MORE_DATA) == r && int(size) > len(buf) {
+			buf = make([]uint16, size)
+			continue
+		}
+		if r != 0 {
+			return "", false
+		}
+		return windows.UTF16ToString(buf), true
+	}
+}
@@ -0,0 +1,132 @@
+/*
+
+units.go
+
+Parse sizes and ages given with unit suffixes, such as 10MB or 30d
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeSuffixes - multipliers for size values; K, M, G... and KiB, MiB, GiB... are binary while KB, MB, GB... are decimal
+var sizeSuffixes = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+	"p":   1 << 50,
+	"pib": 1 << 50,
+	"pb":  1e15,
+	"e":   1 << 60,
+	"eib": 1 << 60,
+	"eb":  1e18,
+}
+
+// ageSuffixes - durations for age values; a month is 30 days and a year is 365 days
+var ageSuffixes = map[string]time.Duration{
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// splitNumber - separate a value such as "1.5GB" into its number and lower-cased suffix
+func splitNumber(value string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid number: %s", value)
+	}
+	return number, strings.ToLower(strings.TrimSpace(value[i:])), nil
+}
+
+/*
+parseSize converts a size with an optional unit suffix into bytes
+
+Args:
+    value: such as 500, 500K, 1.5GiB or 10MB; see sizeSuffixes
+
+Returns:
+    the number of bytes, or an error when value is not valid or does not fit in an int64
+*/
+func parseSize(value string) (int64, error) {
+	number, suffix, err := splitNumber(value)
+	if err != nil {
+		return 0, err
+	}
+	multiplier, ok := sizeSuffixes[suffix]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit: %s", value)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is already out of range
+	size := number * multiplier
+	if math.IsNaN(size) || size < 0 || size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size out of range: %s", value)
+	}
+	return int64(size), nil
+}
+
+/*
+parseAge converts an age with a unit suffix into a time.Duration
+
+Args:
+    value: such as 90s, 15m, 12h, 30d, 2w, 6mo or 1y; see ageSuffixes
+
+Returns:
+    the duration, or an error when value is not valid or does not fit in a time.Duration
+*/
+func parseAge(value string) (time.Duration, error) {
+	number, suffix, err := splitNumber(value)
+	if err != nil {
+		return 0, err
+	}
+	unit, ok := ageSuffixes[suffix]
+	if !ok {
+		return 0, fmt.Errorf("invalid age unit: %s", value)
+	}
+	age := number * float64(unit)
+	if math.IsNaN(age) || age < 0 || age >= math.MaxInt64 {
+		return 0, fmt.Errorf("age out of range: %s", value)
+	}
+	return time.Duration(age), nil
+}
+
+// sizeValue - a flag.Value for sizes with an optional unit suffix; used by -szs and -szl
+type sizeValue int64
+
+func (s *sizeValue) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *sizeValue) Set(value string) error {
+	size, err := parseSize(value)
+	*s = sizeValue(size)
+	return err
+}
@@ -0,0 +1,256 @@
+/*
+
+walk.go
+
+Recursively expand directories given in the list of files, used by the -r, -mindepth, -maxdepth and -L cmd line options
+
+Example: fstat -r -L dirs.txt
+
+With -L, symbolic links to directories are walked as if they were directories. A link which leads back to a
+directory that is already being walked, such as: ln -s .. loop, is reported as an error and not followed, so
+that the walk always ends. The entries beneath a followed link are listed by their path through the link.
+
+*/
+
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// walkBufferSize - the number of names which walkNames() finds ahead of the names being examined
+const walkBufferSize = 1000
+
+/*
+WalkAllFilenames replaces each directory in allFilenames with the directory and everything beneath it
+As with find(1), each listed name is at depth 0
+
+Args:
+    ctx: once done, the walk stops and the names found so far are returned, see cancel.go (-timeout cmd line option)
+
+    allFilenames: the listed file names
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    minDepth: only include entries at this depth or deeper (-mindepth cmd line option)
+
+    maxDepth: when not negative, do not descend below this depth (-maxdepth cmd line option)
+
+    ignorePatterns: directories matching any of these patterns are not descended into, see ignore.go
+
+    sameDevice: when set, do not descend into directories on other file systems (cmd line option: -xdev)
+
+    followLinks: when set, also descend into symbolic links to directories, except for those which form a loop
+                 (cmd line option: -L)
+
+    netfs: when not nil, do not descend into network file systems which do not respond in time, see netfs.go
+           (-nettimeout cmd line option)
+
+Returns:
+    the expanded slice of file names
+*/
+func WalkAllFilenames(ctx context.Context, allFilenames []string, quiet bool, minDepth int, maxDepth int, ignorePatterns []string, sameDevice bool, followLinks bool, netfs *netFileSystems) []string {
+	var allWalked []string
+	walkFilenames(ctx, allFilenames, quiet, minDepth, maxDepth, ignorePatterns, sameDevice, followLinks, netfs, func(fname string) {
+		allWalked = append(allWalked, fname)
+	})
+	return allWalked
+}
+
+/*
+walkNames walks allFilenames in the background, so that each name can be examined as soon as it is found
+instead of once the whole walk is done; when ctx is done, the entries examined so far are still output
+
+Args:
+    ctx, allFilenames, quiet, minDepth, maxDepth, ignorePatterns, sameDevice, followLinks, netfs: passed to
+    WalkAllFilenames()
+
+Returns:
+    a function which returns each name in turn, for use with GetFileInfo(); it returns false after the last name
+*/
+func walkNames(ctx context.Context, allFilenames []string, quiet bool, minDepth int, maxDepth int, ignorePatterns []string, sameDevice bool, followLinks bool, netfs *netFileSystems) func() (string, bool) {
+	walked := make(chan string, walkBufferSize)
+	go func() {
+		defer close(walked)
+		walkStart := time.Now()
+		count := 0
+		walkFilenames(ctx, allFilenames, quiet, minDepth, maxDepth, ignorePatterns, sameDevice, followLinks, netfs, func(fname string) {
+			// GetFileInfo() stops reading once ctx is done
+			select {
+			case walked <- fname:
+				count++
+			case <-ctx.Done():
+			}
+		})
+		logVerbose("walked %d file names in %s", count, time.Since(walkStart).Round(time.Microsecond))
+	}()
+	return func() (string, bool) {
+		fname, ok := <-walked
+		return fname, ok
+	}
+}
+
+/*
+walkFilenames calls found with each name returned by WalkAllFilenames(), in the same order, as soon as it is found
+
+Args:
+    ctx, allFilenames, quiet, minDepth, maxDepth, ignorePatterns, sameDevice, followLinks, netfs: see
+    WalkAllFilenames()
+
+    found: called with each name
+*/
+func walkFilenames(ctx context.Context, allFilenames []string, quiet bool, minDepth int, maxDepth int, ignorePatterns []string, sameDevice bool, followLinks bool, netfs *netFileSystems, found func(fname string)) {
+	sep := string(os.PathSeparator)
+
+	for _, root := range allFilenames {
+		if ctx.Err() != nil {
+			break
+		}
+		// remote names have already been walked, see ExpandRemoteFilenames()
+		if isRemote(root) {
+			found(root)
+			continue
+		}
+		info, err := netfs.lstat(root)
+		if err == nil && followLinks && info.Mode()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(root)
+		}
+		if err != nil || !info.IsDir() {
+			// let GetFileInfo() report any errors
+			if 0 == minDepth {
+				found(root)
+			}
+			continue
+		}
+		device := getDevice(root, info)
+		rootDepth := strings.Count(filepath.Clean(root), sep)
+		if strings.HasSuffix(filepath.Clean(root), sep) {
+			// such as / or C:\
+			rootDepth--
+		}
+
+		var visit fs.WalkDirFunc
+		visit = func(p string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				if !quiet {
+					logError("%s", err)
+				}
+				return nil
+			}
+			depth := strings.Count(filepath.Clean(p), sep) - rootDepth
+			if p == root {
+				depth = 0
+			}
+			// a symbolic link to a directory, when followed, is treated the same as a directory
+			var target os.FileInfo
+			if followLinks && d.Type()&fs.ModeSymlink != 0 {
+				if info, err := os.Stat(p); err == nil && info.IsDir() {
+					target = info
+				}
+			}
+			if (d.IsDir() || target != nil) && p != root {
+				if len(ignorePatterns) > 0 && isIgnored(p, ignorePatterns) {
+					logVerbose("skipped %s: %s: matches a pattern", p, ignoreFileName)
+					return filepath.SkipDir
+				}
+				if sameDevice {
+					dirInfo := target
+					if nil == dirInfo {
+						dirInfo, err = d.Info()
+					}
+					if err == nil && getDevice(p, dirInfo) != device {
+						logVerbose("skipped %s: -xdev: on another file system", p)
+						return filepath.SkipDir
+					}
+				}
+			}
+			if depth >= minDepth {
+				found(p)
+			} else {
+				logDebug("skipped %s: -mindepth: depth is %d", p, depth)
+			}
+			if (d.IsDir() || target != nil) && maxDepth >= 0 && depth >= maxDepth {
+				logDebug("not descending into %s: -maxdepth: depth is %d", p, depth)
+				return filepath.SkipDir
+			}
+			if d.IsDir() {
+				if err := netfs.readable(p); err != nil {
+					if !quiet {
+						logError("%s: %s", p, err)
+					}
+					return filepath.SkipDir
+				}
+				logDebug("walking %s", p)
+			}
+			if target != nil {
+				if ancestor, ok := findLinkLoop(root, p, target); ok && p != root {
+					if !quiet {
+						dest, _ := os.Readlink(p)
+						logError("symbolic link loop: %s -> %s: already walking %s", p, dest, ancestor)
+					}
+					return nil
+				}
+				logDebug("walking %s: following symbolic link", p)
+				// a trailing separator makes WalkDir() read the directory the link points to; its names are
+				// joined to the link's path, and the link itself has already been listed
+				linkDir := p + sep
+				_ = filepath.WalkDir(linkDir, func(q string, e fs.DirEntry, err error) error {
+					if q == linkDir && err == nil {
+						return nil
+					}
+					return visit(q, e, err)
+				})
+			}
+			return nil
+		}
+		_ = filepath.WalkDir(root, visit)
+	}
+}
+
+// fileID - identifies a file regardless of the path used to reach it, such as its device and inode
+type fileID struct {
+	device uint64
+	inode  uint64
+}
+
+/*
+findLinkLoop checks if a symbolic link to a directory leads back to a directory that contains it,
+which would otherwise be walked forever
+
+Args:
+    root: the listed directory being walked
+
+    link: the path of the symbolic link, beneath root
+
+    target: the directory the link points to, from os.Stat()
+
+Returns:
+    the path of the directory containing link which is the same as target, such as root or one of its
+    subdirectories; and true when there is a loop
+*/
+func findLinkLoop(root string, link string, target os.FileInfo) (string, bool) {
+	targetID, ok := getFileID(link, target)
+	if !ok {
+		return "", false
+	}
+	stop := filepath.Clean(root)
+	for dir := filepath.Dir(link); ; dir = filepath.Dir(dir) {
+		// os.Stat() follows any links in dir, so that each directory is identified as it is being walked
+		if info, err := os.Stat(dir); err == nil {
+			if id, ok := getFileID(dir, info); ok && id == targetID {
+				return dir, true
+			}
+		}
+		if dir == stop || dir == filepath.Dir(dir) || len(dir) <= len(stop) {
+			return "", false
+		}
+	}
+}
@@ -0,0 +1,254 @@
+/*
+
+watch.go
+
+Report changes to the listed files as they happen, used by the -watch cmd line option
+
+After the regular output, the directories holding the listed entries are watched for create, modify and delete
+events. Created and modified files are examined with the same filter options as the initial listing; deleted
+files are only reported when they were previously listed. New files beneath a directory are only reported
+with -r, in which case newly created directories are also watched.
+
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchEvent - a single -watch event, output by -ojl; the entry is nil for deleted files
+type watchEvent struct {
+	Event    string    `json:"event"`
+	Time     time.Time `json:"time"`
+	FullName string    `json:"fullname"`
+	*FileStat
+}
+
+// fileWatcher - the state kept by WatchEntries while events are received
+type fileWatcher struct {
+	watcher   *fsnotify.Watcher
+	known     map[string]FileStat
+	listed    map[string]bool
+	trees     map[string]bool
+	recursive bool
+	quiet     bool
+	scan      func(nextName func() (string, bool)) []FileStat
+	render    func(event string, fname string, e *FileStat)
+	onlyTypes string
+}
+
+/*
+WatchEntries outputs file system events for the listed entries until the program is interrupted
+
+Args:
+    allFilenames: every examined name, including those removed by the filter options
+
+    allEntries: the entries that were output
+
+    recursive: when set, report new entries beneath every directory and watch new directories (-r cmd line option)
+
+    scan: examines a list of files with all of the filter options, see main()
+
+    quiet: when set, errors are not reported to STDERR (cmd line option: -q)
+
+    outputJSONL: when set, output each event as a JSON object instead of a table row (-ojl cmd line option)
+
+    loc: when not nil, convert modification times to this time zone (-utc and -tz cmd line options)
+
+    addCommas, convertToMiB, useSI: how sizes are shown (-c, -m, -dec cmd line options)
+
+    timeLayout: how modified times are shown
+
+    onlyTypes: only output these types of entries, such as "FL"; empty for all types (-type cmd line option)
+
+    notifyURL: when not empty, also send each event to this webhook (-notify cmd line option)
+*/
+func WatchEntries(allFilenames []string, allEntries []FileStat, recursive bool, scan func(nextName func() (string, bool)) []FileStat, quiet bool, outputJSONL bool, loc *time.Location, addCommas bool, convertToMiB bool, useSI bool, timeLayout string, onlyTypes string, notifyURL string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logError("unable to watch files: %s", err)
+		os.Exit(1)
+	}
+	defer watcher.Close()
+
+	w := &fileWatcher{watcher: watcher, known: make(map[string]FileStat, len(allEntries)), listed: make(map[string]bool, len(allEntries)), trees: make(map[string]bool), recursive: recursive, quiet: quiet, scan: scan, onlyTypes: onlyTypes}
+	if outputJSONL {
+		enc := json.NewEncoder(os.Stdout)
+		w.render = func(event string, fname string, e *FileStat) {
+			if e != nil && loc != nil {
+				e.ModTime = e.ModTime.In(loc)
+				commitTimeIn(e, loc)
+			}
+			if err := enc.Encode(watchEvent{Event: event, Time: time.Now(), FullName: fname, FileStat: e}); err != nil {
+				logError("%s", err)
+				os.Exit(1)
+			}
+		}
+	} else {
+		fmt.Printf("\n%-19s  %-6s  %-4s  %15s  %-19s  %s\n", "Time", "Event", "Type", "Size", "Mod Time", "Name")
+		w.render = func(event string, fname string, e *FileStat) {
+			var fileType, size, modTime string
+			if e != nil {
+				if loc != nil {
+					e.ModTime = e.ModTime.In(loc)
+				}
+				fileType, size, modTime = e.FileType, formatSize(e.Size, addCommas, convertToMiB, useSI), e.ModTime.Format(timeLayout)
+			}
+			fmt.Printf("%-19s  %-6s  %-4s  %15s  %-19s  %s\n", time.Now().Format(timeLayout), event, fileType, size, modTime, fname)
+		}
+	}
+
+	if len(notifyURL) > 0 {
+		output := w.render
+		w.render = func(event string, fname string, e *FileStat) {
+			output(event, fname, e)
+			PostNotification(notifyURL, notifyPayload{Text: fmt.Sprintf("fstat: %s %s", event, fname), Event: &watchEvent{Event: event, Time: time.Now(), FullName: fname, FileStat: e}}, quiet)
+		}
+	}
+
+	for _, e := range allEntries {
+		w.known[e.FullName] = e
+		w.listed[e.FullName] = true
+	}
+	watched := make(map[string]bool)
+	for _, fname := range allFilenames {
+		dir := filepath.Dir(fname)
+		if recursive {
+			if e, ok := w.known[fname]; ok && "D" == e.FileType {
+				dir = fname
+			} else if info, err := os.Lstat(fname); err == nil && info.IsDir() {
+				dir = fname
+			}
+			w.trees[dir] = true
+		}
+		if !watched[dir] {
+			watched[dir] = true
+			w.add(dir)
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			if !quiet {
+				logError("%s", err)
+			}
+		}
+	}
+}
+
+// add - start watching dir
+func (w *fileWatcher) add(dir string) {
+	if err := w.watcher.Add(dir); err != nil && !w.quiet {
+		logError("unable to watch %s: %s", dir, err)
+	}
+}
+
+// inScope - true when events for fname should be reported, which includes a listed file that is deleted and created again
+func (w *fileWatcher) inScope(fname string) bool {
+	return w.listed[fname] || w.recursive && w.trees[filepath.Dir(fname)]
+}
+
+// shown - true when e is not removed by the -type cmd line option
+func (w *fileWatcher) shown(e *FileStat) bool {
+	return typeIncluded(w.onlyTypes, e.FileType)
+}
+
+/*
+handle reports a single file system event
+
+Args:
+    event: received from the watcher; a rename is reported as a delete, followed by a create of the new name
+*/
+func (w *fileWatcher) handle(event fsnotify.Event) {
+	fname := event.Name
+	if !w.inScope(fname) {
+		return
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		e, ok := w.known[fname]
+		if !ok {
+			return
+		}
+		delete(w.known, fname)
+		if w.trees[fname] {
+			delete(w.trees, fname)
+		}
+		if w.shown(&e) {
+			w.render("delete", fname, nil)
+		}
+		return
+	}
+
+	info, err := os.Lstat(fname)
+	if err != nil {
+		// already removed again, which is reported by its own event
+		return
+	}
+	kind := "modify"
+	if event.Has(fsnotify.Create) {
+		kind = "create"
+	}
+	w.update(kind, fname)
+
+	if w.recursive && info.IsDir() && !w.trees[fname] {
+		// also report anything created before the new directory was watched
+		w.trees[fname] = true
+		w.add(fname)
+		_ = filepath.WalkDir(fname, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && p != fname {
+				if d.IsDir() {
+					w.trees[p] = true
+					w.add(p)
+				}
+				w.update("create", p)
+			}
+			return nil
+		})
+	}
+}
+
+/*
+update examines fname again and reports it when it passes the filter options and has changed
+
+Args:
+    kind: the event, either: create or modify
+
+    fname: the file name
+*/
+func (w *fileWatcher) update(kind string, fname string) {
+	entries := w.scan(sliceNames([]string{fname}))
+	if 0 == len(entries) {
+		return
+	}
+	e := entries[0]
+	old, ok := w.known[fname]
+	if ok && old.Size == e.Size && old.ModTime.Equal(e.ModTime) && old.Mode == e.Mode {
+		// such as repeated write events while a file is being written
+		return
+	}
+	if !ok {
+		kind = "create"
+	}
+	w.known[fname] = e
+	if w.shown(&e) {
+		w.render(kind, fname, &e)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+
+webdav.go
+
+Examine files on WebDAV servers with PROPFIND requests, for names such as webdavs://user@host/path, see remote.go
+
+Example: echo webdavs://alice@dav.example.com/Shared | fstat -r -where 'age > 3y'
+Example: FSTAT_WEBDAV_PASSWORD=... fstat -f 'webdavs://alice@files.example.com/remote.php/dav/files/alice' -r -du
+
+webdavs:// uses HTTPS and webdav:// uses HTTP. With a user name, the password is read from the
+FSTAT_WEBDAV_PASSWORD environment variable, or from the name when given as user:password@host, which other users
+of the computer may see; the password is removed from the names which are output. Redirects are only followed on
+the same server, with the same protocol. Each directory is listed with a single Depth: 1 request. WebDAV does not
+have permission bits, owners or access times, so the mode only shows the type, the owner and group are empty, and
+the access time is the modified time.
+
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webdavPasswordVariable - the environment variable with the password of the user given in a remote name
+const webdavPasswordVariable = envPrefix + "WEBDAV_PASSWORD"
+
+// webdavTimeout - the longest time to wait for the server to start replying to each request
+const webdavTimeout = 30 * time.Second
+
+// webdavMaxRedirects - the most redirects which are followed for each request, such as from /dir to /dir/
+const webdavMaxRedirects = 5
+
+// webdavPropfind - the body of each PROPFIND request, which only asks for the properties that are used
+const webdavPropfind = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/></prop></propfind>`
+
+// webdavMultistatus - the reply to a PROPFIND request, with one response for each resource
+type webdavMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// webdavClient - the server of remote names, with the user and password of each request
+type webdavClient struct {
+	// once done, each request is cancelled
+	ctx      context.Context
+	client   *http.Client
+	base     url.URL
+	user     string
+	password string
+}
+
+/*
+dialWebDAV prepares the requests to a server; no request is sent until a path is examined
+
+Args:
+    ctx: once done, each request is cancelled
+
+    u: the remote name, where only the protocol, user, password, host and port are used
+
+Returns:
+    the client
+*/
+func dialWebDAV(ctx context.Context, u *url.URL) (remoteLister, error) {
+	c := &webdavClient{ctx: ctx, base: url.URL{Scheme: "https", Host: u.Host}}
+	if "webdav" == strings.ToLower(u.Scheme) {
+		c.base.Scheme = "http"
+	}
+	if u.User != nil {
+		c.user = u.User.Username()
+		password, ok := u.User.Password()
+		if !ok {
+			password = os.Getenv(webdavPasswordVariable)
+		}
+		c.password = password
+	}
+
+	// a redirect to a GET request would not return the properties, so each redirect is followed by propfind()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = webdavTimeout
+	c.client = &http.Client{Transport: transport, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	return c, nil
+}
+
+/*
+propfind requests the properties of a resource
+
+Args:
+    p: the slash separated path of the resource
+
+    depth: "0" for only the resource, or "1" to include the entries of a collection
+
+Returns:
+    the stat results of each resource, keyed by its path, with any trailing slash removed;
+    or an error when the server does not reply with the properties
+*/
+func (c *webdavClient) propfind(p string, depth string) (map[string]statRecord, error) {
+	target := c.base
+	target.Path = p
+	location := target.String()
+
+	var resp *http.Response
+	for redirects := 0; ; redirects++ {
+		req, err := http.NewRequestWithContext(c.ctx, "PROPFIND", location, strings.NewReader(webdavPropfind))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Depth", depth)
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		req.Header.Set("User-Agent", "fstat/"+version)
+		if len(c.user) > 0 {
+			req.SetBasicAuth(c.user, c.password)
+		}
+		if resp, err = c.client.Do(req); err != nil {
+			return nil, err
+		}
+		next, err := resp.Location()
+		if resp.StatusCode/100 != 3 || err != nil || redirects == webdavMaxRedirects {
+			break
+		}
+		resp.Body.Close()
+		// the password is sent with every request, so it is never sent to another server or over plain HTTP
+		if !strings.EqualFold(next.Scheme, c.base.Scheme) || !strings.EqualFold(next.Host, c.base.Host) {
+			return nil, fmt.Errorf("PROPFIND: redirected to another server: %s://%s", next.Scheme, next.Host)
+		}
+		location = next.String()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("PROPFIND: %s", resp.Status)
+	}
+
+	var reply webdavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("PROPFIND: %w", err)
+	}
+	records := make(map[string]statRecord)
+	for _, r := range reply.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200") {
+				continue
+			}
+			var rec statRecord
+			if ps.Prop.ResourceType.Collection != nil {
+				rec.Mode = os.ModeDir
+			}
+			if size, err := strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64); err == nil {
+				rec.Size, rec.Allocated = size, size
+			}
+			if modified, err := http.ParseTime(strings.TrimSpace(ps.Prop.LastModified)); err == nil {
+				rec.ModTime, rec.Access = modified, modified
+			}
+			records[path.Clean("/"+href.Path)] = rec
+		}
+	}
+	return records, nil
+}
+
+// lstat - the stat results of a resource; WebDAV does not have symbolic links
+func (c *webdavClient) lstat(p string) (statRecord, error) {
+	records, err := c.propfind(p, "0")
+	if err != nil {
+		return statRecord{}, err
+	}
+	if rec, ok := records[path.Clean("/"+p)]; ok {
+		return rec, nil
+	}
+	// the server may name the resource differently, such as after a redirect
+	for _, rec := range records {
+		return rec, nil
+	}
+	return statRecord{}, fmt.Errorf("PROPFIND: no properties returned")
+}
+
+// readDir - the names and stat results of the entries of a collection, sorted by name
+func (c *webdavClient) readDir(p string) ([]string, map[string]statRecord, error) {
+	dir := path.Clean("/" + p)
+	records, err := c.propfind(strings.TrimSuffix(dir, "/")+"/", "1")
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	children := make(map[string]statRecord)
+	for href, rec := range records {
+		if href == dir || path.Dir(href) != dir {
+			continue
+		}
+		name := path.Base(href)
+		names = append(names, name)
+		children[name] = rec
+	}
+	sort.Strings(names)
+	return names, children, nil
+}
+
+// close - close any idle connections
+func (c *webdavClient) close() {
+	c.client.CloseIdleConnections()
+}
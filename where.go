@@ -0,0 +1,363 @@
+/*
+
+where.go
+
+A small filter expression language, used by the -where cmd line option
+
+Example: -where 'size > 10MB && ext == ".log" && age > 30d'
+
+Fields:
+    name    base file name                   path   full file name
+    ext     lower-cased extension or ""      type   F, D, L, P, S, C, B or ?, see types.go
+    size    bytes, such as: 500K, 10MB       age    time since last modified, such as: 12h, 30d, 1y
+    owner   user that owns the file          group  group that owns the file
+    mode    such as: -rw-r--r--              class  text or binary, for files, see textbin.go
+    interpreter  the #! line of executable scripts, such as: /usr/bin/env python3, see shebang.go
+    width   image width in pixels            height image height in pixels, see imgsize.go
+    duration  of audio and video, such as: 90s, 5m, 2h, see media.go
+    pages   number of pages of PDF files, see pdf.go
+    commit  hash of the last commit          author author of the last commit, see gitlog.go
+    committed  time since the last commit, such as: 30d, 2y; never matches entries without a commit
+
+Operators:
+    == != < <= > >=    compare a field to a value; strings only support == and !=
+    =~ !~              match a string field against a regular expression
+    && || ! ( )        combine comparisons
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whereFields - all fields allowed in a -where expression; true when the field is numeric
+var whereFields = map[string]bool{
+	"name":        false,
+	"path":        false,
+	"ext":         false,
+	"type":        false,
+	"owner":       false,
+	"group":       false,
+	"mode":        false,
+	"class":       false,
+	"interpreter": false,
+	"commit":      false,
+	"author":      false,
+	"size":        true,
+	"age":         true,
+	"width":       true,
+	"height":      true,
+	"duration":    true,
+	"pages":       true,
+	"committed":   true,
+}
+
+// whereOperators - comparison operators, longest first so that "<=" is matched before "<"
+var whereOperators = []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">"}
+
+// whereNode - a parsed -where expression, or part of one
+type whereNode interface {
+	eval(e FileStat, now time.Time) bool
+}
+
+type whereAnd struct{ left, right whereNode }
+type whereOr struct{ left, right whereNode }
+type whereNot struct{ operand whereNode }
+
+// whereCompare - a single comparison of a field to a value
+type whereCompare struct {
+	field  string
+	op     string
+	text   string
+	number int64
+	re     *regexp.Regexp
+}
+
+func (w whereAnd) eval(e FileStat, now time.Time) bool {
+	return w.left.eval(e, now) && w.right.eval(e, now)
+}
+
+func (w whereOr) eval(e FileStat, now time.Time) bool {
+	return w.left.eval(e, now) || w.right.eval(e, now)
+}
+
+func (w whereNot) eval(e FileStat, now time.Time) bool {
+	return !w.operand.eval(e, now)
+}
+
+func (w whereCompare) eval(e FileStat, now time.Time) bool {
+	if whereFields[w.field] {
+		var value int64
+		switch w.field {
+		case "size":
+			value = e.Size
+		case "age":
+			value = int64(now.Sub(e.ModTime))
+		case "width":
+			value = int64(e.Width)
+		case "height":
+			value = int64(e.Height)
+		case "duration":
+			value = int64(e.Duration * float64(time.Second))
+		case "pages":
+			value = e.Pages
+		case "committed":
+			if nil == e.CommitTime {
+				return false
+			}
+			value = int64(now.Sub(*e.CommitTime))
+		}
+		switch w.op {
+		case "==":
+			return value == w.number
+		case "!=":
+			return value != w.number
+		case "<":
+			return value < w.number
+		case "<=":
+			return value <= w.number
+		case ">":
+			return value > w.number
+		default:
+			return value >= w.number
+		}
+	}
+
+	var value string
+	switch w.field {
+	case "name":
+		value = filepath.Base(e.FullName)
+	case "path":
+		value = e.FullName
+	case "ext":
+		if value = extGroupKey(e.FullName); noExtension == value {
+			value = ""
+		}
+	case "type":
+		value = e.FileType
+	case "owner":
+		value = e.Owner
+	case "group":
+		value = e.Group
+	case "mode":
+		value = e.Mode
+	case "class":
+		value = e.Class
+	case "interpreter":
+		value = e.Interpreter
+	case "commit":
+		value = e.Commit
+	case "author":
+		value = e.Author
+	}
+	switch w.op {
+	case "==":
+		return value == w.text
+	case "!=":
+		return value != w.text
+	case "=~":
+		return w.re.MatchString(value)
+	default:
+		return !w.re.MatchString(value)
+	}
+}
+
+// whereParser - a recursive descent parser over the tokens of a -where expression
+type whereParser struct {
+	tokens []string
+	pos    int
+	fields map[string]bool
+}
+
+// tokenizeWhere - split a -where expression into identifiers, values, quoted strings and operators
+func tokenizeWhere(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case ' ' == c || '\t' == c:
+			i++
+		case '"' == c || '\'' == c:
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string starting at: %s", expr[i:])
+			}
+			tokens = append(tokens, expr[i:i+end+2])
+			i += end + 2
+		case '(' == c || ')' == c:
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		default:
+			op := ""
+			for _, o := range whereOperators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if len(op) > 0 {
+				tokens = append(tokens, op)
+				i += len(op)
+				continue
+			}
+			if '!' == c {
+				tokens = append(tokens, "!")
+				i++
+				continue
+			}
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\"'()&|!=<>~", rune(expr[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected character: %c", c)
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+func (p *whereParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *whereParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseOr - expr := and ( "||" and )*
+func (p *whereParser) parseOr() (whereNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && "||" == p.peek() {
+		p.next()
+		var right whereNode
+		right, err = p.parseAnd()
+		left = whereOr{left, right}
+	}
+	return left, err
+}
+
+// parseAnd - and := unary ( "&&" unary )*
+func (p *whereParser) parseAnd() (whereNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && "&&" == p.peek() {
+		p.next()
+		var right whereNode
+		right, err = p.parseUnary()
+		left = whereAnd{left, right}
+	}
+	return left, err
+}
+
+// parseUnary - unary := "!" unary | "(" expr ")" | field operator value
+func (p *whereParser) parseUnary() (whereNode, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		operand, err := p.parseUnary()
+		return whereNot{operand}, err
+	case "(":
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if ")" != p.next() {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return node, nil
+	}
+	return p.parseCompare()
+}
+
+// parseCompare - convert the value of a comparison according to its field
+func (p *whereParser) parseCompare() (whereNode, error) {
+	field := strings.ToLower(p.next())
+	numeric, ok := whereFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field: '%s'", field)
+	}
+	p.fields[field] = true
+
+	op := p.next()
+	validOp := false
+	for _, o := range whereOperators {
+		validOp = validOp || o == op
+	}
+	if !validOp {
+		return nil, fmt.Errorf("missing comparison operator after: %s", field)
+	}
+
+	value := p.next()
+	if len(value) == 0 {
+		return nil, fmt.Errorf("missing value after: %s %s", field, op)
+	}
+	if strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "'") {
+		value = value[1 : len(value)-1]
+	}
+
+	compare := whereCompare{field: field, op: op, text: value}
+	var err error
+	switch {
+	case numeric && ("=~" == op || "!~" == op):
+		return nil, fmt.Errorf("'%s' can not be used with: %s", op, field)
+	case !numeric && ("==" != op && "!=" != op && "=~" != op && "!~" != op):
+		return nil, fmt.Errorf("'%s' can not be used with: %s", op, field)
+	case "size" == field:
+		compare.number, err = parseSize(value)
+	case "age" == field || "duration" == field || "committed" == field:
+		var age time.Duration
+		age, err = parseAge(value)
+		compare.number = int64(age)
+	case "width" == field || "height" == field || "pages" == field:
+		if compare.number, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = fmt.Errorf("invalid number: %s", value)
+		}
+	case "=~" == op || "!~" == op:
+		compare.re, err = regexp.Compile(value)
+	case "ext" == field:
+		compare.text = strings.ToLower(value)
+	}
+	return compare, err
+}
+
+/*
+parseWhere converts a -where expression into a tree of whereNode
+
+Args:
+    expr: the expression, see the top of this file for the syntax
+
+Returns:
+    the root node; the set of fields used by the expression; or an error when expr is not valid
+*/
+func parseWhere(expr string) (whereNode, map[string]bool, error) {
+	tokens, err := tokenizeWhere(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &whereParser{tokens: tokens, fields: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("unexpected: %s", p.tokens[p.pos])
+	}
+	return root, p.fields, nil
+}
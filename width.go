@@ -0,0 +1,323 @@
+/*
+
+width.go
+
+Fit the default table to the terminal width, used by the -truncate, -shorten and -minwidth cmd line options
+
+Example: fstat -truncate name:left -minwidth mode:12 -mode -r dirs.txt
+Example: fstat -shorten dirs -r dirs.txt
+
+One column is shortened so that each line fits; by default this is the Name column, or the Directory column
+with -splitname, shortened in the middle.
+Shortening from the left keeps the end of the value visible, such as the base name of a file. Shortening dirs
+removes directories from the middle of a path, keeping its first component and base name, such as:
+/home/.../project/file.txt. A column is never shortened to less than its minimum width, and narrower columns
+are padded to it.
+
+Columns are named by their header without spaces, ignoring case, such as: modtime, size, type, mode, age,
+devnum, allocated, name, directory, basename
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// how the shortened column is shortened
+const (
+	truncateMiddle = "middle"
+	truncateLeft   = "left"
+	truncateDirs   = "dirs"
+)
+
+// truncateStrategies - every value allowed by -shorten and after the column name of -truncate
+var truncateStrategies = []string{truncateDirs, truncateMiddle, truncateLeft}
+
+// validStrategy - true when strategy is one of truncateStrategies
+func validStrategy(strategy string) bool {
+	for _, s := range truncateStrategies {
+		if strategy == s {
+			return true
+		}
+	}
+	return false
+}
+
+// columnWidths - how the default table fits the terminal; the zero value shortens the Name or Directory column in the middle
+type columnWidths struct {
+	column   string
+	strategy string
+	minimum  map[string]int
+}
+
+// columnName - the name of a column used by -truncate and -minwidth, such as "modtime" for "Mod Time"
+func columnName(header string) string {
+	return strings.ToLower(strings.ReplaceAll(header, " ", ""))
+}
+
+// columnIndex - the position of the named column in header, or -1 when it is not there
+func columnIndex(header []string, name string) int {
+	for i, h := range header {
+		if columnName(h) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+parseColumnWidths validates the -truncate, -shorten and -minwidth cmd line options
+
+Args:
+    truncate: the column to shorten and how, such as "name" or "name:left"; empty for the default
+
+    shorten: how to shorten the column, such as "dirs"; empty for the default
+
+    minWidth: comma separated minimum widths, such as "mode:12,name:30"; empty for none
+
+    header: the columns of the default table, from tableHeader()
+
+Returns:
+    the settings to give to RenderAllEntries()
+
+    an error when a column, strategy, or width is not valid
+*/
+func parseColumnWidths(truncate string, shorten string, minWidth string, header []string) (columnWidths, error) {
+	var widths columnWidths
+	var names []string
+	for _, h := range header {
+		names = append(names, columnName(h))
+	}
+
+	if len(truncate) > 0 {
+		column, strategy, _ := strings.Cut(truncate, ":")
+		column = strings.ToLower(column)
+		if columnIndex(header, column) < 0 {
+			return widths, fmt.Errorf("unknown column for -truncate: %s; use one of: %s", column, strings.Join(names, ", "))
+		}
+		if len(strategy) > 0 && !validStrategy(strategy) {
+			return widths, fmt.Errorf("unknown -truncate strategy: %s; use one of: %s", strategy, strings.Join(truncateStrategies, ", "))
+		}
+		widths.column, widths.strategy = column, strategy
+	}
+
+	if len(shorten) > 0 {
+		if !validStrategy(shorten) {
+			return widths, fmt.Errorf("unknown -shorten: %s; use one of: %s", shorten, strings.Join(truncateStrategies, ", "))
+		}
+		if len(widths.strategy) > 0 && widths.strategy != shorten {
+			return widths, fmt.Errorf("-shorten %s can not be used with: -truncate %s", shorten, truncate)
+		}
+		widths.strategy = shorten
+	}
+
+	if len(minWidth) > 0 {
+		widths.minimum = make(map[string]int)
+		for _, field := range strings.Split(minWidth, ",") {
+			column, value, found := strings.Cut(strings.TrimSpace(field), ":")
+			column = strings.ToLower(column)
+			if columnIndex(header, column) < 0 {
+				return widths, fmt.Errorf("unknown column for -minwidth: %s; use one of: %s", column, strings.Join(names, ", "))
+			}
+			width, err := strconv.Atoi(value)
+			if !found || err != nil || width <= 0 {
+				return widths, fmt.Errorf("invalid -minwidth for %s: %s; use a positive number, such as: %s:20", column, value, column)
+			}
+			widths.minimum[column] = width
+		}
+	}
+	return widths, nil
+}
+
+// minimumWidths - the minimum width of each column of header, or nil when none were given
+func (widths columnWidths) minimumWidths(header []string) []int {
+	if 0 == len(widths.minimum) {
+		return nil
+	}
+	minimum := make([]int, len(header))
+	for i, h := range header {
+		minimum[i] = widths.minimum[columnName(h)]
+	}
+	return minimum
+}
+
+// displayWidth - the number of terminal cells used by s, where wide characters such as CJK and emoji use two
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// widthPrefix - the longest start of s that uses no more than width cells
+func widthPrefix(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		used += runewidth.RuneWidth(r)
+		if used > width {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// widthSuffix - the longest end of s that uses no more than width cells
+func widthSuffix(s string, width int) string {
+	used := 0
+	for i := len(s); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		used += runewidth.RuneWidth(r)
+		if used > width {
+			return s[i:]
+		}
+		i -= size
+	}
+	return s
+}
+
+/*
+truncateValue shortens s to fit within width cells by replacing part of it with "..."
+
+Args:
+    s: the value to shorten
+
+    width: the number of terminal cells available
+
+    strategy: truncateLeft keeps only the end of s; truncateDirs removes directories, see shortenDirs();
+              otherwise its start and end are kept
+
+Returns:
+    s, or the shortened value; a wide character is never split, so this may be one cell narrower than width
+*/
+func truncateValue(s string, width int, strategy string) string {
+	sWidth := displayWidth(s)
+	if sWidth <= width || sWidth <= 5 {
+		return s
+	}
+	if width <= 3 {
+		return widthSuffix(s, width)
+	}
+	if truncateDirs == strategy {
+		return shortenDirs(s, width)
+	}
+	if truncateLeft == strategy {
+		return "..." + widthSuffix(s, width-3)
+	}
+
+	// the same as ellipsis.Shorten(), counting cells instead of bytes
+	half := (width - 3) / 2
+	return widthPrefix(s, half) + "..." + widthSuffix(s, width-3-half)
+}
+
+/*
+shortenDirs removes directories from the middle of a path, keeping its volume name and first directory, and as
+many of its last components as fit, such as: /home/.../project/file.txt or \\server\share\dir\...\file.txt
+
+Args:
+    s: the path, which is wider than width
+
+    width: the number of terminal cells available
+
+Returns:
+    the shortened path; when not even the first component and base name fit, or s has no directories to
+    remove, then the end of s is kept as with truncateLeft
+*/
+func shortenDirs(s string, width int) string {
+	// the separator which ends the first component, such as the one after "/home", "C:\Users" or "\\server\share\dir";
+	// the volume name is never shortened
+	first := -1
+	for i := len(filepath.VolumeName(s)) + 1; i < len(s); i++ {
+		if os.IsPathSeparator(s[i]) && !os.IsPathSeparator(s[i-1]) {
+			first = i
+			break
+		}
+	}
+
+	shortened := ""
+	if first > 0 {
+		head := s[:first+1] + "..."
+		// try longer ends of the path until one does not fit; at least one directory is always removed
+		for i := len(s) - 1; i > first+1; i-- {
+			if !os.IsPathSeparator(s[i]) || os.IsPathSeparator(s[i-1]) {
+				continue
+			}
+			candidate := head + s[i:]
+			if displayWidth(candidate) > width {
+				break
+			}
+			shortened = candidate
+		}
+	}
+	if 0 == len(shortened) {
+		return "..." + widthSuffix(s, width-3)
+	}
+	return shortened
+}
+
+/*
+fitColumns shortens one column of the default table so that each line is no wider than lineWidth
+
+Args:
+    header: the columns, which are the same as each row
+
+    allRows: the table rows; they are modified in place
+
+    lineWidth: the maximum width of each line, including the borders
+
+    widths: the column to shorten, and the minimum width of each column
+
+Returns:
+    allRows, with the column shortened
+*/
+func fitColumns(header []string, allRows [][]string, lineWidth int, widths columnWidths) [][]string {
+	column := len(header) - 1
+	if len(widths.column) > 0 {
+		column = columnIndex(header, widths.column)
+	} else if dir := columnIndex(header, "directory"); dir >= 0 {
+		column = dir
+	}
+	if column < 0 {
+		return allRows
+	}
+
+	// each column is separated by " | " and the table starts with "| " and ends with " |"
+	available := lineWidth - 3*len(header) - 1
+	minimum := widths.minimumWidths(header)
+	for i := range header {
+		if i == column {
+			continue
+		}
+		width := displayWidth(header[i])
+		if minimum != nil && minimum[i] > width {
+			width = minimum[i]
+		}
+		for _, row := range allRows {
+			if w := displayWidth(row[i]); w > width {
+				width = w
+			}
+		}
+		available -= width
+	}
+
+	// keep the legacy room for file names when no column or minimum was given
+	floor := minTermWidth
+	if minimum != nil && minimum[column] > 0 {
+		floor = minimum[column]
+	} else if len(widths.column) > 0 && column != len(header)-1 {
+		floor = displayWidth(header[column])
+	}
+	if available < floor {
+		available = floor
+	}
+
+	for _, row := range allRows {
+		row[column] = truncateValue(row[column], available, widths.strategy)
+	}
+	return allRows
+}